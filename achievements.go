@@ -0,0 +1,243 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AchievementID names an achievement
+type AchievementID string
+
+const (
+	AchievementFirstFruit AchievementID = "first_fruit"
+	AchievementScore50    AchievementID = "score_50"
+	AchievementSurvive500 AchievementID = "survive_500"
+	AchievementVersusWin  AchievementID = "versus_win"
+)
+
+// Achievement is a goal players unlock once, in whichever game they first
+// reach it
+type Achievement struct {
+	ID          AchievementID `json:"id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+
+	// reached returns true once the given player, 1 for the snake and 2 for
+	// the opponent, has reached the goal in the game
+	reached func(state GameState, player int) bool
+}
+
+// achievements are every achievement, in the order they are listed
+var achievements = []Achievement{
+	{
+		ID: AchievementFirstFruit, Name: "First bite", Description: "Eat a fruit",
+		reached: func(state GameState, player int) bool {
+			return playerFruits(state, player) >= 1
+		},
+	},
+	{
+		ID: AchievementScore50, Name: "Half century", Description: "Score 50 points in a game",
+		reached: func(state GameState, player int) bool {
+			return playerScore(state, player) >= 50
+		},
+	},
+	{
+		ID: AchievementSurvive500, Name: "Survivor", Description: "Stay alive for 500 ticks",
+		reached: func(state GameState, player int) bool {
+			return survivedTicks(state, player) >= 500
+		},
+	},
+	{
+		ID: AchievementVersusWin, Name: "Victor", Description: "Win a versus game",
+		reached: func(state GameState, player int) bool {
+			return state.Versus && state.GameOverReason != "" && state.Winner == player
+		},
+	},
+}
+
+type (
+	// UnlockedAchievement is an achievement a player reached. In game states
+	// Player is the number of the snake that reached it; in a player's list
+	// GameID and UnlockedAt tell when they first did.
+	UnlockedAchievement struct {
+		ID         AchievementID `json:"id"`
+		Player     int           `json:"player,omitempty"`
+		GameID     string        `json:"gameId,omitempty"`
+		UnlockedAt *time.Time    `json:"unlockedAt,omitempty"`
+	}
+
+	AchievementsResponse struct {
+		Achievements []Achievement `json:"achievements"`
+	}
+
+	PlayerAchievementsResponse struct {
+		Player       string                `json:"player"`
+		Achievements []UnlockedAchievement `json:"achievements"`
+	}
+)
+
+// playerScore, playerFruits and survivedTicks return how far a player got
+// in a game
+func playerScore(state GameState, player int) int {
+	if player == 2 {
+		return state.OpponentScore
+	}
+	return state.Score
+}
+
+func playerFruits(state GameState, player int) int {
+	if player == 2 {
+		return state.OpponentFruitsEaten
+	}
+	return state.FruitsEaten
+}
+
+// survivedTicks does not count the tick a player died on
+func survivedTicks(state GameState, player int) int {
+	died := state.GameOverReason != "" && state.GameOverReason != ReasonTimeout &&
+		!(state.Versus && state.Winner == player)
+	if died {
+		return state.TicksPlayed - 1
+	}
+	return state.TicksPlayed
+}
+
+// unlockAchievements adds the achievements the players of a game have newly
+// reached to its state and returns them
+func unlockAchievements(state GameState) (GameState, []UnlockedAchievement) {
+	players := 1
+	if state.Opponent != nil {
+		players = 2
+	}
+
+	var unlocked []UnlockedAchievement
+	for player := 1; player <= players; player++ {
+		for _, achievement := range achievements {
+			if hasAchievement(state, achievement.ID, player) || !achievement.reached(state, player) {
+				continue
+			}
+			unlocked = append(unlocked, UnlockedAchievement{ID: achievement.ID, Player: player})
+		}
+	}
+	if len(unlocked) > 0 {
+		state.Achievements = append(append([]UnlockedAchievement{}, state.Achievements...), unlocked...)
+	}
+	return state, unlocked
+}
+
+// hasAchievement returns true if the player already reached the achievement
+// in the game
+func hasAchievement(state GameState, id AchievementID, player int) bool {
+	for _, unlocked := range state.Achievements {
+		if unlocked.ID == id && unlocked.Player == player {
+			return true
+		}
+	}
+	return false
+}
+
+// achievementStore persists the achievements of named players next to the
+// leaderboard
+type achievementStore struct {
+	db *sql.DB
+}
+
+// playerAchievements is the server-wide achievement store, opened in main
+var playerAchievements *achievementStore
+
+// openAchievements creates the achievement table in db if needed
+func openAchievements(db *sql.DB) (*achievementStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS achievements (
+			player      TEXT NOT NULL,
+			achievement TEXT NOT NULL,
+			game_id     TEXT NOT NULL,
+			unlocked_at INTEGER NOT NULL,
+			PRIMARY KEY (player, achievement)
+		);
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return &achievementStore{db: db}, nil
+}
+
+// add stores an achievement of a player; only the first game it was
+// reached in is kept
+func (s *achievementStore) add(player string, id AchievementID, gameID string) error {
+	_, err := s.db.Exec(
+		`INSERT INTO achievements (player, achievement, game_id, unlocked_at) VALUES (?, ?, ?, ?)
+		 ON CONFLICT DO NOTHING`,
+		player, id, gameID, time.Now().UnixMilli())
+	return err
+}
+
+// list returns the achievements of a player in the order they were reached
+func (s *achievementStore) list(player string) ([]UnlockedAchievement, error) {
+	rows, err := s.db.Query(
+		`SELECT achievement, game_id, unlocked_at FROM achievements
+		 WHERE player = ? ORDER BY unlocked_at, achievement`, player)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	unlocked := []UnlockedAchievement{}
+	for rows.Next() {
+		var entry UnlockedAchievement
+		var unlockedAt int64
+		if err := rows.Scan(&entry.ID, &entry.GameID, &unlockedAt); err != nil {
+			return nil, err
+		}
+		at := time.UnixMilli(unlockedAt).UTC()
+		entry.UnlockedAt = &at
+		unlocked = append(unlocked, entry)
+	}
+	return unlocked, rows.Err()
+}
+
+// recordAchievements stores the achievements newly unlocked in a game for
+// its named players; anonymous players only see them in the game state
+func recordAchievements(state GameState, unlocked []UnlockedAchievement) {
+	players := gamePlayers(state)
+	for _, achievement := range unlocked {
+		if achievement.Player > len(players) {
+			continue
+		}
+		player := players[achievement.Player-1]
+		if player == "" {
+			continue
+		}
+		if err := playerAchievements.add(player, achievement.ID, state.GameID); err != nil {
+			log.Printf("recording achievement %s of %s: %v", achievement.ID, player, err)
+		}
+	}
+}
+
+// achievementsHandler lists every achievement
+func achievementsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, AchievementsResponse{Achievements: achievements})
+}
+
+// playerAchievementsHandler lists the achievements a player has unlocked
+func playerAchievementsHandler(w http.ResponseWriter, r *http.Request) {
+	player, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil || player == "" || len(player) > maxPlayerNameLength {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+			"Player name must be between 1 and %d characters", maxPlayerNameLength))
+		return
+	}
+
+	unlocked, err := playerAchievements.list(player)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load achievements")
+		return
+	}
+	jsonResponse(w, PlayerAchievementsResponse{Player: player, Achievements: unlocked})
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// apiKeyHeader carries the API key of a bot client
+	apiKeyHeader = "X-API-Key"
+
+	// apiKeyPrefix starts every API key, so leaked keys are easy to spot
+	apiKeyPrefix = "sk_"
+
+	// adminKeyEnv names the environment variable holding the bootstrap key
+	// that issues the first API keys; it has the admin scope and cannot be
+	// revoked
+	adminKeyEnv = "SNAKE_ADMIN_KEY"
+
+	// botOwnerPrefix names the owner of games a bot client created, so it
+	// cannot be mistaken for an account
+	botOwnerPrefix = "bot:"
+)
+
+// APIScope is a permission granted to an API key
+type APIScope string
+
+const (
+	// ScopePlay creates and plays games
+	ScopePlay APIScope = "play"
+	// ScopeScores submits scores to the leaderboard
+	ScopeScores APIScope = "scores"
+	// ScopeAdmin manages API keys
+	ScopeAdmin APIScope = "admin"
+)
+
+// apiScopes are the scopes a key can be issued with
+var apiScopes = []string{string(ScopePlay), string(ScopeScores), string(ScopeAdmin)}
+
+var (
+	errKeyNotFound = errors.New("API key not found")
+	errKeyExists   = errors.New("API key name is taken")
+)
+
+type (
+	// APIKey identifies a bot client. Key is only set in the response
+	// creating it; the server keeps a hash of it.
+	APIKey struct {
+		ID        string     `json:"id"`
+		Name      string     `json:"name"`
+		Scopes    []APIScope `json:"scopes"`
+		Key       string     `json:"key,omitempty"`
+		Prefix    string     `json:"prefix"`
+		CreatedAt time.Time  `json:"createdAt"`
+		RevokedAt *time.Time `json:"revokedAt,omitempty"`
+	}
+
+	// APIKeyRequest is the body of POST /v1/admin/keys
+	APIKeyRequest struct {
+		Name   string     `json:"name"`
+		Scopes []APIScope `json:"scopes"`
+	}
+
+	APIKeysResponse struct {
+		Keys []APIKey `json:"keys"`
+	}
+)
+
+// has returns true if the key was granted scope
+func (k APIKey) has(scope APIScope) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// owner is the owner of games the key creates and the player its scores
+// are recorded under
+func (k APIKey) owner() string {
+	return botOwnerPrefix + k.Name
+}
+
+// apiKeyStore persists API keys next to the leaderboard
+type apiKeyStore struct {
+	db *sql.DB
+
+	// admin is the bootstrap key from adminKeyEnv, if it is set
+	admin *APIKey
+	// adminHash is the hash of the bootstrap key
+	adminHash string
+}
+
+// apiKeys is the server-wide API key store, opened in main
+var apiKeys *apiKeyStore
+
+// openAPIKeys creates the API key table in db if needed, and sets up the
+// bootstrap admin key if there is one
+func openAPIKeys(db *sql.DB, adminKey string) (*apiKeyStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			scopes     TEXT NOT NULL,
+			key_hash   TEXT NOT NULL UNIQUE,
+			prefix     TEXT NOT NULL,
+			created_at INTEGER NOT NULL,
+			revoked_at INTEGER
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS api_keys_by_name ON api_keys (name);
+	`)
+	if err != nil {
+		return nil, err
+	}
+	store := &apiKeyStore{db: db}
+	if adminKey == "" {
+		log.Printf("api keys: %s not set, keys can only be managed with existing admin keys", adminKeyEnv)
+		return store, nil
+	}
+	store.admin = &APIKey{ID: "admin", Name: "admin", Prefix: "admin", Scopes: []APIScope{ScopeAdmin}}
+	store.adminHash = hashAPIKey(adminKey)
+	return store, nil
+}
+
+// hashAPIKey is how keys are stored and looked up; keys are random, so an
+// unsalted hash is enough
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// create issues a new key with the given name and scopes. Names are unique,
+// revoked keys included, since the name is the owner of the key's games and
+// scores.
+func (s *apiKeyStore) create(name string, scopes []APIScope) (APIKey, error) {
+	if s.admin != nil && name == s.admin.Name {
+		return APIKey{}, errKeyExists
+	}
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return APIKey{}, err
+	}
+	key := APIKey{
+		ID:        uuid.NewString(),
+		Name:      name,
+		Scopes:    scopes,
+		Key:       apiKeyPrefix + hex.EncodeToString(secret),
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	key.Prefix = key.Key[:len(apiKeyPrefix)+6]
+
+	_, err := s.db.Exec(
+		`INSERT INTO api_keys (id, name, scopes, key_hash, prefix, created_at) VALUES (?, ?, ?, ?, ?, ?)`,
+		key.ID, key.Name, joinScopes(scopes), hashAPIKey(key.Key), key.Prefix, key.CreatedAt.UnixMilli())
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed: api_keys.name") {
+		return APIKey{}, errKeyExists
+	}
+	return key, err
+}
+
+// lookup returns the unrevoked key with the given secret
+func (s *apiKeyStore) lookup(secret string) (APIKey, error) {
+	hash := hashAPIKey(secret)
+	if s.admin != nil && subtle.ConstantTimeCompare([]byte(hash), []byte(s.adminHash)) == 1 {
+		return *s.admin, nil
+	}
+	key, err := scanAPIKey(s.db.QueryRow(
+		`SELECT id, name, scopes, prefix, created_at, revoked_at FROM api_keys
+		 WHERE key_hash = ? AND revoked_at IS NULL`, hash))
+	if err == sql.ErrNoRows {
+		return key, errKeyNotFound
+	}
+	return key, err
+}
+
+// list returns every key, newest first, including revoked ones
+func (s *apiKeyStore) list() ([]APIKey, error) {
+	rows, err := s.db.Query(
+		`SELECT id, name, scopes, prefix, created_at, revoked_at FROM api_keys ORDER BY created_at DESC, id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// revoke stops a key from being accepted; revoking it again is a no-op
+func (s *apiKeyStore) revoke(id string) (APIKey, error) {
+	_, err := s.db.Exec(`UPDATE api_keys SET revoked_at = ? WHERE id = ? AND revoked_at IS NULL`,
+		time.Now().UnixMilli(), id)
+	if err != nil {
+		return APIKey{}, err
+	}
+	key, err := scanAPIKey(s.db.QueryRow(
+		`SELECT id, name, scopes, prefix, created_at, revoked_at FROM api_keys WHERE id = ?`, id))
+	if err == sql.ErrNoRows {
+		return key, errKeyNotFound
+	}
+	return key, err
+}
+
+// scanAPIKey reads a key selected as id, name, scopes, prefix, created_at
+// and revoked_at
+func scanAPIKey(row interface{ Scan(...any) error }) (APIKey, error) {
+	var key APIKey
+	var scopes string
+	var createdAt int64
+	var revokedAt sql.NullInt64
+	if err := row.Scan(&key.ID, &key.Name, &scopes, &key.Prefix, &createdAt, &revokedAt); err != nil {
+		return key, err
+	}
+	for _, scope := range strings.Split(scopes, ",") {
+		key.Scopes = append(key.Scopes, APIScope(scope))
+	}
+	key.CreatedAt = time.UnixMilli(createdAt).UTC()
+	if revokedAt.Valid {
+		at := time.UnixMilli(revokedAt.Int64).UTC()
+		key.RevokedAt = &at
+	}
+	return key, nil
+}
+
+// joinScopes is how scopes are stored
+func joinScopes(scopes []APIScope) string {
+	names := make([]string, len(scopes))
+	for i, scope := range scopes {
+		names[i] = string(scope)
+	}
+	return strings.Join(names, ",")
+}
+
+// clientKey is the context key of the API key of a bot client
+type clientKey struct{}
+
+// clientFrom returns the API key the request was made with, if any
+func clientFrom(ctx context.Context) (APIKey, bool) {
+	key, ok := ctx.Value(clientKey{}).(APIKey)
+	return key, ok
+}
+
+// identifyClient puts the API key of X-API-Key into the request context, and
+// its owner in place of an account. Unknown and revoked keys are rejected
+// with 401.
+func identifyClient(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get(apiKeyHeader)
+		if secret == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, err := apiKeys.lookup(secret)
+		if err == errKeyNotFound {
+			unauthorized(w, "Unknown or revoked API key")
+			return
+		}
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to check API key")
+			return
+		}
+		ctx := context.WithValue(r.Context(), clientKey{}, key)
+		ctx = context.WithValue(ctx, accountKey{}, key.owner())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requireAdmin rejects requests not made with an admin API key
+func requireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := clientFrom(r.Context())
+		if !ok {
+			jsonError(w, http.StatusUnauthorized, CodeUnauthorized,
+				"Send an admin API key in the "+apiKeyHeader+" header")
+			return
+		}
+		if !key.has(ScopeAdmin) {
+			jsonError(w, http.StatusForbidden, CodeForbidden,
+				fmt.Sprintf("API key %s lacks the %s scope", key.Prefix, ScopeAdmin))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// createAPIKeyHandler issues a key; its secret is only ever returned here
+func createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req APIKeyRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	// Names must leave room for botOwnerPrefix in the player name
+	if !usernameFormat.MatchString(req.Name) || len(req.Name) > maxPlayerNameLength-len(botOwnerPrefix) {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+			"Name must be 3 to %d letters, digits, _ or -", maxPlayerNameLength-len(botOwnerPrefix)))
+		return
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = []APIScope{ScopePlay, ScopeScores}
+	}
+	for _, scope := range req.Scopes {
+		if !slices.Contains(apiScopes, string(scope)) {
+			jsonResponseWithStatus(w, ErrorResponse{
+				Code:      CodeInvalidRequest,
+				Message:   fmt.Sprintf("Unknown scope: %s", scope),
+				Supported: apiScopes,
+			}, http.StatusBadRequest)
+			return
+		}
+	}
+	slices.Sort(req.Scopes)
+	req.Scopes = slices.Compact(req.Scopes)
+
+	key, err := apiKeys.create(req.Name, req.Scopes)
+	switch err {
+	case nil:
+		jsonResponseWithStatus(w, key, http.StatusCreated)
+	case errKeyExists:
+		jsonError(w, http.StatusConflict, CodeKeyExists, fmt.Sprintf("API key name is taken: %s", req.Name))
+	default:
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to create API key")
+	}
+}
+
+// apiKeysHandler lists every key without its secret
+func apiKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := apiKeys.list()
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load API keys")
+		return
+	}
+	jsonResponse(w, APIKeysResponse{Keys: keys})
+}
+
+// revokeAPIKeyHandler revokes a key; requests made with it fail from then on
+func revokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "key")
+	key, err := apiKeys.revoke(id)
+	if err == errKeyNotFound {
+		jsonError(w, http.StatusNotFound, CodeKeyNotFound, fmt.Sprintf("API key not found: %s", id))
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to revoke API key")
+		return
+	}
+	jsonResponse(w, key)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestAPIKeyNamesAreUnique(t *testing.T) {
+	h := newTestAPI(t)
+	admin, err := apiKeys.create("ops", []APIScope{ScopeAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot, err := apiKeys.create("bot1", []APIScope{ScopePlay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := apiKeys.revoke(bot.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		status int
+	}{
+		{name: "bot2", status: http.StatusCreated},
+		{name: "bot2", status: http.StatusConflict},
+		{name: "bot1", status: http.StatusConflict},
+	}
+	for _, tt := range tests {
+		req := newRequest(t, http.MethodPost, "/v1/admin/keys", "", APIKeyRequest{Name: tt.name})
+		req.Header.Set(apiKeyHeader, admin.Key)
+		rec := send(h, req)
+		if rec.Code != tt.status {
+			t.Errorf("creating %s: status = %d, want %d: %s", tt.name, rec.Code, tt.status, rec.Body)
+			continue
+		}
+		if tt.status == http.StatusConflict {
+			var resp ErrorResponse
+			decode(t, rec, &resp)
+			if resp.Code != CodeKeyExists {
+				t.Errorf("creating %s: code = %s, want %s", tt.name, resp.Code, CodeKeyExists)
+			}
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// defaultAuditPage and maxAuditPage bound the entries GET
+	// /v1/admin/audit returns at once
+	defaultAuditPage = 100
+	maxAuditPage     = 1000
+)
+
+type (
+	// ValidationAudit records a validate request, or ticks submitted over
+	// gRPC or GraphQL, so disputed submissions can be looked into. Caller is the account or bot that sent it, empty
+	// for anonymous players; Status is the HTTP status it was answered
+	// with and Result the outcome of the ticks, if they were applied.
+	ValidationAudit struct {
+		ID           int64     `json:"id"`
+		Caller       string    `json:"caller,omitempty"`
+		RemoteAddr   string    `json:"remoteAddr"`
+		GameID       string    `json:"gameId,omitempty"`
+		Ticks        int       `json:"ticks"`
+		TicksApplied int       `json:"ticksApplied"`
+		Status       int       `json:"status"`
+		Result       string    `json:"result,omitempty"`
+		DurationMs   float64   `json:"durationMs"`
+		CreatedAt    time.Time `json:"createdAt"`
+	}
+
+	// AuditResponse holds a page of audit entries, newest first. Pass Next
+	// as ?before to get the following page.
+	AuditResponse struct {
+		Entries []ValidationAudit `json:"entries"`
+		Next    int64             `json:"next,omitempty"`
+	}
+)
+
+// auditKey is the context key of the audit entry of a validate request
+type auditKey struct{}
+
+// remoteAddrKey is the context key of the client address of a GraphQL
+// request, which its resolvers have no other way to learn
+type remoteAddrKey struct{}
+
+// auditStore keeps the validation audit log next to the leaderboard
+type auditStore struct {
+	db *sql.DB
+}
+
+// audits is the server-wide validation audit log, opened in main
+var audits *auditStore
+
+// openAudits creates the audit table in db if needed
+func openAudits(db *sql.DB) (*auditStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS validation_audit (
+			id            INTEGER PRIMARY KEY AUTOINCREMENT,
+			caller        TEXT NOT NULL,
+			remote_addr   TEXT NOT NULL,
+			game_id       TEXT NOT NULL,
+			ticks         INTEGER NOT NULL,
+			ticks_applied INTEGER NOT NULL,
+			status        INTEGER NOT NULL,
+			result        TEXT NOT NULL,
+			duration_us   INTEGER NOT NULL,
+			created_at    INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS validation_audit_by_game ON validation_audit (game_id, id);
+		CREATE INDEX IF NOT EXISTS validation_audit_by_caller ON validation_audit (caller, id);
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return &auditStore{db: db}, nil
+}
+
+// add appends an entry to the audit log
+func (s *auditStore) add(entry ValidationAudit, duration time.Duration) error {
+	_, err := s.db.Exec(
+		`INSERT INTO validation_audit (caller, remote_addr, game_id, ticks, ticks_applied, status, result,
+			duration_us, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.Caller, entry.RemoteAddr, entry.GameID, entry.Ticks, entry.TicksApplied, entry.Status,
+		entry.Result, duration.Microseconds(), entry.CreatedAt.UnixMilli())
+	return err
+}
+
+// auditFilter narrows down the entries list returns; zero fields match
+// every entry
+type auditFilter struct {
+	gameID, caller string
+	status         int
+	since          time.Time
+	before         int64
+	limit          int
+}
+
+// list returns the newest entries matching the filter
+func (s *auditStore) list(filter auditFilter) ([]ValidationAudit, error) {
+	var where []string
+	var args []any
+	if filter.gameID != "" {
+		where, args = append(where, "game_id = ?"), append(args, filter.gameID)
+	}
+	if filter.caller != "" {
+		where, args = append(where, "caller = ?"), append(args, filter.caller)
+	}
+	if filter.status != 0 {
+		where, args = append(where, "status = ?"), append(args, filter.status)
+	}
+	if !filter.since.IsZero() {
+		where, args = append(where, "created_at >= ?"), append(args, filter.since.UnixMilli())
+	}
+	if filter.before != 0 {
+		where, args = append(where, "id < ?"), append(args, filter.before)
+	}
+	query := `SELECT id, caller, remote_addr, game_id, ticks, ticks_applied, status, result, duration_us, created_at
+		FROM validation_audit`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := s.db.Query(query+" ORDER BY id DESC LIMIT ?", append(args, filter.limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []ValidationAudit{}
+	for rows.Next() {
+		var entry ValidationAudit
+		var durationUs, createdAt int64
+		err := rows.Scan(&entry.ID, &entry.Caller, &entry.RemoteAddr, &entry.GameID, &entry.Ticks,
+			&entry.TicksApplied, &entry.Status, &entry.Result, &durationUs, &createdAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.DurationMs = float64(durationUs) / 1000
+		entry.CreatedAt = time.UnixMilli(createdAt).UTC()
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// auditValidation records every request to the validate route it wraps in
+// the audit log, once it has been answered. The handler fills in what it
+// learns of the game through auditGame and auditResult.
+func auditValidation(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		entry := &ValidationAudit{
+			Caller:     accountFrom(r.Context()),
+			RemoteAddr: r.RemoteAddr,
+			GameID:     chi.URLParam(r, "id"),
+			CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+		}
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r.WithContext(context.WithValue(r.Context(), auditKey{}, entry)))
+
+		entry.Status = ww.Status()
+		if entry.Status == 0 {
+			entry.Status = http.StatusOK
+		}
+		if err := audits.add(*entry, time.Since(start)); err != nil {
+			log.Printf("auditing validation of game %s: %v", entry.GameID, err)
+		}
+	})
+}
+
+// auditStored starts the audit entry of ticks submitted to validateStored
+// over gRPC or GraphQL; validate requests over HTTP are already audited by
+// auditValidation. The returned function records the entry once the ticks
+// were applied, with the HTTP status the outcome is answered with.
+func auditStored(ctx context.Context, gameID string, ticks int) (context.Context, func(statusCode, ticksApplied int, err error)) {
+	if _, ok := ctx.Value(auditKey{}).(*ValidationAudit); ok {
+		return ctx, func(int, int, error) {}
+	}
+	entry := &ValidationAudit{
+		Caller:     accountFrom(ctx),
+		RemoteAddr: remoteAddr(ctx),
+		GameID:     gameID,
+		Ticks:      ticks,
+		CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+	start := time.Now()
+	return context.WithValue(ctx, auditKey{}, entry), func(statusCode, ticksApplied int, err error) {
+		entry.TicksApplied = ticksApplied
+		switch {
+		case err == errGamePaused:
+			entry.Status = http.StatusConflict
+		case err == errNotOwner:
+			entry.Status = http.StatusForbidden
+		case err == errVersionConflict:
+			entry.Status = http.StatusPreconditionFailed
+		case errors.Is(err, errGameNotFound):
+			entry.Status = http.StatusNotFound
+		case err != nil:
+			entry.Status = http.StatusInternalServerError
+		case statusCode == http.StatusConflict:
+			entry.Status, entry.Result = statusCode, "live"
+		case statusCode == http.StatusBadRequest:
+			entry.Status, entry.Result = statusCode, "invalid_tick"
+		case statusCode == http.StatusTeapot:
+			entry.Status, entry.Result = http.StatusOK, string(ResultGameOver)
+		default:
+			entry.Status, entry.Result = statusCode, string(ResultOK)
+		}
+		if err := audits.add(*entry, time.Since(start)); err != nil {
+			log.Printf("auditing validation of game %s: %v", entry.GameID, err)
+		}
+	}
+}
+
+// remoteAddr returns the client address of a gRPC call or GraphQL request
+func remoteAddr(ctx context.Context) string {
+	if addr, ok := ctx.Value(remoteAddrKey{}).(string); ok {
+		return addr
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// auditGame notes the game and tick count of the validate request being
+// audited, if any
+func auditGame(ctx context.Context, gameID string, ticks int) {
+	if entry, ok := ctx.Value(auditKey{}).(*ValidationAudit); ok {
+		entry.GameID, entry.Ticks = gameID, ticks
+	}
+}
+
+// auditResult notes the outcome of the validate request being audited, if
+// any
+func auditResult(ctx context.Context, result string, ticksApplied int) {
+	if entry, ok := ctx.Value(auditKey{}).(*ValidationAudit); ok {
+		entry.Result, entry.TicksApplied = result, ticksApplied
+	}
+}
+
+// auditHandler returns the audit log, filtered by ?gameId, ?caller,
+// ?status and ?since=RFC3339, ?limit=N entries from ?before=ID back
+func auditHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := auditFilter{
+		gameID: query.Get("gameId"),
+		caller: query.Get("caller"),
+		limit:  defaultAuditPage,
+	}
+	if v := query.Get("status"); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil || status < 100 || status > 599 {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("Invalid status: %s", v))
+			return
+		}
+		filter.status = status
+	}
+	if v := query.Get("since"); v != "" {
+		since, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid since: %s, expected an RFC 3339 time", v))
+			return
+		}
+		filter.since = since
+	}
+	if v := query.Get("before"); v != "" {
+		before, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || before <= 0 {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf("Invalid before: %s", v))
+			return
+		}
+		filter.before = before
+	}
+	if v := query.Get("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxAuditPage {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+				"limit must be between 1 and %d", maxAuditPage))
+			return
+		}
+		filter.limit = limit
+	}
+
+	entries, err := audits.list(filter)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load audit log")
+		return
+	}
+	resp := AuditResponse{Entries: entries}
+	if len(entries) == filter.limit {
+		resp.Next = entries[len(entries)-1].ID
+	}
+	jsonResponse(w, resp)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestTicksAuditedOverEveryAPI(t *testing.T) {
+	h := newTestAPI(t)
+	alice := register(t, h, "alice")
+	game := newGame(t, h, alice, GameConfig{Width: 10, Height: 10})
+
+	rec := do(t, h, http.MethodPost, "/v1/games/"+game.GameID+"/validate", alice,
+		ValidateRequest{Ticks: []Tick{{VelY: 1}}})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("validating over HTTP: %d %s", rec.Code, rec.Body)
+	}
+
+	query := `mutation($id: ID!) { submitTicks(id: $id, ticks: [{velX: 0, velY: 1}]) { ticksApplied } }`
+	req := newRequest(t, http.MethodPost, "/graphql", "",
+		GraphQLRequest{Query: query, Variables: map[string]any{"id": game.GameID}})
+	req = req.WithContext(context.WithValue(req.Context(), accountKey{}, "alice"))
+	if rec := send(http.HandlerFunc(graphqlHandler), req); rec.Code != http.StatusOK ||
+		strings.Contains(rec.Body.String(), `"errors"`) {
+		t.Fatalf("submitting ticks over GraphQL: %d %s", rec.Code, rec.Body)
+	}
+
+	ctx := context.WithValue(context.Background(), accountKey{}, "alice")
+	if _, _, _, err := validateStored(ctx, game.GameID, []Tick{{VelX: 1}}); err != nil {
+		t.Fatalf("validating like gRPC: %v", err)
+	}
+
+	entries, err := audits.list(auditFilter{gameID: game.GameID, limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d audit entries, want 3: %+v", len(entries), entries)
+	}
+	for _, entry := range entries {
+		if entry.Caller != "alice" || entry.Status != http.StatusOK || entry.Result != string(ResultOK) ||
+			entry.Ticks != 1 || entry.TicksApplied != 1 {
+			t.Errorf("entry = %+v, want an applied tick of alice", entry)
+		}
+	}
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// jwtSecretEnv names the environment variable holding the HMAC key tokens
+// are signed with; like signingKeyEnv it is kept out of the flags
+const jwtSecretEnv = "SNAKE_JWT_SECRET"
+
+// defaultTokenTTL is how long an issued token is valid unless configured
+// otherwise
+const defaultTokenTTL = 24 * time.Hour
+
+const (
+	// minPasswordLength and maxPasswordLength bound account passwords;
+	// bcrypt ignores everything past 72 bytes
+	minPasswordLength = 8
+	maxPasswordLength = 72
+)
+
+// usernameFormat is the form of account usernames, which also name the
+// player on the leaderboard
+var usernameFormat = regexp.MustCompile(fmt.Sprintf(`^[A-Za-z0-9_-]{3,%d}$`, maxPlayerNameLength))
+
+var (
+	errAccountExists      = errors.New("username is taken")
+	errInvalidCredentials = errors.New("invalid username or password")
+	errNotOwner           = errors.New("game belongs to another player")
+)
+
+type (
+	// Credentials register or log in an account
+	Credentials struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	// Account is a registered player
+	Account struct {
+		Username  string    `json:"username"`
+		CreatedAt time.Time `json:"createdAt"`
+	}
+
+	// TokenResponse carries a token to send as "Authorization: Bearer"
+	TokenResponse struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expiresAt"`
+	}
+)
+
+// accountStore persists accounts next to the leaderboard
+type accountStore struct {
+	db *sql.DB
+}
+
+var (
+	// accounts is the server-wide account store, opened in main
+	accounts *accountStore
+
+	// jwtSecret signs and verifies tokens; tokenTTL is their lifetime
+	jwtSecret []byte
+	tokenTTL  = defaultTokenTTL
+
+	// requireAuth rejects anonymous requests to game routes
+	requireAuth bool
+)
+
+// openAccounts creates the accounts table in db if needed
+func openAccounts(db *sql.DB) (*accountStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS accounts (
+			username      TEXT PRIMARY KEY COLLATE NOCASE,
+			password_hash BLOB NOT NULL,
+			created_at    INTEGER NOT NULL
+		);
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return &accountStore{db: db}, nil
+}
+
+// create registers an account; usernames are unique regardless of case
+func (s *accountStore) create(account Account, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`INSERT INTO accounts (username, password_hash, created_at) VALUES (?, ?, ?)`,
+		account.Username, hash, account.CreatedAt.UnixMilli())
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return errAccountExists
+	}
+	return err
+}
+
+// authenticate returns the account with the given credentials, spelled as
+// it was registered, or errInvalidCredentials
+func (s *accountStore) authenticate(username, password string) (string, error) {
+	var stored string
+	var hash []byte
+	err := s.db.QueryRow(`SELECT username, password_hash FROM accounts WHERE username = ?`, username).
+		Scan(&stored, &hash)
+	if err == sql.ErrNoRows {
+		return "", errInvalidCredentials
+	}
+	if err != nil {
+		return "", err
+	}
+	if bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+		return "", errInvalidCredentials
+	}
+	return stored, nil
+}
+
+// get returns the account with the given username, or errInvalidCredentials
+// if it was deleted since its token was issued
+func (s *accountStore) get(username string) (Account, error) {
+	account := Account{Username: username}
+	var createdAt int64
+	err := s.db.QueryRow(`SELECT created_at FROM accounts WHERE username = ?`, username).Scan(&createdAt)
+	if err == sql.ErrNoRows {
+		return account, errInvalidCredentials
+	}
+	account.CreatedAt = time.UnixMilli(createdAt).UTC()
+	return account, err
+}
+
+// setJWTSecret configures the token key. A random key is generated if none
+// is configured, so tokens are not accepted after a restart or by other
+// replicas.
+func setJWTSecret(secret string) {
+	if secret == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			log.Fatalf("generating token key: %v", err)
+		}
+		log.Printf("accounts: %s not set, using a random token key", jwtSecretEnv)
+		jwtSecret = random
+		return
+	}
+	jwtSecret = []byte(secret)
+}
+
+// issueToken returns a signed token naming the account as its subject
+func issueToken(username string, now time.Time) (TokenResponse, error) {
+	expiresAt := now.Add(tokenTTL).Truncate(time.Second)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{
+		Subject:   username,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+	})
+	signed, err := token.SignedString(jwtSecret)
+	return TokenResponse{Token: signed, ExpiresAt: expiresAt.UTC()}, err
+}
+
+// parseToken returns the account a token was issued to, or an error if it
+// is not a valid, unexpired token of this server
+func parseToken(token string) (string, error) {
+	var claims jwt.RegisteredClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (any, error) {
+		return jwtSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return "", err
+	}
+	if claims.Subject == "" {
+		return "", errors.New("token has no subject")
+	}
+	return claims.Subject, nil
+}
+
+// accountKey is the context key of the authenticated account
+type accountKey struct{}
+
+// accountFrom returns the account that made the request, or an empty
+// string for anonymous requests
+func accountFrom(ctx context.Context) string {
+	account, _ := ctx.Value(accountKey{}).(string)
+	return account
+}
+
+// authenticateRequest puts the account of a bearer token into the request
+// context. Requests without a token stay anonymous; an invalid token is
+// rejected with 401 rather than silently ignored.
+func authenticateRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if header == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(header, "Bearer ")
+		if !ok {
+			unauthorized(w, "Authorization must be a Bearer token")
+			return
+		}
+		account, err := parseToken(token)
+		if err != nil {
+			unauthorized(w, "Invalid or expired token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), accountKey{}, account)))
+	})
+}
+
+// requireAccount rejects anonymous requests with 401 when the server is run
+// with -require-auth, and requests made with an API key lacking scope with
+// 403
+func requireAccount(scope APIScope) func(http.Handler) http.Handler {
+	return accountScope(scope, false)
+}
+
+// requireLogin is like requireAccount, but rejects anonymous requests even
+// without -require-auth, for routes acting on behalf of a named player
+func requireLogin(scope APIScope) func(http.Handler) http.Handler {
+	return accountScope(scope, true)
+}
+
+// accountScope implements requireAccount and requireLogin
+func accountScope(scope APIScope, always bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if (always || requireAuth) && accountFrom(r.Context()) == "" {
+				unauthorized(w, "Log in and send the token as \"Authorization: Bearer <token>\"")
+				return
+			}
+			if key, ok := clientFrom(r.Context()); ok && !key.has(scope) {
+				jsonError(w, http.StatusForbidden, CodeForbidden,
+					fmt.Sprintf("API key %s lacks the %s scope", key.Prefix, scope))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireOwner rejects requests by anyone but its owner to change the game
+// in the {id} route parameter. Games created anonymously have no owner and
+// stay open to everyone.
+func requireOwner(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gameID := chi.URLParam(r, "id")
+		state, err := games.Get(r.Context(), gameID)
+		if err != nil {
+			storeError(w, err, gameID)
+			return
+		}
+		if !ownedBy(state, r.Context()) {
+			ownerError(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// requirePlayer rejects requests to steer the game in the {id} route
+// parameter by anyone who neither may change it nor plays one of its
+// snakes; which snake they may steer is checked by controlledBy
+func requirePlayer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gameID := chi.URLParam(r, "id")
+		state, err := games.Get(r.Context(), gameID)
+		if err != nil {
+			storeError(w, err, gameID)
+			return
+		}
+		account := accountFrom(r.Context())
+		if !ownedBy(state, r.Context()) && (account == "" || !slices.Contains(state.Players, account)) {
+			ownerError(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ownedBy returns true if the account of ctx may change the game
+func ownedBy(state GameState, ctx context.Context) bool {
+	return state.Owner == "" || state.Owner == accountFrom(ctx)
+}
+
+// controlledBy returns true if the account of ctx may steer a snake of the
+// game, 1 for the snake and 2 for the opponent. A snake played by a named
+// player only takes directions from them; an anonymous snake is steered by
+// whoever may change the game, or by anyone for the opponent.
+func controlledBy(state GameState, ctx context.Context, player int) bool {
+	if player <= len(state.Players) && state.Players[player-1] != "" {
+		return state.Players[player-1] == accountFrom(ctx)
+	}
+	return player == 2 || ownedBy(state, ctx)
+}
+
+// unauthorized writes a 401 asking for a bearer token
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="snake"`)
+	jsonError(w, http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// ownerError writes the 403 for changing a game owned by another player
+func ownerError(w http.ResponseWriter) {
+	jsonError(w, http.StatusForbidden, CodeForbidden, "Game belongs to another player")
+}
+
+// steerError writes the 403 for steering the snake of another player
+func steerError(w http.ResponseWriter, player int) {
+	jsonError(w, http.StatusForbidden, CodeForbidden, fmt.Sprintf("Snake %d is steered by another player", player))
+}
+
+// registerHandler creates an account and logs it in
+func registerHandler(w http.ResponseWriter, r *http.Request) {
+	var req Credentials
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if !usernameFormat.MatchString(req.Username) {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+			"Username must be 3 to %d letters, digits, _ or -", maxPlayerNameLength))
+		return
+	}
+	if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+			"Password must be between %d and %d bytes", minPasswordLength, maxPasswordLength))
+		return
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	switch err := accounts.create(Account{Username: req.Username, CreatedAt: now}, req.Password); err {
+	case nil:
+	case errAccountExists:
+		jsonError(w, http.StatusConflict, CodeAccountExists, fmt.Sprintf("Username is taken: %s", req.Username))
+		return
+	default:
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to create account")
+		return
+	}
+
+	token, err := issueToken(req.Username, now)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to issue token")
+		return
+	}
+	jsonResponseWithStatus(w, token, http.StatusCreated)
+}
+
+// loginHandler exchanges the credentials of an account for a token
+func loginHandler(w http.ResponseWriter, r *http.Request) {
+	var req Credentials
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	username, err := accounts.authenticate(req.Username, req.Password)
+	if err == errInvalidCredentials {
+		jsonError(w, http.StatusUnauthorized, CodeInvalidCredentials, "Invalid username or password")
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to log in")
+		return
+	}
+
+	token, err := issueToken(username, time.Now())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to issue token")
+		return
+	}
+	jsonResponse(w, token)
+}
+
+// meHandler returns the account of the token
+func meHandler(w http.ResponseWriter, r *http.Request) {
+	username := accountFrom(r.Context())
+	if username == "" {
+		unauthorized(w, "Log in and send the token as \"Authorization: Bearer <token>\"")
+		return
+	}
+	account, err := accounts.get(username)
+	if err == errInvalidCredentials {
+		unauthorized(w, "Account no longer exists")
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load account")
+		return
+	}
+	jsonResponse(w, account)
+}
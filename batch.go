@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+)
+
+// maxBatchSize is the largest number of games a single /new/batch call creates
+const maxBatchSize = 100
+
+type (
+	// BatchRequest creates either Count games sharing the embedded config, or
+	// one game per entry in Games
+	BatchRequest struct {
+		GameConfig
+		Count int          `json:"count"`
+		Games []GameConfig `json:"games"`
+	}
+
+	BatchResponse struct {
+		Games []GameState `json:"games"`
+	}
+)
+
+// newBatchHandler creates several games in one call
+func newBatchHandler(w http.ResponseWriter, r *http.Request) {
+	var req BatchRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	configs := req.Games
+	if len(configs) > 0 && req.Count > 0 {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+			"Specify either count or games, not both")
+		return
+	}
+	if len(configs) == 0 {
+		configs = make([]GameConfig, req.Count)
+		for i := range configs {
+			configs[i] = req.GameConfig
+		}
+	}
+
+	if len(configs) == 0 || len(configs) > maxBatchSize {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+			"Batch size must be between 1 and %d, got %d", maxBatchSize, len(configs)))
+		return
+	}
+
+	for i, config := range configs {
+		if code, message := gameConfigError(config); code != "" {
+			jsonError(w, http.StatusBadRequest, code, fmt.Sprintf("games[%d]: %s", i, message))
+			return
+		}
+	}
+
+	created := make([]GameState, len(configs))
+	for i, config := range configs {
+		var err error
+		created[i], err = createGame(r.Context(), config)
+		if err != nil {
+			discardGames(r.Context(), created[:i+1])
+			storeError(w, err, created[i].GameID)
+			return
+		}
+	}
+
+	jsonResponse(w, BatchResponse{Games: created})
+}
+
+// discardGames deletes the games of a batch that failed part way, so that a
+// failed batch leaves no games behind that the client never learns about
+func discardGames(ctx context.Context, created []GameState) {
+	for _, state := range created {
+		if state.GameID == "" {
+			continue
+		}
+		if err := games.Delete(ctx, state.GameID); err != nil && !errors.Is(err, errGameNotFound) {
+			log.Printf("deleting game %s of a failed batch: %v", state.GameID, err)
+		}
+	}
+}
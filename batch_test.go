@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// failingStore stores the first saves games and fails to save any more
+type failingStore struct {
+	*memoryStore
+	saves int
+}
+
+func (s *failingStore) Save(ctx context.Context, state GameState) error {
+	if s.saves == 0 {
+		return errors.New("store unavailable")
+	}
+	s.saves--
+	return s.memoryStore.Save(ctx, state)
+}
+
+func TestBatchFailureLeavesNoGames(t *testing.T) {
+	h := newTestAPI(t)
+	store := &failingStore{memoryStore: newMemoryStore(), saves: 2}
+	games = store
+
+	rec := do(t, h, http.MethodPost, "/v1/games/batch", "",
+		BatchRequest{GameConfig: GameConfig{Width: 10, Height: 10}, Count: 3})
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusServiceUnavailable, rec.Body)
+	}
+	if n := len(store.games); n != 0 {
+		t.Errorf("%d games left in the store after the batch failed", n)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// BotDifficulty names a preset strategy of the bot opponent
+type BotDifficulty string
+
+const (
+	BotEasy   BotDifficulty = "easy"
+	BotMedium BotDifficulty = "medium"
+	BotHard   BotDifficulty = "hard"
+)
+
+// Bot steers the opponent of a versus game in place of a second player
+type Bot struct {
+	Difficulty BotDifficulty `json:"difficulty"`
+
+	// ReactionTicks is how many ticks the bot keeps its heading after each
+	// decision
+	ReactionTicks int `json:"reactionTicks"`
+
+	// MistakeChance is the percentage of decisions taken at random
+	MistakeChance int `json:"mistakeChance"`
+
+	// Lookahead is how many moves ahead the bot checks that it survives
+	Lookahead int `json:"lookahead"`
+}
+
+// botDifficulties are the bot presets, from practice to competition
+var botDifficulties = []Bot{
+	{Difficulty: BotEasy, ReactionTicks: 2, MistakeChance: 20, Lookahead: 1},
+	{Difficulty: BotMedium, ReactionTicks: 1, MistakeChance: 5, Lookahead: 3},
+	{Difficulty: BotHard, ReactionTicks: 0, MistakeChance: 0, Lookahead: 6},
+}
+
+// BotRequest is the body of POST /v1/games/{id}/bot
+type BotRequest struct {
+	Difficulty BotDifficulty `json:"difficulty"`
+}
+
+// BotsResponse is the body of GET /v1/bots
+type BotsResponse struct {
+	Bots []Bot `json:"bots"`
+}
+
+// botFor returns the preset of the given difficulty
+func botFor(difficulty BotDifficulty) (Bot, bool) {
+	for _, bot := range botDifficulties {
+		if bot.Difficulty == difficulty {
+			return bot, true
+		}
+	}
+	return Bot{}, false
+}
+
+// botsHandler lists the bot presets
+func botsHandler(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, BotsResponse{Bots: botDifficulties})
+}
+
+// botHandler makes a bot the opponent of a versus game and starts it, or
+// changes the difficulty of the bot already playing it
+func botHandler(w http.ResponseWriter, r *http.Request) {
+	var req BotRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Difficulty == "" {
+		req.Difficulty = BotMedium
+	}
+	bot, ok := botFor(req.Difficulty)
+	if !ok {
+		supported := make([]string, len(botDifficulties))
+		for i, bot := range botDifficulties {
+			supported[i] = string(bot.Difficulty)
+		}
+		jsonResponseWithStatus(w, ErrorResponse{
+			Code:      CodeInvalidRequest,
+			Message:   fmt.Sprintf("Unknown difficulty: %s", req.Difficulty),
+			Supported: supported,
+		}, http.StatusBadRequest)
+		return
+	}
+
+	gameID := chi.URLParam(r, "id")
+	var botErr error
+	_, err := games.Update(r.Context(), gameID, func(state GameState) GameState {
+		botErr = nil
+		switch {
+		case !state.Versus:
+			botErr = errNotVersus
+		case state.Opponent != nil && state.Bot == nil:
+			botErr = errGameFull
+		case state.Opponent == nil:
+			state = addOpponent(state)
+			fallthrough
+		default:
+			state.Bot = &bot
+			state = touch(state)
+		}
+		return state
+	})
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+
+	switch botErr {
+	case errNotVersus:
+		jsonError(w, http.StatusConflict, CodeNotVersus,
+			"Game was not created as a versus game")
+		return
+	case errGameFull:
+		jsonError(w, http.StatusConflict, CodeGameFull,
+			fmt.Sprintf("Game %s already has two players", gameID))
+		return
+	}
+
+	state, err := loop.start(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+
+	jsonResponse(w, state)
+}
+
+// botTick returns the direction the bot steers the opponent in on the next
+// tick. Between decisions it keeps its heading; otherwise it takes the move
+// that survives the most of its lookahead, breaking ties towards the nearest
+// fruit, unless it makes a mistake and picks any move that is not a reversal.
+// The player's snake is treated as standing still.
+func botTick(state GameState) Tick {
+	bot, snake := *state.Bot, *state.Opponent
+	if bot.ReactionTicks > 0 && state.TicksPlayed%(bot.ReactionTicks+1) != 0 {
+		return heading(snake)
+	}
+
+	var moves []Tick
+	for _, tick := range hintTicks {
+		if isValidMove(GameState{Snake: snake}, GameState{Snake: Snake{VelX: tick.VelX, VelY: tick.VelY}}) {
+			moves = append(moves, tick)
+		}
+	}
+	// Mistakes are drawn from the inverted seed, apart from the fruit
+	// spawns, so replays of bot games stay reproducible
+	rng := seededRand(^state.Seed, uint64(state.TicksPlayed))
+	if rng.Intn(100) < bot.MistakeChance {
+		return moves[rng.Intn(len(moves))]
+	}
+
+	best, bestSurvived, bestDistance := heading(snake), -1, 0
+	for _, tick := range moves {
+		next := wrapSnake(state, moveSnake(snake, tick))
+		survived := botSurvival(state, next, bot.Lookahead)
+		distance := botFruitDistance(state, next.Position)
+		if survived > bestSurvived || survived == bestSurvived && distance < bestDistance {
+			best, bestSurvived, bestDistance = tick, survived, distance
+		}
+	}
+	return best
+}
+
+// botSurvival returns how many of the next depth moves, starting with the
+// one that brought the opponent to snake, it can survive at best
+func botSurvival(state GameState, snake Snake, depth int) int {
+	if depth == 0 {
+		return 0
+	}
+	if versusCollision(state, snake, state.Snake) != "" {
+		return 0
+	}
+	best := 0
+	for _, tick := range hintTicks {
+		if !isValidMove(GameState{Snake: snake}, GameState{Snake: Snake{VelX: tick.VelX, VelY: tick.VelY}}) {
+			continue
+		}
+		best = max(best, botSurvival(state, wrapSnake(state, moveSnake(snake, tick)), depth-1))
+		if best == depth-1 {
+			break
+		}
+	}
+	return best + 1
+}
+
+// botFruitDistance is the distance from pos to the nearest fruit that is not
+// poison, or the size of the board if there is none
+func botFruitDistance(state GameState, pos Position) int {
+	best := state.Width + state.Height
+	for _, fruit := range state.Fruits {
+		if fruit.Type != FruitPoison {
+			best = min(best, hintDistance(state, pos, fruit.Position))
+		}
+	}
+	return best
+}
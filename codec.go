@@ -0,0 +1,123 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// msgpackMediaType selects MessagePack instead of JSON for request and
+// response bodies; application/x-msgpack is accepted as an alias
+const msgpackMediaType = "application/msgpack"
+
+// codec reads and writes bodies in one wire format. Both formats use the
+// json struct tags of the API types.
+type codec struct {
+	mediaType string
+	encode    func(w io.Writer, v any) error
+	decode    func(r io.Reader, v any) error
+}
+
+var (
+	jsonCodec = codec{
+		mediaType: "application/json",
+		encode:    func(w io.Writer, v any) error { return json.NewEncoder(w).Encode(v) },
+		decode:    func(r io.Reader, v any) error { return json.NewDecoder(r).Decode(v) },
+	}
+
+	msgpackCodec = codec{
+		mediaType: msgpackMediaType,
+		encode: func(w io.Writer, v any) error {
+			enc := msgpack.NewEncoder(w)
+			enc.SetCustomStructTag("json")
+			return enc.Encode(v)
+		},
+		decode: func(r io.Reader, v any) error {
+			dec := msgpack.NewDecoder(r)
+			dec.SetCustomStructTag("json")
+			return dec.Decode(v)
+		},
+	}
+)
+
+// codecs are the codecs by the media types naming them
+var codecs = map[string]codec{
+	"application/json":      jsonCodec,
+	msgpackMediaType:        msgpackCodec,
+	"application/x-msgpack": msgpackCodec,
+}
+
+// errUnsupportedBody is returned by decodeBody for a body in a media type no
+// codec decodes, such as protobuf on a route that only takes codec bodies
+var errUnsupportedBody = errors.New("unsupported request body type")
+
+// codecMediaTypes are the media types decodeBody decodes
+var codecMediaTypes = []string{"application/json", msgpackMediaType, "application/x-msgpack"}
+
+// acceptedTypes returns the media types listed in the Accept header, in
+// order, leaving out those given a quality of 0
+func acceptedTypes(r *http.Request) []string {
+	var types []string
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err == nil && params["q"] != "0" {
+			types = append(types, mediaType)
+		}
+	}
+	return types
+}
+
+// negotiate picks the codec of the response from the Accept header, JSON
+// unless another supported format is listed first, and records it as the
+// response Content-Type for jsonResponse to encode with. Handlers writing
+// other formats set their own Content-Type.
+func negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chosen := jsonCodec
+		for _, mediaType := range acceptedTypes(r) {
+			if c, ok := codecs[mediaType]; ok {
+				chosen = c
+				break
+			}
+		}
+		w.Header().Set("Content-Type", chosen.mediaType)
+		w.Header().Add("Vary", "Accept")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// responseCodec returns the codec for the response Content-Type, or JSON if
+// it has none of the codec media types
+func responseCodec(w http.ResponseWriter) codec {
+	if c, ok := codecs[w.Header().Get("Content-Type")]; ok {
+		return c
+	}
+	return jsonCodec
+}
+
+// encodeBody writes v in the format chosen for the response
+func encodeBody(w http.ResponseWriter, v any) error {
+	c := responseCodec(w)
+	w.Header().Set("Content-Type", c.mediaType)
+	return c.encode(w, v)
+}
+
+// decodeBody reads the request body into v in the format named by its
+// Content-Type, JSON if it has none, or returns errUnsupportedBody if no
+// codec reads that format
+func decodeBody(r *http.Request, v any) error {
+	c := jsonCodec
+	if mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type")); err == nil {
+		found, ok := codecs[mediaType]
+		if !ok {
+			return errUnsupportedBody
+		}
+		c = found
+	}
+	return c.decode(r.Body, v)
+}
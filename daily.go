@@ -0,0 +1,153 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// dailyDateLayout is how challenge days are written
+	dailyDateLayout = "2006-01-02"
+
+	// dailyTickLimit gives every daily game the same number of ticks to
+	// score in
+	dailyTickLimit = 1000
+)
+
+// dailyConfig is the game every daily challenge is; only its seed changes
+// from one day to the next
+var dailyConfig = GameConfig{
+	Width:        20,
+	Height:       20,
+	Obstacles:    12,
+	FruitChances: FruitChances{Golden: 10, Poison: 5},
+	TickLimit:    dailyTickLimit,
+}
+
+// DailyLeaderboardResponse holds the best score of each player in the daily
+// challenge of Date
+type DailyLeaderboardResponse struct {
+	Date   string       `json:"date"`
+	Scores []ScoreEntry `json:"scores"`
+}
+
+// dailyDate returns the challenge day of t, which rolls over at midnight UTC
+// like the daily leaderboard window
+func dailyDate(t time.Time) string {
+	return t.UTC().Format(dailyDateLayout)
+}
+
+// dailySeed derives the seed of a day's challenge from its date, so every
+// server gives everyone the same board, obstacles and fruit sequence
+func dailySeed(date string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("daily:" + date))
+	if seed := int64(h.Sum64()); seed != 0 {
+		return seed
+	}
+	return 1
+}
+
+// openDailyScores creates the daily leaderboard table in db if needed
+func openDailyScores(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS daily_scores (
+			game_id    TEXT PRIMARY KEY REFERENCES scores (game_id),
+			day        TEXT NOT NULL,
+			player     TEXT NOT NULL,
+			score      INTEGER NOT NULL,
+			created_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS daily_scores_by_day ON daily_scores (day, score DESC, created_at);
+	`)
+	return err
+}
+
+// addDaily records a score of a daily challenge game on the leaderboard of
+// its day, as part of the transaction recording it on the main one
+func addDaily(tx *sql.Tx, date string, entry ScoreEntry) error {
+	_, err := tx.Exec(
+		`INSERT INTO daily_scores (game_id, day, player, score, created_at) VALUES (?, ?, ?, ?, ?)
+		 ON CONFLICT DO NOTHING`,
+		entry.GameID, date, entry.Player, entry.Score, entry.CreatedAt.UnixMilli())
+	return err
+}
+
+// topDaily returns the n best players of a day's challenge with their
+// highest score, earliest first among equal scores
+func (l *leaderboard) topDaily(date string, n int) ([]ScoreEntry, error) {
+	rows, err := l.db.Query(
+		`SELECT game_id, player, score, created_at, anonymous FROM (
+			SELECT game_id, daily_scores.player, daily_scores.score, daily_scores.created_at, anonymous,
+				ROW_NUMBER() OVER (PARTITION BY daily_scores.player, anonymous
+					ORDER BY daily_scores.score DESC, daily_scores.created_at) AS attempt
+			FROM daily_scores JOIN scores USING (game_id) WHERE day = ?
+		 ) WHERE attempt = 1 ORDER BY score DESC, created_at LIMIT ?`, date, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []ScoreEntry{}
+	for rows.Next() {
+		entry := ScoreEntry{Width: dailyConfig.Width, Height: dailyConfig.Height}
+		var createdAt int64
+		if err := rows.Scan(&entry.GameID, &entry.Player, &entry.Score, &createdAt, &entry.Anonymous); err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = time.UnixMilli(createdAt).UTC()
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// dailyHandler creates a game of today's challenge. Its score is submitted
+// with POST /v1/scores like any other, and also counts for the day.
+func dailyHandler(w http.ResponseWriter, r *http.Request) {
+	date := dailyDate(clock())
+	config := dailyConfig
+	config.Seed, config.daily = dailySeed(date), date
+
+	gameState, err := createGame(r.Context(), config)
+	if err != nil {
+		storeError(w, err, gameState.GameID)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	jsonResponse(w, gameState)
+}
+
+// dailyLeaderboardHandler returns the best players of the challenge of
+// ?date=YYYY-MM-DD, today by default, ?limit=N of them
+func dailyLeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = dailyDate(clock())
+	} else if _, err := time.Parse(dailyDateLayout, date); err != nil {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+			fmt.Sprintf("Invalid date: %s, expected YYYY-MM-DD", date))
+		return
+	}
+
+	limit := defaultLeaderboardSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLeaderboardSize {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+				"limit must be between 1 and %d", maxLeaderboardSize))
+			return
+		}
+	}
+
+	entries, err := scores.topDaily(date, limit)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load daily leaderboard")
+		return
+	}
+	jsonResponse(w, DailyLeaderboardResponse{Date: date, Scores: entries})
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// ErrorCode is a stable, machine-readable identifier included in every error
+// body. Clients branch on these rather than on HTTP status alone, so existing
+// values must never be renamed or reused.
+type ErrorCode string
+
+const (
+	CodeInvalidRequest   ErrorCode = "E_INVALID_REQUEST"
+	CodeInvalidBoard     ErrorCode = "E_INVALID_BOARD"
+	CodeBoardTooLarge    ErrorCode = "E_BOARD_TOO_LARGE"
+	CodeGameNotFound     ErrorCode = "E_GAME_NOT_FOUND"
+	CodeInvalidGameID    ErrorCode = "E_INVALID_GAME_ID"
+	CodeInvalidTick      ErrorCode = "E_INVALID_TICK"
+	CodeTooManyTicks     ErrorCode = "E_TOO_MANY_TICKS"
+	CodeBodyTooLarge     ErrorCode = "E_BODY_TOO_LARGE"
+	CodeInvalidSignature ErrorCode = "E_INVALID_SIGNATURE"
+	CodeGameOver         ErrorCode = "E_GAME_OVER"
+	CodeGameLive         ErrorCode = "E_GAME_LIVE"
+	CodeGameNotLive      ErrorCode = "E_GAME_NOT_LIVE"
+	CodeGameNotOver      ErrorCode = "E_GAME_NOT_OVER"
+	CodeGamePaused       ErrorCode = "E_GAME_PAUSED"
+	CodeQueueFull        ErrorCode = "E_DIRECTION_QUEUE_FULL"
+	CodeNotVersus        ErrorCode = "E_NOT_VERSUS"
+	CodeGameFull         ErrorCode = "E_GAME_FULL"
+	CodeNoOpponent       ErrorCode = "E_NO_OPPONENT"
+	CodeScoreExists      ErrorCode = "E_SCORE_EXISTS"
+	CodeVersionConflict  ErrorCode = "E_VERSION_CONFLICT"
+	CodeUnsupportedType  ErrorCode = "E_UNSUPPORTED_MEDIA_TYPE"
+	CodeRateLimited      ErrorCode = "E_RATE_LIMITED"
+	CodeUnavailable      ErrorCode = "E_UNAVAILABLE"
+	CodeInternal         ErrorCode = "E_INTERNAL"
+
+	CodeTournamentNotFound ErrorCode = "E_TOURNAMENT_NOT_FOUND"
+	CodeTournamentStarted  ErrorCode = "E_TOURNAMENT_STARTED"
+	CodeTournamentFull     ErrorCode = "E_TOURNAMENT_FULL"
+	CodeNotEnoughPlayers   ErrorCode = "E_NOT_ENOUGH_PLAYERS"
+	CodePlayerRegistered   ErrorCode = "E_PLAYER_REGISTERED"
+	CodeMatchNotFound      ErrorCode = "E_MATCH_NOT_FOUND"
+	CodeMatchNotReady      ErrorCode = "E_MATCH_NOT_READY"
+	CodeMatchDecided       ErrorCode = "E_MATCH_DECIDED"
+	CodeMatchDraw          ErrorCode = "E_MATCH_DRAW"
+	CodeGameRecorded       ErrorCode = "E_GAME_RECORDED"
+	CodeMatchPlayers       ErrorCode = "E_MATCH_PLAYERS"
+	CodeTicketNotFound     ErrorCode = "E_TICKET_NOT_FOUND"
+	CodeUnauthorized       ErrorCode = "E_UNAUTHORIZED"
+	CodeForbidden          ErrorCode = "E_FORBIDDEN"
+	CodeAccountExists      ErrorCode = "E_ACCOUNT_EXISTS"
+	CodeInvalidCredentials ErrorCode = "E_INVALID_CREDENTIALS"
+	CodeKeyNotFound        ErrorCode = "E_KEY_NOT_FOUND"
+	CodeKeyExists          ErrorCode = "E_KEY_EXISTS"
+	CodeProviderNotFound   ErrorCode = "E_PROVIDER_NOT_FOUND"
+	CodeProviderFailed     ErrorCode = "E_PROVIDER_FAILED"
+	CodeWebhookNotFound    ErrorCode = "E_WEBHOOK_NOT_FOUND"
+	CodeTooManyWebhooks    ErrorCode = "E_TOO_MANY_WEBHOOKS"
+	CodeDeliveryNotFound   ErrorCode = "E_DELIVERY_NOT_FOUND"
+)
+
+// ErrorResponse is the JSON body written for every non-2xx response
+type ErrorResponse struct {
+	Code    ErrorCode  `json:"code"`
+	Message string     `json:"message"`
+	State   *GameState `json:"state,omitempty"`
+
+	// Reason tells why a rejected tick batch stopped being applied
+	Reason GameOverReason `json:"reason,omitempty"`
+
+	// Supported lists the accepted media types on 415 responses
+	Supported []string `json:"supported,omitempty"`
+}
+
+// bodyError writes the error for a request body that failed to decode: 413
+// if it exceeded its size limit, 415 if it is in a format the route does not
+// read, or 400 otherwise
+func bodyError(w http.ResponseWriter, err error) {
+	if errors.Is(err, errUnsupportedBody) {
+		jsonResponseWithStatus(w, ErrorResponse{
+			Code:      CodeUnsupportedType,
+			Message:   "Unsupported Content-Type for this route",
+			Supported: codecMediaTypes,
+		}, http.StatusUnsupportedMediaType)
+		return
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		jsonError(w, http.StatusRequestEntityTooLarge, CodeBodyTooLarge,
+			fmt.Sprintf("Request body too large: max=%d bytes", tooLarge.Limit))
+		return
+	}
+	jsonError(w, http.StatusBadRequest, CodeInvalidRequest, "Invalid request body")
+}
+
+// gameIDError writes the 400 for a malformed game ID
+func gameIDError(w http.ResponseWriter, id string) {
+	jsonError(w, http.StatusBadRequest, CodeInvalidGameID, fmt.Sprintf("Invalid game ID: %q", id))
+}
+
+// jsonError writes an error body with the given status code and error code
+func jsonError(w http.ResponseWriter, statusCode int, code ErrorCode, message string) {
+	jsonResponseWithStatus(w, ErrorResponse{Code: code, Message: message}, statusCode)
+}
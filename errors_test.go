@@ -0,0 +1,169 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestErrorCodes(t *testing.T) {
+	h := newTestAPI(t)
+	alice := register(t, h, "alice")
+	bob := register(t, h, "bob")
+
+	game := newGame(t, h, alice, GameConfig{Width: 10, Height: 10})
+	live := newGame(t, h, alice, GameConfig{Width: 10, Height: 10, Live: true})
+	paused := newGame(t, h, alice, GameConfig{Width: 10, Height: 10})
+	if rec := do(t, h, http.MethodPost, "/v1/games/"+paused.GameID+"/pause", alice, nil); rec.Code != http.StatusOK {
+		t.Fatalf("pausing game: %d %s", rec.Code, rec.Body)
+	}
+	over := finishedGame(t, h, alice)
+
+	tooMany := make([]Tick, defaultMaxTicks+1)
+	for i := range tooMany {
+		tooMany[i] = Tick{VelX: 1}
+	}
+
+	tests := []struct {
+		name                string
+		method, path, token string
+		body                any
+		// header is added to the request; a Content-Type in it replaces
+		// the JSON of body with raw
+		header http.Header
+		raw    string
+		status int
+		code   ErrorCode
+	}{
+		{name: "malformed body", method: http.MethodPost, path: "/v1/games", token: alice,
+			header: http.Header{"Content-Type": {"application/json"}}, raw: "{",
+			status: http.StatusBadRequest, code: CodeInvalidRequest},
+		{name: "unsupported media type", method: http.MethodPost, path: "/v1/games", token: alice,
+			header: http.Header{"Content-Type": {"text/plain"}}, raw: "width=10",
+			status: http.StatusUnsupportedMediaType, code: CodeUnsupportedType},
+		{name: "protobuf on a route without protobuf", method: http.MethodPost, path: "/v1/games", token: alice,
+			header: http.Header{"Content-Type": {protobufMediaType}}, raw: "\x08\x0a",
+			status: http.StatusUnsupportedMediaType, code: CodeUnsupportedType},
+		{name: "protobuf direction", method: http.MethodPatch, path: "/v1/games/" + live.GameID + "/direction", token: alice,
+			header: http.Header{"Content-Type": {protobufMediaType}}, raw: `{"velX":0,"velY":1}`,
+			status: http.StatusUnsupportedMediaType, code: CodeUnsupportedType},
+		{name: "empty board", method: http.MethodPost, path: "/v1/games", token: alice,
+			body: GameConfig{}, status: http.StatusBadRequest, code: CodeInvalidBoard},
+		{name: "board too large", method: http.MethodPost, path: "/v1/games", token: alice,
+			body: GameConfig{Width: maxBoardSize + 1, Height: 10}, status: http.StatusBadRequest, code: CodeBoardTooLarge},
+		{name: "malformed game ID", method: http.MethodGet, path: "/v1/games/nope",
+			status: http.StatusBadRequest, code: CodeInvalidGameID},
+		{name: "unknown game", method: http.MethodGet, path: "/v1/games/" + uuid.NewString(),
+			status: http.StatusNotFound, code: CodeGameNotFound},
+		{name: "too many ticks", method: http.MethodPost, path: "/v1/games/" + game.GameID + "/validate", token: alice,
+			body: ValidateRequest{Ticks: tooMany}, status: http.StatusRequestEntityTooLarge, code: CodeTooManyTicks},
+		{name: "reversal", method: http.MethodPost, path: "/v1/games/" + game.GameID + "/validate", token: alice,
+			body: ValidateRequest{Ticks: []Tick{{VelX: -1}}}, status: http.StatusBadRequest, code: CodeInvalidTick},
+		{name: "validate live game", method: http.MethodPost, path: "/v1/games/" + live.GameID + "/validate",
+			token: alice, body: ValidateRequest{Ticks: []Tick{{VelX: 1}}}, status: http.StatusConflict, code: CodeGameLive},
+		{name: "validate paused game", method: http.MethodPost, path: "/v1/games/" + paused.GameID + "/validate",
+			token: alice, body: ValidateRequest{Ticks: []Tick{{VelX: 1}}}, status: http.StatusConflict, code: CodeGamePaused},
+		{name: "pause finished game", method: http.MethodPost, path: "/v1/games/" + over + "/pause", token: alice,
+			status: http.StatusConflict, code: CodeGameOver},
+		{name: "hint for finished game", method: http.MethodGet, path: "/v1/games/" + over + "/hint", token: alice,
+			status: http.StatusConflict, code: CodeGameOver},
+		{name: "stale If-Match", method: http.MethodPost, path: "/v1/games/" + game.GameID + "/validate", token: alice,
+			body: ValidateRequest{Ticks: []Tick{{VelX: 1}}}, header: http.Header{"If-Match": {`"stale"`}},
+			status: http.StatusPreconditionFailed, code: CodeVersionConflict},
+		{name: "validate game of another account", method: http.MethodPost, path: "/v1/games/" + game.GameID + "/validate",
+			token: bob, body: ValidateRequest{Ticks: []Tick{{VelX: 1}}}, status: http.StatusForbidden, code: CodeForbidden},
+		{name: "delete game of another account", method: http.MethodDelete, path: "/v1/games/" + game.GameID,
+			token: bob, status: http.StatusForbidden, code: CodeForbidden},
+		{name: "steer game that is not live", method: http.MethodPatch, path: "/v1/games/" + game.GameID + "/direction",
+			token: alice, body: DirectionRequest{Tick: Tick{VelY: 1}}, status: http.StatusConflict, code: CodeGameNotLive},
+		{name: "join game that is not versus", method: http.MethodPost, path: "/v1/games/" + game.GameID + "/join",
+			token: bob, status: http.StatusConflict, code: CodeNotVersus},
+		{name: "anonymous tournament", method: http.MethodPost, path: "/v1/tournaments",
+			body: map[string]string{"name": "cup"}, status: http.StatusUnauthorized, code: CodeUnauthorized},
+		{name: "unknown tournament", method: http.MethodGet, path: "/v1/tournaments/" + uuid.NewString(),
+			status: http.StatusNotFound, code: CodeTournamentNotFound},
+		{name: "unknown ticket", method: http.MethodGet, path: "/v1/matchmaking/queue/" + uuid.NewString(), token: alice,
+			status: http.StatusNotFound, code: CodeTicketNotFound},
+		{name: "unknown webhook", method: http.MethodDelete, path: "/v1/webhooks/" + uuid.NewString(), token: alice,
+			status: http.StatusNotFound, code: CodeWebhookNotFound},
+		{name: "taken username", method: http.MethodPost, path: "/v1/auth/register",
+			body:   map[string]string{"username": "alice", "password": "password1"},
+			status: http.StatusConflict, code: CodeAccountExists},
+		{name: "wrong password", method: http.MethodPost, path: "/v1/auth/login",
+			body:   map[string]string{"username": "alice", "password": "password2"},
+			status: http.StatusUnauthorized, code: CodeInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(t, tt.method, tt.path, tt.token, tt.body)
+			if tt.header.Get("Content-Type") != "" {
+				req.Body, req.ContentLength = io.NopCloser(strings.NewReader(tt.raw)), int64(len(tt.raw))
+			}
+			for name, values := range tt.header {
+				req.Header[name] = values
+			}
+			rec := send(h, req)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			var resp ErrorResponse
+			decode(t, rec, &resp)
+			if resp.Code != tt.code {
+				t.Errorf("code = %s, want %s: %s", resp.Code, tt.code, resp.Message)
+			}
+		})
+	}
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	h := newTestAPI(t)
+	alice := register(t, h, "alice")
+	game := newGame(t, h, alice, GameConfig{Width: 10, Height: 10})
+
+	defer func(limit int64) { maxBodyBytes = limit }(maxBodyBytes)
+	maxBodyBytes = 64
+	rec := do(t, h, http.MethodPost, "/v1/games/"+game.GameID+"/validate", alice,
+		ValidateRequest{Ticks: make([]Tick, 100)})
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusRequestEntityTooLarge, rec.Body)
+	}
+	var resp ErrorResponse
+	decode(t, rec, &resp)
+	if resp.Code != CodeBodyTooLarge {
+		t.Errorf("code = %s, want %s", resp.Code, CodeBodyTooLarge)
+	}
+}
+
+func TestValidateIfMatch(t *testing.T) {
+	h := newTestAPI(t)
+	alice := register(t, h, "alice")
+	game := newGame(t, h, alice, GameConfig{Width: 10, Height: 10})
+	path := "/v1/games/" + game.GameID + "/validate"
+	ticks := ValidateRequest{Ticks: []Tick{{VelX: 0, VelY: 1}}}
+
+	validate := func(etag string) *httptest.ResponseRecorder {
+		req := newRequest(t, http.MethodPost, path, alice, ticks)
+		req.Header.Set("If-Match", etag)
+		return send(h, req)
+	}
+
+	etag := do(t, h, http.MethodGet, "/v1/games/"+game.GameID, alice, nil).Header().Get("ETag")
+	if rec := validate(etag); rec.Code != http.StatusOK {
+		t.Fatalf("matching If-Match: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	rec := validate(etag)
+	if rec.Code != http.StatusPreconditionFailed {
+		t.Fatalf("stale If-Match: status = %d, want %d: %s", rec.Code, http.StatusPreconditionFailed, rec.Body)
+	}
+	var resp ErrorResponse
+	decode(t, rec, &resp)
+	if resp.Code != CodeVersionConflict || resp.State == nil || resp.State.TicksPlayed != 1 {
+		t.Errorf("stale If-Match: got %s with state %+v, want %s with the current state",
+			resp.Code, resp.State, CodeVersionConflict)
+	}
+}
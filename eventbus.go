@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+// GameEventType names a game lifecycle event published on the event bus
+type GameEventType string
+
+const (
+	GameCreated    GameEventType = "game.created"
+	GameTick       GameEventType = "game.tick"
+	GameFruitEaten GameEventType = "game.fruit_eaten"
+	GameOver       GameEventType = "game.over"
+)
+
+// GameEvent is what the event bus publishes for analytics pipelines. Ticks
+// counts the ticks applied by a game.tick, which are several when a validate
+// request submits them at once; Player and Fruits tell which snake ate how
+// many fruits in a game.fruit_eaten.
+type GameEvent struct {
+	ID            string         `json:"id"`
+	Type          GameEventType  `json:"type"`
+	GameID        string         `json:"gameId"`
+	Time          time.Time      `json:"time"`
+	Players       []string       `json:"players,omitempty"`
+	Width         int            `json:"width"`
+	Height        int            `json:"height"`
+	Versus        bool           `json:"versus,omitempty"`
+	Daily         string         `json:"daily,omitempty"`
+	TicksPlayed   int            `json:"ticksPlayed"`
+	Score         int            `json:"score"`
+	OpponentScore int            `json:"opponentScore,omitempty"`
+	Ticks         int            `json:"ticks,omitempty"`
+	Player        int            `json:"player,omitempty"`
+	Fruits        int            `json:"fruits,omitempty"`
+	Reason        GameOverReason `json:"reason,omitempty"`
+	Winner        int            `json:"winner,omitempty"`
+}
+
+// EventBusConfig selects the broker game events are published to. Topic is
+// the Kafka topic, or the prefix of the NATS subjects, which are the topic
+// followed by the event type.
+type EventBusConfig struct {
+	Kind         string
+	NATSURL      string
+	KafkaBrokers string
+	Topic        string
+}
+
+// eventBus publishes game events without waiting for the broker, so a slow
+// or unreachable broker never holds up a game
+type eventBus interface {
+	publish(event GameEvent, payload []byte) error
+	close() error
+}
+
+// bus is the server-wide event bus, set up in main; it drops every event
+// until then
+var bus eventBus = noEventBus{}
+
+// setupEventBus connects to the broker of the given kind: none, nats or
+// kafka. The returned function flushes pending events on shutdown.
+func setupEventBus(config EventBusConfig) (func() error, error) {
+	var err error
+	switch config.Kind {
+	case "", "none":
+		return func() error { return nil }, nil
+	case "nats":
+		bus, err = newNATSBus(config.NATSURL, config.Topic)
+	case "kafka":
+		bus, err = newKafkaBus(config.KafkaBrokers, config.Topic)
+	default:
+		return nil, fmt.Errorf("unknown event bus %q, expected none, nats or kafka", config.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bus.close, nil
+}
+
+type noEventBus struct{}
+
+func (noEventBus) publish(GameEvent, []byte) error { return nil }
+func (noEventBus) close() error                    { return nil }
+
+// natsBus publishes each event type on its own subject
+type natsBus struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+func newNATSBus(url, prefix string) (*natsBus, error) {
+	conn, err := nats.Connect(url, nats.Name("snake-game-api"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+	return &natsBus{conn: conn, prefix: prefix}, nil
+}
+
+func (b *natsBus) publish(event GameEvent, payload []byte) error {
+	return b.conn.Publish(b.prefix+"."+string(event.Type), payload)
+}
+
+func (b *natsBus) close() error {
+	return b.conn.Drain()
+}
+
+// kafkaBus publishes every event to one topic, keyed by game so the events
+// of a game stay in order on their partition
+type kafkaBus struct {
+	writer *kafka.Writer
+}
+
+func newKafkaBus(brokers, topic string) (*kafkaBus, error) {
+	var addrs []string
+	for _, broker := range strings.Split(brokers, ",") {
+		if broker = strings.TrimSpace(broker); broker != "" {
+			addrs = append(addrs, broker)
+		}
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("no kafka brokers given")
+	}
+	return &kafkaBus{writer: &kafka.Writer{
+		Addr:         kafka.TCP(addrs...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		BatchTimeout: 100 * time.Millisecond,
+		Async:        true,
+		Completion: func(messages []kafka.Message, err error) {
+			if err != nil {
+				log.Printf("publishing %d events to kafka: %v", len(messages), err)
+			}
+		},
+	}}, nil
+}
+
+func (b *kafkaBus) publish(event GameEvent, payload []byte) error {
+	return b.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:     []byte(event.GameID),
+		Value:   payload,
+		Headers: []kafka.Header{{Key: "type", Value: []byte(event.Type)}},
+	})
+}
+
+func (b *kafkaBus) close() error {
+	return b.writer.Close()
+}
+
+// publishEvent fills in the event from the state of its game and hands it
+// to the bus
+func publishEvent(event GameEvent, state GameState) {
+	event.ID = uuid.NewString()
+	event.GameID = state.GameID
+	event.Time = time.Now().UTC()
+	event.Players = gamePlayers(state)
+	event.Width, event.Height = state.Width, state.Height
+	event.Versus, event.Daily = state.Versus, state.Daily
+	event.TicksPlayed = state.TicksPlayed
+	event.Score, event.OpponentScore = state.Score, state.OpponentScore
+
+	payload, err := json.Marshal(event)
+	if err == nil {
+		err = bus.publish(event, payload)
+	}
+	if err != nil {
+		eventsPublished.WithLabelValues(string(event.Type), "error").Inc()
+		log.Printf("publishing %s of game %s: %v", event.Type, state.GameID, err)
+		return
+	}
+	eventsPublished.WithLabelValues(string(event.Type), "ok").Inc()
+}
+
+// publishProgress publishes the events of the ticks that took a game from
+// before to after
+func publishProgress(before, after GameState) {
+	if ticks := after.TicksPlayed - before.TicksPlayed; ticks > 0 {
+		publishEvent(GameEvent{Type: GameTick, Ticks: ticks}, after)
+	}
+	if eaten := after.FruitsEaten - before.FruitsEaten; eaten > 0 {
+		publishEvent(GameEvent{Type: GameFruitEaten, Player: 1, Fruits: eaten}, after)
+	}
+	if eaten := after.OpponentFruitsEaten - before.OpponentFruitsEaten; eaten > 0 {
+		publishEvent(GameEvent{Type: GameFruitEaten, Player: 2, Fruits: eaten}, after)
+	}
+	if before.GameOverReason == "" && after.GameOverReason != "" {
+		publishEvent(GameEvent{Type: GameOver, Reason: after.GameOverReason, Winner: after.Winner}, after)
+	}
+}
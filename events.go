@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// sseHeartbeatInterval is how often an idle event stream sends a comment to
+// keep proxies from closing the connection
+const sseHeartbeatInterval = 15 * time.Second
+
+// Event names sent on the spectator stream
+const (
+	eventState    = "state"
+	eventTick     = "tick"
+	eventFruit    = "fruit"
+	eventGameOver = "gameover"
+)
+
+// eventsHandler streams the state changes of a game as Server-Sent Events:
+// the current state on connect, then a tick event per update, a fruit event
+// whenever a snake eats, and a final gameover event
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Streaming unsupported")
+		return
+	}
+
+	gameID := chi.URLParam(r, "id")
+	updates, unsubscribe := loop.subscribe(gameID)
+	defer unsubscribe()
+
+	state, err := games.Get(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+
+	clearDeadlines(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	writeEvent(w, eventState, state)
+	if state.GameOverReason != "" {
+		writeEvent(w, eventGameOver, state)
+		flusher.Flush()
+		return
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case next, ok := <-updates:
+			if !ok {
+				return
+			}
+			if next.Score > state.Score || next.OpponentScore > state.OpponentScore {
+				writeEvent(w, eventFruit, next)
+			}
+			writeEvent(w, eventTick, next)
+			if next.GameOverReason != "" {
+				writeEvent(w, eventGameOver, next)
+				flusher.Flush()
+				return
+			}
+			state = next
+		}
+		flusher.Flush()
+	}
+}
+
+// writeEvent writes a single Server-Sent Event with a JSON payload
+func writeEvent(w http.ResponseWriter, event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultFixtureSize is the board size used when a fixture request gives none
+const defaultFixtureSize = 10
+
+// fixtures builds games in precisely specified states for end-to-end tests
+// of client rendering and error handling
+var fixtures = map[string]func(boardSize Position) GameState{
+	// The snake sits on the right edge heading right: the next tick hits the wall
+	"about-to-die": func(boardSize Position) GameState {
+		state := initializeGame(GameConfig{Width: boardSize.X, Height: boardSize.Y})
+		state.Snake.Position = Position{X: boardSize.X - 1, Y: boardSize.Y / 2}
+		state.Fruits = []Fruit{{Position: Position{X: 0, Y: 0}, Type: FruitNormal}}
+		return state
+	},
+	// The fruit is directly ahead of the snake: the next tick eats it
+	"fruit-adjacent": func(boardSize Position) GameState {
+		state := initializeGame(GameConfig{Width: boardSize.X, Height: boardSize.Y})
+		state.Fruits = []Fruit{{
+			Position: Position{X: state.Snake.X + 1, Y: state.Snake.Y},
+			Type:     FruitNormal,
+		}}
+		return state
+	},
+}
+
+// fixtureHandler creates and stores a game in the named fixture state
+func fixtureHandler(w http.ResponseWriter, r *http.Request) {
+	name := chi.URLParam(r, "name")
+	build, ok := fixtures[name]
+	if !ok {
+		names := make([]string, 0, len(fixtures))
+		for n := range fixtures {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		jsonError(w, http.StatusNotFound, CodeInvalidRequest,
+			fmt.Sprintf("Unknown fixture %q, available: %v", name, names))
+		return
+	}
+
+	boardSize := Position{X: defaultFixtureSize, Y: defaultFixtureSize}
+	if v := r.URL.Query().Get("w"); v != "" {
+		boardSize.X, _ = strconv.Atoi(v)
+	}
+	if v := r.URL.Query().Get("h"); v != "" {
+		boardSize.Y, _ = strconv.Atoi(v)
+	}
+	if boardSize.X < 2 || boardSize.Y < 1 || boardSize.X > maxBoardSize || boardSize.Y > maxBoardSize {
+		jsonError(w, http.StatusBadRequest, CodeInvalidBoard, fmt.Sprintf(
+			"Invalid fixture board: width=%d, height=%d", boardSize.X, boardSize.Y))
+		return
+	}
+
+	state, err := persistNewGame(r.Context(), build(boardSize))
+	if err != nil {
+		storeError(w, err, state.GameID)
+		return
+	}
+
+	jsonResponse(w, state)
+}
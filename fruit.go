@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// FruitType decides what eating a fruit does
+type FruitType string
+
+const (
+	// FruitNormal is worth one point
+	FruitNormal FruitType = "normal"
+	// FruitGolden is worth goldenFruitPoints
+	FruitGolden FruitType = "golden"
+	// FruitPoison ends the game of the snake that eats it
+	FruitPoison FruitType = "poison"
+)
+
+// goldenFruitPoints is the score of a golden fruit
+const goldenFruitPoints = 5
+
+// Fruit is a fruit on the board
+type Fruit struct {
+	Position
+	Type FruitType `json:"type"`
+}
+
+// FruitChances are the percentages of fruits spawned golden or poisoned;
+// every other fruit is normal
+type FruitChances struct {
+	Golden int `json:"golden,omitempty"`
+	Poison int `json:"poison,omitempty"`
+}
+
+// points returns the score of eating the fruit
+func (f Fruit) points() int {
+	if f.Type == FruitGolden {
+		return goldenFruitPoints
+	}
+	return 1
+}
+
+// fruitChancesError returns an error code and message if the chances are
+// not percentages or add up to more than 100
+func fruitChancesError(chances FruitChances) (ErrorCode, string) {
+	if chances.Golden < 0 || chances.Poison < 0 || chances.Golden+chances.Poison > 100 {
+		return CodeInvalidRequest, fmt.Sprintf(
+			"Invalid fruit chances: golden=%d, poison=%d", chances.Golden, chances.Poison)
+	}
+	return "", ""
+}
+
+// pickFruitType draws the type of a new fruit from rng
+func pickFruitType(chances FruitChances, rng *rand.Rand) FruitType {
+	roll := rng.Intn(100)
+	switch {
+	case roll < chances.Golden:
+		return FruitGolden
+	case roll < chances.Golden+chances.Poison:
+		return FruitPoison
+	}
+	return FruitNormal
+}
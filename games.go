@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var errVersionConflict = errors.New("game state does not match If-Match")
+
+const (
+	// defaultWaitTimeout is how long a long-poll waits for a new version
+	// unless the client asks otherwise; maxWaitTimeout bounds what it may
+	// ask for
+	defaultWaitTimeout = 30 * time.Second
+	maxWaitTimeout     = time.Minute
+)
+
+// ifMatchKey is the context key of the If-Match precondition of a request
+// changing a game
+type ifMatchKey struct{}
+
+// ifMatch is an If-Match header, with the codec whose encoding of the state
+// its ETags were computed on
+type ifMatch struct {
+	header string
+	codec  codec
+}
+
+// dryRunKey is the context key marking changes to a game that are only
+// checked, not made
+type dryRunKey struct{}
+
+// withDryRun marks ctx as a dry run if r asks for one with ?dryRun=true, or
+// returns an error if the parameter is not a boolean
+func withDryRun(ctx context.Context, r *http.Request) (context.Context, error) {
+	v := r.URL.Query().Get("dryRun")
+	if v == "" {
+		return ctx, nil
+	}
+	dryRun, err := strconv.ParseBool(v)
+	if err != nil || !dryRun {
+		return ctx, err
+	}
+	return context.WithValue(ctx, dryRunKey{}, true), nil
+}
+
+// isDryRun returns true if the changes made with ctx must leave the game as
+// it is
+func isDryRun(ctx context.Context) bool {
+	dryRun, _ := ctx.Value(dryRunKey{}).(bool)
+	return dryRun
+}
+
+// getGameHandler returns the current state of a game. The ETag changes
+// whenever the state does, so polling clients sending If-None-Match only
+// download states they have not seen.
+func getGameHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	state, err := games.Get(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+
+	writeGame(w, r, state)
+}
+
+// writeGame writes a state with its ETag; a 304 if the request's
+// If-None-Match lists it
+func writeGame(w http.ResponseWriter, r *http.Request, state GameState) {
+	c := responseCodec(w)
+	etag, body, err := stateETag(c, state)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Encoding game state failed")
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", c.mediaType)
+	w.Write(body)
+}
+
+// waitHandler long-polls a game for clients without WebSocket or SSE
+// support. It answers with the state as soon as its version is past
+// ?sinceVersion=N, 0 by default, or the game is over, and with 304 once
+// ?timeout passes without a change.
+func waitHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	since, err := strconv.Atoi(r.URL.Query().Get("sinceVersion"))
+	if r.URL.Query().Get("sinceVersion") == "" {
+		since, err = 0, nil
+	}
+	if err != nil || since < 0 {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, "sinceVersion must be a version of the game")
+		return
+	}
+	timeout := defaultWaitTimeout
+	if v := r.URL.Query().Get("timeout"); v != "" {
+		timeout, err = time.ParseDuration(v)
+		if err != nil || timeout <= 0 || timeout > maxWaitTimeout {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("timeout must be a duration up to %s, such as 30s", maxWaitTimeout))
+			return
+		}
+	}
+
+	updates, unsubscribe := loop.subscribe(gameID)
+	defer unsubscribe()
+
+	state, err := games.Get(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+	clearDeadlines(w)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for state.Version <= since && state.GameOverReason == "" {
+		select {
+		case <-r.Context().Done():
+			return
+		case next, ok := <-updates:
+			if !ok {
+				// The game was removed, or ended without this update
+				if state, err = games.Get(r.Context(), gameID); err != nil {
+					storeError(w, err, gameID)
+					return
+				}
+				writeGame(w, r, state)
+				return
+			}
+			state = next
+		case <-timer.C:
+			// Changes made on other replicas are not published to this one
+			if state, err = games.Get(r.Context(), gameID); err != nil {
+				storeError(w, err, gameID)
+				return
+			}
+			if state.Version <= since {
+				w.Header().Set("Cache-Control", "no-cache")
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+	}
+	writeGame(w, r, state)
+}
+
+// stateETag returns the ETag of a state encoded with c, and the encoding
+func stateETag(c codec, state GameState) (string, []byte, error) {
+	var body bytes.Buffer
+	if err := c.encode(&body, state); err != nil {
+		return "", nil, err
+	}
+	sum := sha256.Sum256(body.Bytes())
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, body.Bytes(), nil
+}
+
+// withIfMatch makes the changes to a game made with ctx conditional on the
+// If-Match header of r, if it has one
+func withIfMatch(ctx context.Context, w http.ResponseWriter, r *http.Request) context.Context {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, ifMatchKey{}, ifMatch{header: header, codec: responseCodec(w)})
+}
+
+// preconditionHolds returns false if ctx carries an If-Match header that
+// lists none of the ETags of state
+func preconditionHolds(ctx context.Context, state GameState) bool {
+	condition, ok := ctx.Value(ifMatchKey{}).(ifMatch)
+	if !ok {
+		return true
+	}
+	etag, _, err := stateETag(condition.codec, state)
+	return err == nil && etagMatches(condition.header, etag)
+}
+
+// etagMatches returns true if an If-None-Match or If-Match header lists
+// etag, using the weak comparison
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// deleteGameHandler ends a game and removes it with its replay. Clients
+// watching the game receive a final state abandoned unless it was already
+// over. An abandoned game is finished like one that ended on its own: it
+// is recorded in the history of its players, published and notified.
+func deleteGameHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	state, err := games.Get(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+	if err := games.Delete(r.Context(), gameID); err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+
+	before := state
+	if state.GameOverReason == "" {
+		state.GameOverReason = ReasonAbandoned
+		gamesAbandoned.Inc()
+	}
+	loop.publish(gameID, state, true)
+	if before.GameOverReason == "" {
+		recordGame(state)
+		publishProgress(before, state)
+		notifyGameOver(state)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pauseHandler freezes a game: its clock stops and ticks are rejected
+// until it is resumed
+func pauseHandler(w http.ResponseWriter, r *http.Request) {
+	setStatus(w, r, GamePaused)
+}
+
+// resumeHandler lets a paused game accept ticks and advance again
+func resumeHandler(w http.ResponseWriter, r *http.Request) {
+	setStatus(w, r, GameActive)
+}
+
+// setStatus moves a game that is not over to the given status and tells
+// the clients watching it
+func setStatus(w http.ResponseWriter, r *http.Request, status GameStatus) {
+	gameID := chi.URLParam(r, "id")
+	var over bool
+	state, err := games.Update(r.Context(), gameID, func(state GameState) GameState {
+		over = state.GameOverReason != ""
+		if !over {
+			state.Status = status
+			state = touch(state)
+		}
+		return state
+	})
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+	if over {
+		jsonResponseWithStatus(w, ErrorResponse{
+			Code:    CodeGameOver,
+			Message: fmt.Sprintf("Game over: %s", state.GameOverReason),
+			State:   &state,
+		}, http.StatusConflict)
+		return
+	}
+
+	if status == GamePaused {
+		loop.pause(gameID)
+	} else {
+		loop.resume(state)
+	}
+	loop.publish(gameID, state, true)
+	jsonResponse(w, state)
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWait(t *testing.T) {
+	h := newTestAPI(t)
+	game := newGame(t, h, "", GameConfig{Width: 10, Height: 10})
+	path := "/v1/games/" + game.GameID
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+	}{
+		{name: "times out", query: "?sinceVersion=0&timeout=20ms", status: http.StatusNotModified},
+		{name: "invalid version", query: "?sinceVersion=latest", status: http.StatusBadRequest},
+		{name: "timeout too long", query: "?timeout=1h", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(t, h, http.MethodGet, path+"/wait"+tt.query, "", nil); rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+
+	waited := make(chan *httptest.ResponseRecorder)
+	go func() {
+		waited <- send(h, httptest.NewRequest(http.MethodGet, path+"/wait?sinceVersion=0&timeout=5s", nil))
+	}()
+	if rec := do(t, h, http.MethodPost, path+"/validate", "", ValidateRequest{Ticks: []Tick{{VelY: 1}}}); rec.Code != http.StatusOK {
+		t.Fatalf("validating: %d %s", rec.Code, rec.Body)
+	}
+	rec := <-waited
+	var state GameState
+	decode(t, rec, &state)
+	if rec.Code != http.StatusOK || state.Version != 1 || state.TicksPlayed != 1 {
+		t.Errorf("wait answered %d with version %d after %d ticks, want 200 with version 1 after 1",
+			rec.Code, state.Version, state.TicksPlayed)
+	}
+
+	// Rejected ticks change nothing, so the version stays
+	do(t, h, http.MethodPost, path+"/validate", "", ValidateRequest{Ticks: []Tick{{VelY: -1}}})
+	decode(t, do(t, h, http.MethodGet, path, "", nil), &state)
+	if state.Version != 1 {
+		t.Errorf("version = %d after rejected ticks, want 1", state.Version)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	h := newTestAPI(t)
+	game := newGame(t, h, "", GameConfig{Width: 10, Height: 10})
+	path := "/v1/games/" + game.GameID
+
+	rec := do(t, h, http.MethodPost, path+"/validate?dryRun=true", "", ValidateRequest{Ticks: []Tick{{VelX: 1}, {VelY: 1}}})
+	var preview ValidateResponse
+	decode(t, rec, &preview)
+	if rec.Code != http.StatusOK || !preview.DryRun || preview.TicksApplied != 2 || preview.TicksPlayed != 2 {
+		t.Errorf("dry run answered %d with %d ticks applied, %d played, dryRun=%t; want 200 with 2, 2, true",
+			rec.Code, preview.TicksApplied, preview.TicksPlayed, preview.DryRun)
+	}
+	var state GameState
+	decode(t, do(t, h, http.MethodGet, path, "", nil), &state)
+	if state.TicksPlayed != 0 || state.Version != 0 {
+		t.Errorf("dry run left the game at tick %d, version %d; want both 0", state.TicksPlayed, state.Version)
+	}
+	if rec := do(t, h, http.MethodPost, path+"/validate?dryRun=maybe", "", ValidateRequest{Ticks: []Tick{{VelX: 1}}}); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid dryRun: status = %d, want 400", rec.Code)
+	}
+
+	live := newGame(t, h, "", GameConfig{Width: 10, Height: 10, Live: true, Mode: ModeWrap})
+	path = "/v1/games/" + live.GameID + "/direction?dryRun=true"
+	tests := []struct {
+		name   string
+		tick   Tick
+		status int
+	}{
+		{name: "turn", tick: Tick{VelY: 1}, status: http.StatusOK},
+		{name: "reversal", tick: Tick{VelX: -1}, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rec := do(t, h, http.MethodPatch, path, "", DirectionRequest{Tick: tt.tick}); rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+	loop.mu.Lock()
+	queued := loop.directions[live.GameID][0]
+	loop.mu.Unlock()
+	if len(queued) != 0 {
+		t.Errorf("dry run queued %v", queued)
+	}
+}
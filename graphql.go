@@ -0,0 +1,522 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// graphqlSchemaSDL is the schema served at /graphql
+const graphqlSchemaSDL = `
+schema {
+	query: Query
+	mutation: Mutation
+}
+
+type Query {
+	game(id: ID!): Game
+	leaderboard(limit: Int! = 10, window: String! = "alltime"): [Score!]!
+	replay(id: ID!): Replay
+}
+
+type Mutation {
+	createGame(config: GameConfigInput!): Game!
+	submitTicks(id: ID!, ticks: [TickInput!]!): Validation!
+}
+` + graphqlTypesSDL
+
+// graphqlTypesSDL are the types shared by the GraphQL schemas. Fields mirror
+// the JSON bodies of the HTTP API; seeds are strings since GraphQL integers
+// are 32-bit.
+const graphqlTypesSDL = `
+type Position {
+	x: Int!
+	y: Int!
+}
+
+type Snake {
+	x: Int!
+	y: Int!
+	velX: Int!
+	velY: Int!
+	body: [Position!]!
+}
+
+type Fruit {
+	x: Int!
+	y: Int!
+	type: String!
+}
+
+type PowerUp {
+	x: Int!
+	y: Int!
+	type: String!
+}
+
+type Effect {
+	type: String!
+	ticksLeft: Int!
+}
+
+type Game {
+	id: ID!
+	width: Int!
+	height: Int!
+	score: Int!
+	fruits: [Fruit!]!
+	snake: Snake!
+	fruitSpawn: String!
+	mode: String!
+	obstacles: [Position!]!
+	powerUps: [PowerUp!]!
+	effects: [Effect!]!
+	seed: String!
+	ticksPlayed: Int!
+	tickLimit: Int!
+	lastActivity: String!
+	live: Boolean!
+	versus: Boolean!
+	opponent: Snake
+	opponentScore: Int!
+	winner: Int!
+	status: String!
+	gameOverReason: String
+}
+
+type Validation {
+	game: Game!
+	ticksApplied: Int!
+	status: String!
+}
+
+type Score {
+	gameId: ID!
+	player: String!
+	score: Int!
+	width: Int!
+	height: Int!
+	createdAt: String!
+}
+
+type Tick {
+	velX: Int!
+	velY: Int!
+}
+
+type ReplayTick {
+	velX: Int!
+	velY: Int!
+	opponent: Tick
+}
+
+type Replay {
+	gameId: ID!
+	initial: Game!
+	ticks: [ReplayTick!]!
+}
+
+input GameConfigInput {
+	width: Int!
+	height: Int!
+	fruitSpawn: String
+	mode: String
+	live: Boolean
+	versus: Boolean
+	obstacles: Int
+	fruitCount: Int
+	golden: Int
+	poison: Int
+	powerUpChance: Int
+	tickLimit: Int
+	ttlSeconds: Int
+	seed: String
+}
+
+input TickInput {
+	velX: Int!
+	velY: Int!
+}
+`
+
+// maxGraphQLDepth bounds how deeply a query may nest selections
+const maxGraphQLDepth = 10
+
+var graphqlSchema = graphql.MustParseSchema(graphqlSchemaSDL, &graphqlResolver{},
+	graphql.MaxDepth(maxGraphQLDepth))
+
+// GraphQLRequest is the body of a POST /graphql
+type GraphQLRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// graphqlHandler executes a GraphQL query or mutation. Errors are reported
+// in the response body with the usual error code in their extensions.
+func graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+	var req GraphQLRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	// GraphQL responses are always JSON
+	w.Header().Set("Content-Type", "application/json")
+	ctx := context.WithValue(r.Context(), remoteAddrKey{}, r.RemoteAddr)
+	jsonResponse(w, graphqlSchema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+}
+
+// graphqlError is a resolver error carrying one of the API error codes
+type graphqlError struct {
+	code    ErrorCode
+	message string
+}
+
+func (e graphqlError) Error() string {
+	return e.message
+}
+
+func (e graphqlError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+// storeGraphQLError converts a store error into a graphqlError, like storeError
+func storeGraphQLError(err error, gameID string) error {
+	if errors.Is(err, errGameNotFound) {
+		return graphqlError{CodeGameNotFound, fmt.Sprintf("Game not found: %s", gameID)}
+	}
+	return graphqlError{CodeUnavailable, "Game store unavailable"}
+}
+
+// lookupGameID returns an error unless id is a well-formed game ID and games
+// are kept server-side
+func lookupGameID(id graphql.ID) error {
+	if signingKey != nil {
+		return graphqlError{CodeInvalidRequest, "Not available in stateless mode"}
+	}
+	if !validGameID(string(id)) {
+		return graphqlError{CodeInvalidGameID, fmt.Sprintf("Invalid game ID: %q", id)}
+	}
+	return nil
+}
+
+// graphqlResolver resolves the Query and Mutation root types
+type graphqlResolver struct{}
+
+func (*graphqlResolver) Game(ctx context.Context, args struct{ ID graphql.ID }) (*gameResolver, error) {
+	if err := lookupGameID(args.ID); err != nil {
+		return nil, err
+	}
+	state, err := games.Get(ctx, string(args.ID))
+	if err != nil {
+		return nil, storeGraphQLError(err, string(args.ID))
+	}
+	return &gameResolver{state}, nil
+}
+
+func (*graphqlResolver) Leaderboard(args struct {
+	Limit  int32
+	Window string
+}) ([]scoreResolver, error) {
+	limit := int(args.Limit)
+	if limit <= 0 || limit > maxLeaderboardSize {
+		return nil, graphqlError{CodeInvalidRequest, fmt.Sprintf(
+			"limit must be between 1 and %d", maxLeaderboardSize)}
+	}
+	window := LeaderboardWindow(args.Window)
+	if !window.valid() {
+		return nil, graphqlError{CodeInvalidRequest, fmt.Sprintf("Unknown window: %s", window)}
+	}
+
+	since, _ := window.bounds(time.Now())
+	entries, err := scores.top(limit, leaderboardFilter{since: since})
+	if err != nil {
+		return nil, graphqlError{CodeInternal, "Failed to load leaderboard"}
+	}
+	resolvers := make([]scoreResolver, len(entries))
+	for i, entry := range entries {
+		resolvers[i] = scoreResolver{entry}
+	}
+	return resolvers, nil
+}
+
+func (*graphqlResolver) Replay(ctx context.Context, args struct{ ID graphql.ID }) (*replayResolver, error) {
+	if err := lookupGameID(args.ID); err != nil {
+		return nil, err
+	}
+	replay, err := games.Replay(ctx, string(args.ID))
+	if err != nil {
+		return nil, storeGraphQLError(err, string(args.ID))
+	}
+	return &replayResolver{replay}, nil
+}
+
+// GameConfigInput is the GraphQL input for a GameConfig
+type GameConfigInput struct {
+	Width         int32
+	Height        int32
+	FruitSpawn    *string
+	Mode          *string
+	Live          *bool
+	Versus        *bool
+	Obstacles     *int32
+	FruitCount    *int32
+	Golden        *int32
+	Poison        *int32
+	PowerUpChance *int32
+	TickLimit     *int32
+	TTLSeconds    *int32
+	Seed          *string
+}
+
+// gameConfig converts the input into a GameConfig, leaving unset fields zero
+func (in GameConfigInput) gameConfig() (GameConfig, error) {
+	config := GameConfig{Width: int(in.Width), Height: int(in.Height)}
+	if in.FruitSpawn != nil {
+		config.FruitSpawn = FruitSpawn(*in.FruitSpawn)
+	}
+	if in.Mode != nil {
+		config.Mode = GameMode(*in.Mode)
+	}
+	if in.Live != nil {
+		config.Live = *in.Live
+	}
+	if in.Versus != nil {
+		config.Versus = *in.Versus
+	}
+	for _, field := range []struct {
+		in  *int32
+		out *int
+	}{
+		{in.Obstacles, &config.Obstacles},
+		{in.FruitCount, &config.FruitCount},
+		{in.Golden, &config.FruitChances.Golden},
+		{in.Poison, &config.FruitChances.Poison},
+		{in.PowerUpChance, &config.PowerUpChance},
+		{in.TickLimit, &config.TickLimit},
+		{in.TTLSeconds, &config.TTLSeconds},
+	} {
+		if field.in != nil {
+			*field.out = int(*field.in)
+		}
+	}
+	if in.Seed != nil {
+		seed, err := strconv.ParseInt(*in.Seed, 10, 64)
+		if err != nil {
+			return config, graphqlError{CodeInvalidRequest, fmt.Sprintf("Invalid seed: %s", err.Error())}
+		}
+		config.Seed = seed
+	}
+	return config, nil
+}
+
+func (*graphqlResolver) CreateGame(ctx context.Context, args struct{ Config GameConfigInput }) (*gameResolver, error) {
+	config, err := args.Config.gameConfig()
+	if err != nil {
+		return nil, err
+	}
+	if code, message := gameConfigError(config); code != "" {
+		return nil, graphqlError{code, message}
+	}
+
+	gameState, err := createGame(ctx, config)
+	if err != nil {
+		return nil, storeGraphQLError(err, gameState.GameID)
+	}
+	return &gameResolver{gameState}, nil
+}
+
+// TickInput is the GraphQL input for a Tick
+type TickInput struct {
+	VelX int32
+	VelY int32
+}
+
+func (*graphqlResolver) SubmitTicks(ctx context.Context, args struct {
+	ID    graphql.ID
+	Ticks []TickInput
+}) (*validationResolver, error) {
+	if err := lookupGameID(args.ID); err != nil {
+		return nil, err
+	}
+	if len(args.Ticks) > maxTicks {
+		return nil, graphqlError{CodeTooManyTicks, fmt.Sprintf(
+			"Too many ticks: %d, max=%d; split them over several requests", len(args.Ticks), maxTicks)}
+	}
+
+	ticks := make([]Tick, len(args.Ticks))
+	for i, tick := range args.Ticks {
+		ticks[i] = Tick{VelX: int(tick.VelX), VelY: int(tick.VelY)}
+	}
+
+	state, ticksApplied, statusCode, err := validateStored(ctx, string(args.ID), ticks)
+	if err == errGamePaused {
+		return nil, graphqlError{CodeGamePaused, "Game is paused; resume it before sending ticks"}
+	}
+	if err == errNotOwner {
+		return nil, graphqlError{CodeForbidden, "Game belongs to another player"}
+	}
+	if err != nil {
+		return nil, storeGraphQLError(err, string(args.ID))
+	}
+	result, code, message := validationOutcome(statusCode, ticksApplied)
+	if code != "" {
+		return nil, graphqlError{code, message}
+	}
+
+	return &validationResolver{state, ticksApplied, result}, nil
+}
+
+type positionResolver struct{ pos Position }
+
+func (r positionResolver) X() int32 { return int32(r.pos.X) }
+func (r positionResolver) Y() int32 { return int32(r.pos.Y) }
+
+func positionResolvers(positions []Position) []positionResolver {
+	resolvers := make([]positionResolver, len(positions))
+	for i, pos := range positions {
+		resolvers[i] = positionResolver{pos}
+	}
+	return resolvers
+}
+
+type snakeResolver struct{ snake Snake }
+
+func (r snakeResolver) X() int32                 { return int32(r.snake.X) }
+func (r snakeResolver) Y() int32                 { return int32(r.snake.Y) }
+func (r snakeResolver) VelX() int32              { return int32(r.snake.VelX) }
+func (r snakeResolver) VelY() int32              { return int32(r.snake.VelY) }
+func (r snakeResolver) Body() []positionResolver { return positionResolvers(r.snake.Body) }
+
+type fruitResolver struct{ fruit Fruit }
+
+func (r fruitResolver) X() int32     { return int32(r.fruit.X) }
+func (r fruitResolver) Y() int32     { return int32(r.fruit.Y) }
+func (r fruitResolver) Type() string { return string(r.fruit.Type) }
+
+type powerUpResolver struct{ powerUp PowerUp }
+
+func (r powerUpResolver) X() int32     { return int32(r.powerUp.X) }
+func (r powerUpResolver) Y() int32     { return int32(r.powerUp.Y) }
+func (r powerUpResolver) Type() string { return string(r.powerUp.Type) }
+
+type effectResolver struct{ effect Effect }
+
+func (r effectResolver) Type() string     { return string(r.effect.Type) }
+func (r effectResolver) TicksLeft() int32 { return int32(r.effect.TicksLeft) }
+
+type gameResolver struct{ state GameState }
+
+func (r *gameResolver) ID() graphql.ID       { return graphql.ID(r.state.GameID) }
+func (r *gameResolver) Width() int32         { return int32(r.state.Width) }
+func (r *gameResolver) Height() int32        { return int32(r.state.Height) }
+func (r *gameResolver) Score() int32         { return int32(r.state.Score) }
+func (r *gameResolver) Snake() snakeResolver { return snakeResolver{r.state.Snake} }
+func (r *gameResolver) FruitSpawn() string   { return string(r.state.FruitSpawn) }
+func (r *gameResolver) Mode() string         { return string(r.state.Mode) }
+func (r *gameResolver) Seed() string         { return strconv.FormatInt(r.state.Seed, 10) }
+func (r *gameResolver) TicksPlayed() int32   { return int32(r.state.TicksPlayed) }
+func (r *gameResolver) TickLimit() int32     { return int32(r.state.TickLimit) }
+func (r *gameResolver) LastActivity() string { return r.state.LastActivity.Format(time.RFC3339) }
+func (r *gameResolver) Live() bool           { return r.state.Live }
+func (r *gameResolver) Versus() bool         { return r.state.Versus }
+func (r *gameResolver) OpponentScore() int32 { return int32(r.state.OpponentScore) }
+func (r *gameResolver) Winner() int32        { return int32(r.state.Winner) }
+func (r *gameResolver) Status() string       { return string(r.state.Status) }
+func (r *gameResolver) Obstacles() []positionResolver {
+	return positionResolvers(r.state.Obstacles)
+}
+
+func (r *gameResolver) Fruits() []fruitResolver {
+	resolvers := make([]fruitResolver, len(r.state.Fruits))
+	for i, fruit := range r.state.Fruits {
+		resolvers[i] = fruitResolver{fruit}
+	}
+	return resolvers
+}
+
+func (r *gameResolver) PowerUps() []powerUpResolver {
+	resolvers := make([]powerUpResolver, len(r.state.PowerUps))
+	for i, powerUp := range r.state.PowerUps {
+		resolvers[i] = powerUpResolver{powerUp}
+	}
+	return resolvers
+}
+
+func (r *gameResolver) Effects() []effectResolver {
+	resolvers := make([]effectResolver, len(r.state.Effects))
+	for i, effect := range r.state.Effects {
+		resolvers[i] = effectResolver{effect}
+	}
+	return resolvers
+}
+
+func (r *gameResolver) Opponent() *snakeResolver {
+	if r.state.Opponent == nil {
+		return nil
+	}
+	return &snakeResolver{*r.state.Opponent}
+}
+
+func (r *gameResolver) GameOverReason() *string {
+	if r.state.GameOverReason == "" {
+		return nil
+	}
+	reason := string(r.state.GameOverReason)
+	return &reason
+}
+
+type validationResolver struct {
+	state        GameState
+	ticksApplied int
+	status       ValidateStatus
+}
+
+func (r *validationResolver) Game() *gameResolver { return &gameResolver{r.state} }
+func (r *validationResolver) TicksApplied() int32 { return int32(r.ticksApplied) }
+func (r *validationResolver) Status() string      { return string(r.status) }
+
+type scoreResolver struct{ entry ScoreEntry }
+
+func (r scoreResolver) GameID() graphql.ID { return graphql.ID(r.entry.GameID) }
+func (r scoreResolver) Player() string     { return r.entry.Player }
+func (r scoreResolver) Score() int32       { return int32(r.entry.Score) }
+func (r scoreResolver) Width() int32       { return int32(r.entry.Width) }
+func (r scoreResolver) Height() int32      { return int32(r.entry.Height) }
+func (r scoreResolver) CreatedAt() string  { return r.entry.CreatedAt.Format(time.RFC3339) }
+
+type tickResolver struct{ tick Tick }
+
+func (r tickResolver) VelX() int32 { return int32(r.tick.VelX) }
+func (r tickResolver) VelY() int32 { return int32(r.tick.VelY) }
+
+type replayTickResolver struct{ tick ReplayTick }
+
+func (r replayTickResolver) VelX() int32 { return int32(r.tick.VelX) }
+func (r replayTickResolver) VelY() int32 { return int32(r.tick.VelY) }
+func (r replayTickResolver) Opponent() *tickResolver {
+	if r.tick.Opponent == nil {
+		return nil
+	}
+	return &tickResolver{*r.tick.Opponent}
+}
+
+type replayResolver struct{ replay Replay }
+
+func (r *replayResolver) GameID() graphql.ID     { return graphql.ID(r.replay.GameID) }
+func (r *replayResolver) Initial() *gameResolver { return &gameResolver{r.replay.Initial} }
+func (r *replayResolver) Ticks() []replayTickResolver {
+	resolvers := make([]replayTickResolver, len(r.replay.Ticks))
+	for i, tick := range r.replay.Ticks {
+		resolvers[i] = replayTickResolver{tick}
+	}
+	return resolvers
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/graph-gophers/graphql-go"
+)
+
+// graphqlSubscriptionSDL is the schema served over WebSocket at /graphql.
+// Resolvers are looked up by field name alone, so Subscription.game cannot
+// share a schema with Query.game; games are queried with POST /graphql.
+const graphqlSubscriptionSDL = `
+schema {
+	query: Query
+	subscription: Subscription
+}
+
+type Query {
+	leaderboard(limit: Int! = 10, window: String! = "alltime"): [Score!]!
+	replay(id: ID!): Replay
+}
+
+type Subscription {
+	game(id: ID!): Game!
+}
+` + graphqlTypesSDL
+
+var graphqlSubscriptionSchema = graphql.MustParseSchema(graphqlSubscriptionSDL, &graphqlSubscriptionResolver{},
+	graphql.MaxDepth(maxGraphQLDepth))
+
+// graphqlSubscriptionResolver resolves the Query and Subscription root types
+// of graphqlSubscriptionSchema
+type graphqlSubscriptionResolver struct{ graphqlResolver }
+
+// Game sends the current state of a game, then its state after every
+// update until the game is over
+func (*graphqlSubscriptionResolver) Game(ctx context.Context, args struct{ ID graphql.ID }) (<-chan *gameResolver, error) {
+	if err := lookupGameID(args.ID); err != nil {
+		return nil, err
+	}
+	gameID := string(args.ID)
+	updates, unsubscribe := loop.subscribe(gameID)
+	state, err := games.Get(ctx, gameID)
+	if err != nil {
+		unsubscribe()
+		return nil, storeGraphQLError(err, gameID)
+	}
+
+	states := make(chan *gameResolver)
+	go func() {
+		defer close(states)
+		defer unsubscribe()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case states <- &gameResolver{state}:
+			}
+			if state.GameOverReason != "" {
+				return
+			}
+			var ok bool
+			select {
+			case <-ctx.Done():
+				return
+			case state, ok = <-updates:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+	return states, nil
+}
+
+// graphqlWSProtocol is the WebSocket subprotocol spoken at /graphql
+const graphqlWSProtocol = "graphql-transport-ws"
+
+// Close codes of graphqlWSProtocol
+const (
+	closeBadMessage      = 4400
+	closeUnauthorized    = 4401
+	closeSubscriberTaken = 4409
+	closeTooManyInits    = 4429
+)
+
+var graphqlUpgrader = websocket.Upgrader{Subprotocols: []string{graphqlWSProtocol}}
+
+// graphqlWSMessage is a message of graphqlWSProtocol
+type graphqlWSMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// graphqlOperation is an operation running on a GraphQL WebSocket
+type graphqlOperation struct{ stop context.CancelFunc }
+
+// graphqlWSHandler runs GraphQL subscriptions, and queries, over a WebSocket
+// speaking graphql-transport-ws. Every result is sent as a next message, and
+// a complete message follows once an operation ends on the server.
+func graphqlWSHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := graphqlUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	// The connection outlives the server's request timeouts
+	conn.SetReadDeadline(time.Time{})
+	conn.SetWriteDeadline(time.Time{})
+
+	closeWith := func(code int, text string) {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text),
+			time.Now().Add(time.Second))
+	}
+	if conn.Subprotocol() != graphqlWSProtocol {
+		closeWith(closeBadMessage, "Use the "+graphqlWSProtocol+" subprotocol")
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(r.Context(), remoteAddrKey{}, r.RemoteAddr))
+	defer cancel()
+	var mu sync.Mutex
+	operations := make(map[string]*graphqlOperation)
+	send := func(msg graphqlWSMessage) {
+		mu.Lock()
+		defer mu.Unlock()
+		conn.WriteJSON(msg)
+	}
+
+	acknowledged := false
+	for {
+		var msg graphqlWSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			return
+		}
+		switch msg.Type {
+		case "connection_init":
+			if acknowledged {
+				closeWith(closeTooManyInits, "Too many initialisation requests")
+				return
+			}
+			acknowledged = true
+			send(graphqlWSMessage{Type: "connection_ack"})
+		case "ping":
+			send(graphqlWSMessage{Type: "pong"})
+		case "pong":
+		case "subscribe":
+			if !acknowledged {
+				closeWith(closeUnauthorized, "Unauthorized")
+				return
+			}
+			var req GraphQLRequest
+			if err := json.Unmarshal(msg.Payload, &req); err != nil || msg.ID == "" {
+				closeWith(closeBadMessage, "Invalid subscribe message")
+				return
+			}
+			mu.Lock()
+			_, taken := operations[msg.ID]
+			opCtx, stop := context.WithCancel(ctx)
+			op := &graphqlOperation{stop}
+			if !taken {
+				operations[msg.ID] = op
+			}
+			mu.Unlock()
+			if taken {
+				stop()
+				closeWith(closeSubscriberTaken, "Subscriber for "+msg.ID+" already exists")
+				return
+			}
+
+			responses, err := graphqlSubscriptionSchema.Subscribe(opCtx, req.Query, req.OperationName, req.Variables)
+			if err != nil {
+				stop()
+				closeWith(websocket.CloseInternalServerErr, "Subscriptions unavailable")
+				return
+			}
+			go func(id string) {
+				for response := range responses {
+					payload, _ := json.Marshal(response)
+					send(graphqlWSMessage{ID: id, Type: "next", Payload: payload})
+				}
+				// The client is only told of operations it did not stop
+				mu.Lock()
+				ended := operations[id] == op
+				if ended {
+					delete(operations, id)
+				}
+				mu.Unlock()
+				stop()
+				if ended {
+					send(graphqlWSMessage{ID: id, Type: "complete"})
+				}
+			}(msg.ID)
+		case "complete":
+			mu.Lock()
+			if op, ok := operations[msg.ID]; ok {
+				op.stop()
+				delete(operations, msg.ID)
+			}
+			mu.Unlock()
+		default:
+			closeWith(closeBadMessage, "Unknown message type: "+msg.Type)
+			return
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestGraphQLSubscription(t *testing.T) {
+	h := newTestAPI(t)
+	mux := http.NewServeMux()
+	mux.Handle("/v1/", h)
+	mux.HandleFunc("/graphql", graphqlWSHandler)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	game := newGame(t, h, "", GameConfig{Width: 10, Height: 10})
+	dialer := websocket.Dialer{Subprotocols: []string{graphqlWSProtocol}}
+	conn, _, err := dialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/graphql", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	receive := func(want string) graphqlWSMessage {
+		t.Helper()
+		var msg graphqlWSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("reading %s: %v", want, err)
+		}
+		if msg.Type != want {
+			t.Fatalf("got %s message %s, want %s", msg.Type, msg.Payload, want)
+		}
+		return msg
+	}
+	receiveGame := func() (ticksPlayed int, gameOverReason *string) {
+		t.Helper()
+		var result struct {
+			Data struct {
+				Game struct {
+					TicksPlayed    int     `json:"ticksPlayed"`
+					GameOverReason *string `json:"gameOverReason"`
+				} `json:"game"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(receive("next").Payload, &result); err != nil {
+			t.Fatal(err)
+		}
+		return result.Data.Game.TicksPlayed, result.Data.Game.GameOverReason
+	}
+
+	conn.WriteJSON(graphqlWSMessage{Type: "connection_init"})
+	receive("connection_ack")
+	payload, _ := json.Marshal(GraphQLRequest{
+		Query:     `subscription($id: ID!) { game(id: $id) { ticksPlayed gameOverReason } }`,
+		Variables: map[string]any{"id": game.GameID},
+	})
+	conn.WriteJSON(graphqlWSMessage{ID: "1", Type: "subscribe", Payload: payload})
+	if ticks, _ := receiveGame(); ticks != 0 {
+		t.Errorf("first state at tick %d, want 0", ticks)
+	}
+
+	path := "/v1/games/" + game.GameID + "/validate"
+	if rec := do(t, h, http.MethodPost, path, "", ValidateRequest{Ticks: []Tick{{VelX: 1}}}); rec.Code != http.StatusOK {
+		t.Fatalf("validating: %d %s", rec.Code, rec.Body)
+	}
+	if ticks, _ := receiveGame(); ticks != 1 {
+		t.Errorf("state after validating at tick %d, want 1", ticks)
+	}
+
+	do(t, h, http.MethodPost, path, "", ValidateRequest{Ticks: []Tick{{VelY: -1}}})
+	if _, reason := receiveGame(); reason == nil {
+		t.Error("no game over reason after running into the wall")
+	}
+	if msg := receive("complete"); msg.ID != "1" {
+		t.Errorf("completed operation %q, want 1", msg.ID)
+	}
+}
@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	snakepb "github.com/rodrygw/snake-game-api/proto/snake/v1"
+)
+
+// defaultGRPCAddr is where the gRPC service listens unless -grpc-addr says
+// otherwise
+const defaultGRPCAddr = ":9090"
+
+// grpcService implements the SnakeService of proto/snake/v1 on top of the
+// same store and game loop as the HTTP API
+type grpcService struct {
+	snakepb.UnimplementedSnakeServiceServer
+}
+
+// newGRPCServer returns the gRPC server exposing the game service
+func newGRPCServer() *grpc.Server {
+	server := grpc.NewServer(
+		grpc.UnaryInterceptor(authenticateUnary),
+		grpc.StreamInterceptor(authenticateStream),
+	)
+	snakepb.RegisterSnakeServiceServer(server, grpcService{})
+	return server
+}
+
+// authenticateCall is the gRPC counterpart of authenticateRequest,
+// identifyClient and requireAccount(ScopePlay): it puts the account of the
+// authorization bearer token or x-api-key metadata into the context, and
+// rejects calls the HTTP API would reject
+func authenticateCall(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get("authorization"); len(values) > 0 {
+		token, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok {
+			return ctx, status.Errorf(codes.Unauthenticated, "%s: Authorization must be a Bearer token", CodeUnauthorized)
+		}
+		account, err := parseToken(token)
+		if err != nil {
+			return ctx, status.Errorf(codes.Unauthenticated, "%s: Invalid or expired token", CodeUnauthorized)
+		}
+		ctx = context.WithValue(ctx, accountKey{}, account)
+	}
+	if values := md.Get(apiKeyHeader); len(values) > 0 {
+		key, err := apiKeys.lookup(values[0])
+		if err == errKeyNotFound {
+			return ctx, status.Errorf(codes.Unauthenticated, "%s: Unknown or revoked API key", CodeUnauthorized)
+		}
+		if err != nil {
+			return ctx, status.Errorf(codes.Internal, "%s: Failed to check API key", CodeInternal)
+		}
+		ctx = context.WithValue(ctx, clientKey{}, key)
+		ctx = context.WithValue(ctx, accountKey{}, key.owner())
+	}
+
+	if requireAuth && accountFrom(ctx) == "" {
+		return ctx, status.Errorf(codes.Unauthenticated,
+			"%s: Log in and send the token as \"authorization: Bearer <token>\" metadata", CodeUnauthorized)
+	}
+	if key, ok := clientFrom(ctx); ok && !key.has(ScopePlay) {
+		return ctx, status.Errorf(codes.PermissionDenied, "%s: API key %s lacks the %s scope",
+			CodeForbidden, key.Prefix, ScopePlay)
+	}
+	return ctx, nil
+}
+
+func authenticateUnary(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := authenticateCall(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func authenticateStream(srv any, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := authenticateCall(stream.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticatedStream is a stream whose context carries its account
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+// storeStatus converts a store error into a gRPC status, like storeError
+func storeStatus(err error, gameID string) error {
+	if errors.Is(err, errGameNotFound) {
+		return status.Errorf(codes.NotFound, "Game not found: %s", gameID)
+	}
+	return status.Error(codes.Unavailable, "Game store unavailable")
+}
+
+// statelessStatus is returned by calls that need games kept server-side
+func statelessStatus() error {
+	return status.Error(codes.Unimplemented, "Not available in stateless mode; use the HTTP API")
+}
+
+func (grpcService) NewGame(ctx context.Context, req *snakepb.NewGameRequest) (*snakepb.GameState, error) {
+	if signingKey != nil {
+		return nil, statelessStatus()
+	}
+
+	config := gameConfigFromProto(req.GetConfig())
+	if code, message := gameConfigError(config); code != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "%s: %s", code, message)
+	}
+
+	gameState, err := createGame(ctx, config)
+	if err != nil {
+		return nil, storeStatus(err, gameState.GameID)
+	}
+	return gameStateToProto(gameState), nil
+}
+
+func (grpcService) Validate(ctx context.Context, req *snakepb.ValidateRequest) (*snakepb.ValidateResponse, error) {
+	if signingKey != nil {
+		return nil, statelessStatus()
+	}
+	if !validGameID(req.GetGameId()) {
+		return nil, status.Errorf(codes.InvalidArgument, "%s: Invalid game ID: %q", CodeInvalidGameID, req.GetGameId())
+	}
+	if n := len(req.GetTicks()); n > maxTicks {
+		return nil, status.Errorf(codes.ResourceExhausted, "%s: Too many ticks: %d, max=%d; split them over several requests",
+			CodeTooManyTicks, n, maxTicks)
+	}
+
+	ticks := make([]Tick, len(req.GetTicks()))
+	for i, tick := range req.GetTicks() {
+		ticks[i] = tickFromProto(tick)
+	}
+
+	state, ticksApplied, statusCode, err := validateStored(ctx, req.GetGameId(), ticks)
+	if err == errGamePaused {
+		return nil, status.Errorf(codes.FailedPrecondition, "%s: Game is paused; resume it before sending ticks", CodeGamePaused)
+	}
+	if err == errNotOwner {
+		return nil, status.Errorf(codes.PermissionDenied, "%s: Game belongs to another player", CodeForbidden)
+	}
+	if err != nil {
+		return nil, storeStatus(err, req.GetGameId())
+	}
+
+	result, code, message := validationOutcome(statusCode, ticksApplied)
+	switch code {
+	case CodeGameLive:
+		return nil, status.Errorf(codes.FailedPrecondition, "%s: %s", code, message)
+	case CodeInvalidTick:
+		return nil, status.Errorf(codes.InvalidArgument, "%s: %s", code, message)
+	}
+
+	return validateResponseToProto(ValidateResponse{
+		GameState:    state,
+		TicksApplied: ticksApplied,
+		Status:       result,
+	}), nil
+}
+
+// StreamGame is the gRPC counterpart of wsHandler: direction changes arrive
+// on the request stream while the game loop advances the game, and the state
+// is pushed after every tick until the game is over. Like the WebSocket it
+// only takes streams from the player steering the snake.
+func (grpcService) StreamGame(stream snakepb.SnakeService_StreamGameServer) error {
+	if signingKey != nil {
+		return statelessStatus()
+	}
+
+	first, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	gameID := first.GetGameId()
+	if !validGameID(gameID) {
+		return status.Errorf(codes.InvalidArgument, "%s: Invalid game ID: %q", CodeInvalidGameID, gameID)
+	}
+	player := 1
+	if first.GetPlayer() == 2 {
+		player = 2
+	}
+
+	state, err := games.Get(stream.Context(), gameID)
+	if err != nil {
+		return storeStatus(err, gameID)
+	}
+	if !controlledBy(state, stream.Context(), player) {
+		return status.Errorf(codes.PermissionDenied, "%s: Snake %d is steered by another player", CodeForbidden, player)
+	}
+
+	updates, unsubscribe := loop.subscribe(gameID)
+	defer unsubscribe()
+
+	state, err = loop.start(stream.Context(), gameID)
+	if err != nil {
+		return storeStatus(err, gameID)
+	}
+	if state.GameOverReason != "" {
+		return status.Errorf(codes.FailedPrecondition, "%s: Game over: %s", CodeGameOver, state.GameOverReason)
+	}
+
+	ctx := context.WithoutCancel(stream.Context())
+	done := make(chan struct{})
+	rejected := make(chan string, 1)
+	go func() {
+		defer close(done)
+		req := first
+		for {
+			if tick := req.GetTick(); tick != nil {
+				message := rejectionMessage(loop.setDirection(ctx, gameID, player, tickFromProto(tick)))
+				if message != "" {
+					select {
+					case rejected <- message:
+					default:
+					}
+				}
+			}
+			var err error
+			if req, err = stream.Recv(); err != nil {
+				return
+			}
+		}
+	}()
+
+	if err := sendState(stream, state); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-done:
+			return nil
+		case message := <-rejected:
+			err := stream.Send(&snakepb.StreamGameResponse{
+				Event: &snakepb.StreamGameResponse_Error{Error: &snakepb.Error{
+					Code:    string(CodeInvalidTick),
+					Message: message,
+				}},
+			})
+			if err != nil {
+				return err
+			}
+		case state, ok := <-updates:
+			if !ok {
+				return nil
+			}
+			if err := sendState(stream, state); err != nil {
+				return err
+			}
+			if state.GameOverReason != "" {
+				return nil
+			}
+		}
+	}
+}
+
+// sendState pushes a game state on a StreamGame stream
+func sendState(stream snakepb.SnakeService_StreamGameServer, state GameState) error {
+	return stream.Send(&snakepb.StreamGameResponse{
+		Event: &snakepb.StreamGameResponse_State{State: gameStateToProto(state)},
+	})
+}
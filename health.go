@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+)
+
+// readinessTimeout bounds each dependency check of /readyz
+const readinessTimeout = 2 * time.Second
+
+// HealthResponse is the body of a passing health or readiness probe
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+// healthzHandler reports that the process is up and serving requests
+func healthzHandler(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, HealthResponse{Status: "ok"})
+}
+
+// readyzHandler reports whether the game store and leaderboard database
+// can be reached, so traffic is only routed to instances that can serve it
+func readyzHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+
+	if err := games.Ping(ctx); err != nil {
+		log.Printf("readiness: game store: %v", err)
+		jsonError(w, http.StatusServiceUnavailable, CodeUnavailable, "Game store is unavailable")
+		return
+	}
+	if err := scores.ping(ctx); err != nil {
+		log.Printf("readiness: leaderboard: %v", err)
+		jsonError(w, http.StatusServiceUnavailable, CodeUnavailable, "Leaderboard database is unavailable")
+		return
+	}
+
+	jsonResponse(w, HealthResponse{Status: "ok"})
+}
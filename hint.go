@@ -0,0 +1,274 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/time/rate"
+)
+
+const (
+	// defaultHintRate and defaultHintBurst bound how often hints are given
+	// for a single game: one every two seconds, with up to five at once
+	defaultHintRate  = 0.5
+	defaultHintBurst = 5
+	// maxHintLimiters bounds the games whose hint budget is remembered;
+	// games with a full budget are forgotten first
+	maxHintLimiters = 10000
+)
+
+// HintResponse is the body of GET /v1/games/{id}/hint
+type HintResponse struct {
+	// Tick is the suggested next tick, omitted if every move is fatal
+	Tick *Tick `json:"tick,omitempty"`
+
+	// Fruit is the nearest fruit reachable without a collision and Path the
+	// cells leading to it after the head, both omitted if none is reachable
+	// and Tick only keeps the snake alive
+	Fruit *Position  `json:"fruit,omitempty"`
+	Path  []Position `json:"path,omitempty"`
+}
+
+// hintTicks are the moves tried from a cell, in a fixed order so that equally
+// good hints are always the same
+var hintTicks = []Tick{keyTicks[keyUp], keyTicks[keyDown], keyTicks[keyLeft], keyTicks[keyRight]}
+
+// gameLimiter rate limits requests per game
+type gameLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+// hintLimiter rate limits GET /v1/games/{id}/hint
+var hintLimiter = newGameLimiter(defaultHintRate, defaultHintBurst)
+
+func newGameLimiter(limit rate.Limit, burst int) *gameLimiter {
+	return &gameLimiter{limit: limit, burst: burst, limiters: make(map[string]*rate.Limiter)}
+}
+
+// reserve takes a request from the budget of the game and returns zero, or
+// leaves the budget alone and returns how long until the next request fits
+func (l *gameLimiter) reserve(gameID string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	limiter, ok := l.limiters[gameID]
+	if !ok {
+		if len(l.limiters) >= maxHintLimiters {
+			for id, other := range l.limiters {
+				if other.TokensAt(now) >= float64(l.burst) {
+					delete(l.limiters, id)
+				}
+			}
+		}
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[gameID] = limiter
+	}
+
+	reservation := limiter.ReserveN(now, 1)
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return delay
+	}
+	return 0
+}
+
+// hintHandler suggests the next tick of a game: the first move of a shortest
+// safe path from the head to the nearest fruit, or any move that does not
+// end the game if no fruit can be reached
+func hintHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	if delay := hintLimiter.reserve(gameID); delay > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+		jsonError(w, http.StatusTooManyRequests, CodeRateLimited,
+			fmt.Sprintf("Hint rate limit exceeded for this game; retry in %s", delay.Round(time.Millisecond)))
+		return
+	}
+
+	state, err := games.Get(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+	if state.GameOverReason != "" {
+		jsonResponseWithStatus(w, ErrorResponse{
+			Code:    CodeGameOver,
+			Message: fmt.Sprintf("Game over: %s", state.GameOverReason),
+			State:   &state,
+		}, http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	jsonResponse(w, hint(state))
+}
+
+// hint computes the suggestion of hintHandler
+func hint(state GameState) HintResponse {
+	if path := fruitPath(state); len(path) > 0 {
+		fruit := path[len(path)-1]
+		tick := tickTowards(state, state.Snake.Position, path[0])
+		return HintResponse{Tick: &tick, Fruit: &fruit, Path: path}
+	}
+	for _, next := range hintMoves(state, state.Snake.Position, true) {
+		if !hintBlocked(state, next, 1) {
+			tick := tickTowards(state, state.Snake.Position, next)
+			return HintResponse{Tick: &tick}
+		}
+	}
+	return HintResponse{}
+}
+
+// fruitPath searches a shortest path from the head to the nearest fruit
+// that is not poison with A*, moving one cell per tick. It returns the cells
+// after the head up to the fruit, or nil if no fruit can be reached safely.
+func fruitPath(state GameState) []Position {
+	var targets []Position
+	for _, fruit := range state.Fruits {
+		if fruit.Type != FruitPoison {
+			targets = append(targets, fruit.Position)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+	estimate := func(pos Position) int {
+		best := math.MaxInt
+		for _, target := range targets {
+			best = min(best, hintDistance(state, pos, target))
+		}
+		return best
+	}
+
+	start := state.Snake.Position
+	steps := map[Position]int{start: 0}
+	previous := make(map[Position]Position)
+	open := &pathQueue{{pos: start, cost: estimate(start)}}
+	for open.Len() > 0 {
+		node := heap.Pop(open).(pathNode)
+		if node.pos != start && fruitAt(state, node.pos) >= 0 {
+			var path []Position
+			for pos := node.pos; pos != start; pos = previous[pos] {
+				path = append([]Position{pos}, path...)
+			}
+			return path
+		}
+
+		g := steps[node.pos]
+		if node.cost > g+estimate(node.pos) {
+			// A shorter way to this cell was queued after this one
+			continue
+		}
+		for _, next := range hintMoves(state, node.pos, node.pos == start) {
+			if hintBlocked(state, next, g+1) {
+				continue
+			}
+			if seen, ok := steps[next]; ok && seen <= g+1 {
+				continue
+			}
+			steps[next] = g + 1
+			previous[next] = node.pos
+			heap.Push(open, pathNode{pos: next, cost: g + 1 + estimate(next)})
+		}
+	}
+	return nil
+}
+
+// hintMoves returns the cells one move away from pos, wrapped around the
+// board when the snake wraps. From the head the move reversing the snake
+// onto itself is left out.
+func hintMoves(state GameState, pos Position, fromHead bool) []Position {
+	var moves []Position
+	for _, tick := range hintTicks {
+		if fromHead && !isValidMove(state, GameState{Snake: Snake{VelX: tick.VelX, VelY: tick.VelY}}) {
+			continue
+		}
+		next := wrapSnake(state, Snake{Position: Position{X: pos.X + tick.VelX, Y: pos.Y + tick.VelY}})
+		moves = append(moves, next.Position)
+	}
+	return moves
+}
+
+// hintBlocked returns true if entering pos after the given number of moves
+// ends the game. Body segments count only until the tail has moved past
+// them, and the opponent is treated as standing still.
+func hintBlocked(state GameState, pos Position, moves int) bool {
+	if pos.X < 0 || pos.Y < 0 || pos.X >= state.Width || pos.Y >= state.Height {
+		return true
+	}
+	if isObstacle(state, pos) {
+		return true
+	}
+	if i := fruitAt(state, pos); i >= 0 && state.Fruits[i].Type == FruitPoison {
+		return true
+	}
+	body := state.Snake.Body
+	for i, segment := range body {
+		if segment == pos && i+moves < len(body) {
+			return true
+		}
+	}
+	if opponent := state.Opponent; opponent != nil {
+		if opponent.Position == pos {
+			return true
+		}
+		for _, segment := range opponent.Body {
+			if segment == pos {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hintDistance is the number of moves between two cells on an empty board
+func hintDistance(state GameState, a, b Position) int {
+	dx, dy := abs(a.X-b.X), abs(a.Y-b.Y)
+	if state.Mode == ModeWrap || hasEffect(state, PowerUpPhase) {
+		dx, dy = min(dx, state.Width-dx), min(dy, state.Height-dy)
+	}
+	return dx + dy
+}
+
+// tickTowards returns the tick moving from one cell to the neighbouring cell
+// to, across an edge of the board if the two are on opposite edges
+func tickTowards(state GameState, from, to Position) Tick {
+	for _, tick := range hintTicks {
+		next := wrapSnake(state, Snake{Position: Position{X: from.X + tick.VelX, Y: from.Y + tick.VelY}})
+		if next.Position == to {
+			return tick
+		}
+	}
+	return Tick{}
+}
+
+// pathNode is a cell queued by fruitPath with its estimated path length
+type pathNode struct {
+	pos  Position
+	cost int
+}
+
+// pathQueue is a min-heap of pathNodes by cost
+type pathQueue []pathNode
+
+func (q pathQueue) Len() int           { return len(q) }
+func (q pathQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q pathQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *pathQueue) Push(x any)        { *q = append(*q, x.(pathNode)) }
+
+func (q *pathQueue) Pop() any {
+	old := *q
+	node := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return node
+}
@@ -0,0 +1,520 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// defaultLeaderboardSize is how many entries /leaderboard returns by default
+	defaultLeaderboardSize = 10
+	// maxLeaderboardSize is the largest top-N /leaderboard accepts
+	maxLeaderboardSize = 100
+	// maxPlayerNameLength bounds the player name stored with a score
+	maxPlayerNameLength = 32
+)
+
+var errScoreExists = errors.New("a score was already submitted for this game")
+
+// LeaderboardWindow limits the leaderboard to the scores of the current day
+// or week, or keeps every score. Windows roll over at midnight UTC, and weeks
+// start on Monday; older scores stay in the all-time leaderboard.
+type LeaderboardWindow string
+
+const (
+	WindowDaily   LeaderboardWindow = "daily"
+	WindowWeekly  LeaderboardWindow = "weekly"
+	WindowAllTime LeaderboardWindow = "alltime"
+)
+
+// leaderboardWindows are the accepted values of ?window
+var leaderboardWindows = []string{string(WindowDaily), string(WindowWeekly), string(WindowAllTime)}
+
+// valid returns true if the window is known; empty means all time
+func (w LeaderboardWindow) valid() bool {
+	return w == "" || w == WindowDaily || w == WindowWeekly || w == WindowAllTime
+}
+
+// bounds returns when the window containing now started and when it rolls
+// over, or zero times for the all-time window
+func (w LeaderboardWindow) bounds(now time.Time) (since, resetsAt time.Time) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	switch w {
+	case WindowDaily:
+		return day, day.AddDate(0, 0, 1)
+	case WindowWeekly:
+		// Weekday counts from Sunday; weeks here start on Monday
+		monday := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return monday, monday.AddDate(0, 0, 7)
+	}
+	return time.Time{}, time.Time{}
+}
+
+// Difficulties of a score besides the bot difficulties of games against a
+// bot
+const (
+	DifficultySolo   = "solo"
+	DifficultyVersus = "versus"
+)
+
+// scoreDifficulty returns what a game's score was played against: nobody,
+// another player or a bot of some difficulty
+func scoreDifficulty(state GameState) string {
+	switch {
+	case state.Bot != nil:
+		return string(state.Bot.Difficulty)
+	case state.Versus:
+		return DifficultyVersus
+	}
+	return DifficultySolo
+}
+
+// ruleProfile names the rules a game was played by: its mode, followed by
+// the variants changing how hard it is to score, joined with "+"
+func ruleProfile(state GameState) string {
+	rules := []string{string(ModeClassic)}
+	if state.Mode != "" {
+		rules[0] = string(state.Mode)
+	}
+	if len(state.Obstacles) > 0 {
+		rules = append(rules, "obstacles")
+	}
+	if state.PowerUpChance > 0 {
+		rules = append(rules, "powerups")
+	}
+	if state.TickLimit > 0 {
+		rules = append(rules, "timed")
+	}
+	return strings.Join(rules, "+")
+}
+
+type (
+	// ScoreEntry is a final score recorded on the leaderboard. Its board,
+	// Difficulty and Rules make up the leaderboard partition it ranks in.
+	ScoreEntry struct {
+		GameID     string    `json:"gameId"`
+		Player     string    `json:"player"`
+		Score      int       `json:"score"`
+		Width      int       `json:"width"`
+		Height     int       `json:"height"`
+		Difficulty string    `json:"difficulty,omitempty"`
+		Rules      string    `json:"rules,omitempty"`
+		CreatedAt  time.Time `json:"createdAt"`
+
+		// Anonymous scores were submitted without an account, under a
+		// name of the submitter's choosing
+		Anonymous bool `json:"anonymous,omitempty"`
+	}
+
+	// ScoreRequest submits the final score of a finished game. Player is
+	// ignored for logged in players, whose scores carry their account;
+	// anonymous players may not use the name of an account or bot.
+	ScoreRequest struct {
+		GameID string `json:"gameId"`
+		Player string `json:"player"`
+	}
+
+	// LeaderboardResponse holds the top scores of a window, with the time
+	// range of the window unless it is all time
+	LeaderboardResponse struct {
+		Window   LeaderboardWindow `json:"window"`
+		Since    *time.Time        `json:"since,omitempty"`
+		ResetsAt *time.Time        `json:"resetsAt,omitempty"`
+		Scores   []ScoreEntry      `json:"scores"`
+	}
+
+	// LeaderboardPartition is a leaderboard of the scores on one board
+	// size, against one difficulty and by one rule profile. Path gets its
+	// top scores.
+	LeaderboardPartition struct {
+		Board      string `json:"board"`
+		Difficulty string `json:"difficulty"`
+		Rules      string `json:"rules"`
+		Scores     int    `json:"scores"`
+		TopScore   int    `json:"topScore"`
+		Path       string `json:"path"`
+	}
+
+	// LeaderboardIndexResponse lists the partitions with scores in a window
+	LeaderboardIndexResponse struct {
+		Window     LeaderboardWindow      `json:"window"`
+		Partitions []LeaderboardPartition `json:"partitions"`
+	}
+)
+
+// leaderboardFilter selects the scores of a leaderboard: those since a
+// time, unless it is zero, and of a partition, as far as it is set
+type leaderboardFilter struct {
+	since         time.Time
+	width, height int
+	difficulty    string
+	rules         string
+}
+
+// conditions returns the WHERE clause of the filter, empty if it selects
+// every score, and its arguments
+func (f leaderboardFilter) conditions() (string, []any) {
+	var conditions []string
+	var args []any
+	if !f.since.IsZero() {
+		conditions, args = append(conditions, "created_at >= ?"), append(args, f.since.UnixMilli())
+	}
+	if f.width != 0 {
+		conditions, args = append(conditions, "width = ? AND height = ?"), append(args, f.width, f.height)
+	}
+	if f.difficulty != "" {
+		conditions, args = append(conditions, "difficulty = ?"), append(args, f.difficulty)
+	}
+	if f.rules != "" {
+		conditions, args = append(conditions, "rules = ?"), append(args, f.rules)
+	}
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// parseBoard parses a board size written as WIDTHxHEIGHT
+func parseBoard(board string) (width, height int, ok bool) {
+	w, h, found := strings.Cut(board, "x")
+	width, errW := strconv.Atoi(w)
+	height, errH := strconv.Atoi(h)
+	return width, height, found && errW == nil && errH == nil && width > 0 && height > 0
+}
+
+// leaderboard persists final scores in an embedded SQLite database
+type leaderboard struct {
+	db *sql.DB
+}
+
+// scores is the server-wide leaderboard, opened in main
+var scores *leaderboard
+
+// openLeaderboard opens (creating if needed) the SQLite database at path
+func openLeaderboard(path string) (*leaderboard, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; serialize access instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS scores (
+			game_id    TEXT PRIMARY KEY,
+			player     TEXT NOT NULL,
+			score      INTEGER NOT NULL,
+			width      INTEGER NOT NULL,
+			height     INTEGER NOT NULL,
+			created_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS scores_by_score ON scores (score DESC, created_at);
+		CREATE INDEX IF NOT EXISTS scores_by_time ON scores (created_at, score DESC);
+	`)
+	// Scores recorded before anonymous ones were told apart count as
+	// submitted by their player, and those recorded before leaderboards
+	// were partitioned as solo classic games
+	for _, column := range []string{
+		`anonymous INTEGER NOT NULL DEFAULT 0`,
+		`difficulty TEXT NOT NULL DEFAULT 'solo'`,
+		`rules TEXT NOT NULL DEFAULT 'classic'`,
+	} {
+		if err != nil {
+			break
+		}
+		_, err = db.Exec(`ALTER TABLE scores ADD COLUMN ` + column)
+		if err != nil && strings.Contains(err.Error(), "duplicate column name") {
+			err = nil
+		}
+	}
+	if err == nil {
+		_, err = db.Exec(`CREATE INDEX IF NOT EXISTS scores_by_partition
+			ON scores (width, height, difficulty, rules, score DESC, created_at)`)
+	}
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &leaderboard{db: db}, nil
+}
+
+// add records a final score; each game can only be recorded once. Scores of
+// daily challenge games are recorded on the board of their day, and every
+// score counts towards the ranks of its partition, in the same transaction.
+func (l *leaderboard) add(entry ScoreEntry, day string) error {
+	tx, err := l.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
+		`INSERT INTO scores (game_id, player, score, width, height, created_at, anonymous, difficulty, rules)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		entry.GameID, entry.Player, entry.Score, entry.Width, entry.Height,
+		entry.CreatedAt.UnixMilli(), entry.Anonymous, entry.Difficulty, entry.Rules)
+	if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+		return errScoreExists
+	}
+	if err != nil {
+		return err
+	}
+	if day != "" {
+		if err := addDaily(tx, day, entry); err != nil {
+			return err
+		}
+	}
+	if err := addRank(tx, entry); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// ping returns an error if the database cannot currently be reached
+func (l *leaderboard) ping(ctx context.Context) error {
+	return l.db.PingContext(ctx)
+}
+
+// top returns the n highest scores the filter selects, earliest first among
+// equal scores. All-time queries walk scores_by_score, and windowed ones
+// only scan the window with scores_by_time, unless a partition is selected
+// and scores_by_partition finds its scores.
+func (l *leaderboard) top(n int, filter leaderboardFilter) ([]ScoreEntry, error) {
+	where, args := filter.conditions()
+	index := ""
+	if filter == (leaderboardFilter{since: filter.since}) && !filter.since.IsZero() {
+		index = " INDEXED BY scores_by_time"
+	}
+	rows, err := l.db.Query(`SELECT game_id, player, score, width, height, created_at, anonymous, difficulty, rules
+		 FROM scores`+index+where+` ORDER BY score DESC, created_at LIMIT ?`, append(args, n)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []ScoreEntry{}
+	for rows.Next() {
+		var entry ScoreEntry
+		var createdAt int64
+		err := rows.Scan(&entry.GameID, &entry.Player, &entry.Score,
+			&entry.Width, &entry.Height, &createdAt, &entry.Anonymous, &entry.Difficulty, &entry.Rules)
+		if err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = time.UnixMilli(createdAt).UTC()
+		entries = append(entries, entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// partitions returns the partitions with scores recorded since the given
+// time, or ever if it is zero, with how many scores they hold and the best
+func (l *leaderboard) partitions(since time.Time) ([]LeaderboardPartition, error) {
+	where, args := leaderboardFilter{since: since}.conditions()
+	rows, err := l.db.Query(`SELECT width, height, difficulty, rules, COUNT(*), MAX(score)
+		FROM scores`+where+` GROUP BY width, height, difficulty, rules
+		ORDER BY width, height, difficulty, rules`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	partitions := []LeaderboardPartition{}
+	for rows.Next() {
+		var partition LeaderboardPartition
+		var width, height int
+		err := rows.Scan(&width, &height, &partition.Difficulty, &partition.Rules,
+			&partition.Scores, &partition.TopScore)
+		if err != nil {
+			return nil, err
+		}
+		partition.Board = fmt.Sprintf("%dx%d", width, height)
+		partition.Path = "/v1/leaderboard?" + url.Values{
+			"board":      {partition.Board},
+			"difficulty": {partition.Difficulty},
+			"rules":      {partition.Rules},
+		}.Encode()
+		partitions = append(partitions, partition)
+	}
+	return partitions, rows.Err()
+}
+
+// playerNameTaken returns true if anonymous scores may not be recorded under
+// name, because it is the username of an account or names a bot
+func playerNameTaken(name string) (bool, error) {
+	if strings.HasPrefix(strings.ToLower(name), botOwnerPrefix) {
+		return true, nil
+	}
+	_, err := accounts.get(name)
+	if err == errInvalidCredentials {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// submitScoreHandler records the final score of a finished game
+func submitScoreHandler(w http.ResponseWriter, r *http.Request) {
+	var req ScoreRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	req.Player = strings.TrimSpace(req.Player)
+	account := accountFrom(r.Context())
+	if account != "" {
+		req.Player = account
+	}
+	if req.Player == "" || len(req.Player) > maxPlayerNameLength {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+			"Player name must be between 1 and %d characters", maxPlayerNameLength))
+		return
+	}
+	if account == "" {
+		taken, err := playerNameTaken(req.Player)
+		if err != nil {
+			jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to check player name")
+			return
+		}
+		if taken {
+			jsonError(w, http.StatusConflict, CodeAccountExists, fmt.Sprintf(
+				"Player name belongs to an account: %s; log in to submit scores under it", req.Player))
+			return
+		}
+	}
+	if !validGameID(req.GameID) {
+		gameIDError(w, req.GameID)
+		return
+	}
+
+	state, err := games.Get(r.Context(), req.GameID)
+	if err != nil {
+		storeError(w, err, req.GameID)
+		return
+	}
+	if !ownedBy(state, r.Context()) {
+		ownerError(w)
+		return
+	}
+	if state.GameOverReason == "" {
+		jsonError(w, http.StatusConflict, CodeGameNotOver,
+			"Only finished games can be submitted to the leaderboard")
+		return
+	}
+
+	entry := ScoreEntry{
+		GameID:     state.GameID,
+		Player:     req.Player,
+		Score:      state.Score,
+		Width:      state.Width,
+		Height:     state.Height,
+		Difficulty: scoreDifficulty(state),
+		Rules:      ruleProfile(state),
+		CreatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+		Anonymous:  account == "",
+	}
+	switch err := scores.add(entry, state.Daily); err {
+	case nil:
+		if account := accountFrom(r.Context()); account != "" {
+			notifyHighScore(account, entry)
+		}
+		jsonResponseWithStatus(w, entry, http.StatusCreated)
+	case errScoreExists:
+		jsonError(w, http.StatusConflict, CodeScoreExists,
+			"A score was already submitted for this game")
+	default:
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to record score")
+	}
+}
+
+// leaderboardWindow returns the ?window of r, all time by default, or
+// writes a 400 and returns false if it is unknown
+func leaderboardWindow(w http.ResponseWriter, r *http.Request) (LeaderboardWindow, bool) {
+	window := LeaderboardWindow(r.URL.Query().Get("window"))
+	if !window.valid() {
+		jsonResponseWithStatus(w, ErrorResponse{
+			Code:      CodeInvalidRequest,
+			Message:   fmt.Sprintf("Unknown window: %s", window),
+			Supported: leaderboardWindows,
+		}, http.StatusBadRequest)
+		return "", false
+	}
+	if window == "" {
+		window = WindowAllTime
+	}
+	return window, true
+}
+
+// leaderboardHandler returns the top scores, ?limit=N of them, of the
+// ?window=daily|weekly|alltime. Scores of every partition rank together
+// unless ?board=WxH, ?difficulty or ?rules narrow them down.
+func leaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	window, ok := leaderboardWindow(w, r)
+	if !ok {
+		return
+	}
+	query := r.URL.Query()
+	filter := leaderboardFilter{difficulty: query.Get("difficulty"), rules: query.Get("rules")}
+	if v := query.Get("board"); v != "" {
+		if filter.width, filter.height, ok = parseBoard(v); !ok {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid board: %s, expected WIDTHxHEIGHT such as 10x10", v))
+			return
+		}
+	}
+
+	limit := defaultLeaderboardSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLeaderboardSize {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+				"limit must be between 1 and %d", maxLeaderboardSize))
+			return
+		}
+	}
+
+	since, resetsAt := window.bounds(time.Now())
+	filter.since = since
+	entries, err := scores.top(limit, filter)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load leaderboard")
+		return
+	}
+
+	response := LeaderboardResponse{Window: window, Scores: entries}
+	if window != WindowAllTime {
+		response.Since, response.ResetsAt = &since, &resetsAt
+	}
+	jsonResponse(w, response)
+}
+
+// leaderboardsHandler lists the leaderboard partitions with scores in the
+// ?window=daily|weekly|alltime
+func leaderboardsHandler(w http.ResponseWriter, r *http.Request) {
+	window, ok := leaderboardWindow(w, r)
+	if !ok {
+		return
+	}
+
+	since, _ := window.bounds(time.Now())
+	partitions, err := scores.partitions(since)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load leaderboards")
+		return
+	}
+	jsonResponse(w, LeaderboardIndexResponse{Window: window, Partitions: partitions})
+}
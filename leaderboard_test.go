@@ -0,0 +1,199 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLeaderboardAddDaily(t *testing.T) {
+	newTestAPI(t)
+	day := "2026-10-14"
+	entry := func() ScoreEntry {
+		return ScoreEntry{GameID: uuid.NewString(), Player: "alice", Score: 7, Width: 10, Height: 10,
+			CreatedAt: time.Now().UTC().Truncate(time.Millisecond)}
+	}
+
+	if err := scores.add(entry(), day); err != nil {
+		t.Fatal(err)
+	}
+	if err := scores.add(entry(), ""); err != nil {
+		t.Fatal(err)
+	}
+	all, _ := scores.top(10, leaderboardFilter{})
+	daily, _ := scores.topDaily(day, 10)
+	if len(all) != 2 || len(daily) != 1 {
+		t.Fatalf("recorded %d scores and %d daily ones, want 2 and 1", len(all), len(daily))
+	}
+
+	// A failing daily insert must not leave the score on the main board
+	if _, err := scores.db.Exec(`DROP TABLE daily_scores`); err != nil {
+		t.Fatal(err)
+	}
+	if err := scores.add(entry(), day); err == nil {
+		t.Fatal("add succeeded without a daily board")
+	}
+	if all, _ := scores.top(10, leaderboardFilter{}); len(all) != 2 {
+		t.Errorf("recorded %d scores, want the failed one rolled back", len(all))
+	}
+}
+
+// finishedGame returns the ID of a game the token's account, if any, lost
+// to the wall
+func finishedGame(t *testing.T, h http.Handler, token string) string {
+	t.Helper()
+	game := newGame(t, h, token, GameConfig{Width: 3, Height: 3})
+	rec := do(t, h, http.MethodPost, "/v1/games/"+game.GameID+"/validate", token,
+		ValidateRequest{Ticks: []Tick{{VelX: 1}, {VelX: 1}, {VelX: 1}}})
+	var state GameState
+	decode(t, rec, &state)
+	if state.GameOverReason == "" {
+		t.Fatalf("finishing game: %d %s", rec.Code, rec.Body)
+	}
+	return game.GameID
+}
+
+func TestSubmitScorePlayerNames(t *testing.T) {
+	h := newTestAPI(t)
+	alice := register(t, h, "alice")
+
+	tests := []struct {
+		name      string
+		token     string
+		player    string
+		status    int
+		want      string
+		anonymous bool
+	}{
+		{name: "anonymous guest", player: "guest", status: http.StatusCreated, want: "guest", anonymous: true},
+		{name: "anonymous as an account", player: "alice", status: http.StatusConflict},
+		{name: "anonymous as an account in other case", player: "ALICE", status: http.StatusConflict},
+		{name: "anonymous as a bot", player: "bot:ranker", status: http.StatusConflict},
+		{name: "logged in", token: alice, player: "someone", status: http.StatusCreated, want: "alice"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gameID := finishedGame(t, h, tt.token)
+			rec := do(t, h, http.MethodPost, "/v1/scores", tt.token, ScoreRequest{GameID: gameID, Player: tt.player})
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.status != http.StatusCreated {
+				var resp ErrorResponse
+				decode(t, rec, &resp)
+				if resp.Code != CodeAccountExists {
+					t.Errorf("code = %s, want %s", resp.Code, CodeAccountExists)
+				}
+				return
+			}
+			var entry ScoreEntry
+			decode(t, rec, &entry)
+			if entry.Player != tt.want || entry.Anonymous != tt.anonymous {
+				t.Errorf("recorded %q, anonymous %v; want %q, %v", entry.Player, entry.Anonymous, tt.want, tt.anonymous)
+			}
+		})
+	}
+
+	top, err := scores.top(10, leaderboardFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range top {
+		if entry.Anonymous != (entry.Player == "guest") {
+			t.Errorf("leaderboard lists %s with anonymous %v", entry.Player, entry.Anonymous)
+		}
+	}
+}
+
+func TestLeaderboardPartitions(t *testing.T) {
+	h := newTestAPI(t)
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, entry := range []ScoreEntry{
+		{Width: 5, Height: 5, Difficulty: DifficultySolo, Rules: "classic", Score: 3},
+		{Width: 5, Height: 5, Difficulty: DifficultySolo, Rules: "classic", Score: 9},
+		{Width: 5, Height: 5, Difficulty: DifficultySolo, Rules: "wrap", Score: 4},
+		{Width: 50, Height: 50, Difficulty: string(BotHard), Rules: "classic", Score: 40},
+	} {
+		entry.GameID, entry.Player, entry.CreatedAt = uuid.NewString(), "alice", now
+		if err := scores.add(entry, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var index LeaderboardIndexResponse
+	decode(t, do(t, h, http.MethodGet, "/v1/leaderboards", "", nil), &index)
+	want := []LeaderboardPartition{
+		{Board: "5x5", Difficulty: "solo", Rules: "classic", Scores: 2, TopScore: 9,
+			Path: "/v1/leaderboard?board=5x5&difficulty=solo&rules=classic"},
+		{Board: "5x5", Difficulty: "solo", Rules: "wrap", Scores: 1, TopScore: 4,
+			Path: "/v1/leaderboard?board=5x5&difficulty=solo&rules=wrap"},
+		{Board: "50x50", Difficulty: "hard", Rules: "classic", Scores: 1, TopScore: 40,
+			Path: "/v1/leaderboard?board=50x50&difficulty=hard&rules=classic"},
+	}
+	if len(index.Partitions) != len(want) {
+		t.Fatalf("partitions = %+v, want %+v", index.Partitions, want)
+	}
+	for i := range want {
+		if index.Partitions[i] != want[i] {
+			t.Errorf("partition %d = %+v, want %+v", i, index.Partitions[i], want[i])
+		}
+	}
+
+	tests := []struct {
+		query  string
+		status int
+		scores []int
+	}{
+		{query: "", status: http.StatusOK, scores: []int{40, 9, 4, 3}},
+		{query: "?board=5x5", status: http.StatusOK, scores: []int{9, 4, 3}},
+		{query: "?board=5x5&rules=classic", status: http.StatusOK, scores: []int{9, 3}},
+		{query: "?difficulty=hard", status: http.StatusOK, scores: []int{40}},
+		{query: "?board=5by5", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := do(t, h, http.MethodGet, "/v1/leaderboard"+tt.query, "", nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var board LeaderboardResponse
+			decode(t, rec, &board)
+			var got []int
+			for _, entry := range board.Scores {
+				got = append(got, entry.Score)
+			}
+			if len(got) != len(tt.scores) {
+				t.Fatalf("scores = %v, want %v", got, tt.scores)
+			}
+			for i := range got {
+				if got[i] != tt.scores[i] {
+					t.Errorf("scores = %v, want %v", got, tt.scores)
+				}
+			}
+		})
+	}
+}
+
+func TestRuleProfile(t *testing.T) {
+	tests := []struct {
+		state      GameState
+		rules      string
+		difficulty string
+	}{
+		{state: GameState{}, rules: "classic", difficulty: "solo"},
+		{state: GameState{Mode: ModeWrap, Obstacles: []Position{{X: 1, Y: 1}}, TickLimit: 100},
+			rules: "wrap+obstacles+timed", difficulty: "solo"},
+		{state: GameState{Versus: true, PowerUpChance: 10}, rules: "classic+powerups", difficulty: "versus"},
+		{state: GameState{Versus: true, Bot: &Bot{Difficulty: BotEasy}}, rules: "classic", difficulty: "easy"},
+	}
+	for _, tt := range tests {
+		if rules, difficulty := ruleProfile(tt.state), scoreDifficulty(tt.state); rules != tt.rules || difficulty != tt.difficulty {
+			t.Errorf("rules, difficulty = %s, %s; want %s, %s", rules, difficulty, tt.rules, tt.difficulty)
+		}
+	}
+}
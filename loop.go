@@ -0,0 +1,389 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// defaultTickInterval is how often the game loop advances live games unless
+// configured otherwise
+const defaultTickInterval = 150 * time.Millisecond
+
+var (
+	errGameNotLive = errors.New("game is not live")
+	errReversal    = errors.New("the snake cannot reverse onto itself")
+	errBadVelocity = errors.New("velocity must move one cell up, down, left or right")
+	errNoOpponent  = errors.New("no opponent has joined the game")
+	errGamePaused  = errors.New("game is paused")
+	errQueueFull   = errors.New("too many direction changes queued")
+)
+
+// DirectionRequest changes the heading of a live game. In versus games
+// Player 2 steers the opponent; otherwise Player may be omitted.
+type DirectionRequest struct {
+	Tick
+	Player int `json:"player,omitempty"`
+}
+
+// playerDirections holds the direction of each snake for one tick of a live
+// game, indexed by player number minus one. A zero Tick keeps the heading.
+type playerDirections [2]Tick
+
+// directionQueues holds the direction changes each player has queued, indexed
+// like playerDirections. The loop applies one change per snake and tick.
+type directionQueues [2][]Tick
+
+// maxQueuedDirections bounds how many direction changes a player may queue
+// ahead of the loop
+const maxQueuedDirections = 3
+
+// gameLoop advances every live game at a fixed tick rate. Clients steer live
+// games with direction changes and watch them by subscribing to updates.
+type gameLoop struct {
+	interval time.Duration
+
+	mu          sync.Mutex
+	directions  map[string]directionQueues
+	subscribers map[string]map[chan GameState]struct{}
+}
+
+// loop is the server-wide game loop
+var loop = newGameLoop(defaultTickInterval)
+
+// newGameLoop creates a game loop ticking at the given interval
+func newGameLoop(interval time.Duration) *gameLoop {
+	return &gameLoop{
+		interval:    interval,
+		directions:  make(map[string]directionQueues),
+		subscribers: make(map[string]map[chan GameState]struct{}),
+	}
+}
+
+// active returns how many live games the loop is advancing
+func (l *gameLoop) active() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.directions)
+}
+
+// run advances all live games once per interval, forever
+func (l *gameLoop) run() {
+	ticker := time.NewTicker(l.interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		l.advance()
+	}
+}
+
+// advance moves every live game forward by one tick, applying the next
+// queued direction change of each snake
+func (l *gameLoop) advance() {
+	l.mu.Lock()
+	directions := make(map[string]playerDirections, len(l.directions))
+	for id, queues := range l.directions {
+		var dirs playerDirections
+		for i, queue := range queues {
+			if len(queue) > 0 {
+				dirs[i] = queue[0]
+			}
+		}
+		directions[id] = dirs
+	}
+	l.mu.Unlock()
+
+	ctx := context.Background()
+	for id, dirs := range directions {
+		var unlocked []UnlockedAchievement
+		var before GameState
+		state, err := games.UpdateRecorded(ctx, id, func(state GameState) (GameState, []ReplayTick) {
+			before = state
+			if dirs != (playerDirections{}) {
+				// Applying a queued direction change is the players' doing
+				state = touch(state)
+			}
+			state, ticks := advanceLive(state, dirs)
+			state, unlocked = unlockAchievements(state)
+			return state, ticks
+		})
+		if err != nil && !errors.Is(err, errGameNotFound) {
+			// Transient store failure: try again on the next tick
+			log.Printf("advancing game %s: %v", id, err)
+			continue
+		}
+		if err == nil && state.Status == GamePaused {
+			continue
+		}
+		l.dequeue(id, dirs)
+		l.publish(id, state, err == nil)
+		if err == nil {
+			rateGame(state)
+			recordGame(state)
+			recordAchievements(state, unlocked)
+			publishProgress(before, state)
+			if before.GameOverReason == "" && state.GameOverReason != "" {
+				notifyGameOver(state)
+			}
+		}
+	}
+}
+
+// dequeue drops the direction changes applied by a tick. New changes are only
+// ever appended, so the applied ones are still at the front of each queue.
+func (l *gameLoop) dequeue(gameID string, applied playerDirections) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	queues, live := l.directions[gameID]
+	if !live {
+		return
+	}
+	for i, dir := range applied {
+		if dir != (Tick{}) && len(queues[i]) > 0 {
+			queues[i] = queues[i][1:]
+		}
+	}
+	l.directions[gameID] = queues
+}
+
+// advanceLive steps a live game by one tick with the players' chosen
+// directions, keeping a snake on its heading if its direction would reverse
+// it. A bot opponent ignores the direction changes of player 2. It returns the new state and the tick applied, if any, for the replay.
+func advanceLive(state GameState, dirs playerDirections) (GameState, []ReplayTick) {
+	if state.GameOverReason != "" || state.Status == GamePaused {
+		return state, nil
+	}
+
+	if dirs[0] == (Tick{}) || !isValidMove(state, GameState{Snake: Snake{VelX: dirs[0].VelX, VelY: dirs[0].VelY}}) {
+		dirs[0] = heading(state.Snake)
+	}
+	if state.Opponent == nil {
+		next, _ := stepGame(state, dirs[0])
+		return next, []ReplayTick{{Tick: dirs[0]}}
+	}
+
+	if state.Bot != nil {
+		dirs[1] = botTick(state)
+	}
+	opponent := GameState{Snake: *state.Opponent}
+	if dirs[1] == (Tick{}) || !isValidMove(opponent, GameState{Snake: Snake{VelX: dirs[1].VelX, VelY: dirs[1].VelY}}) {
+		dirs[1] = heading(*state.Opponent)
+	}
+	next, _ := stepVersus(state, dirs[0], dirs[1])
+	return next, []ReplayTick{{Tick: dirs[0], Opponent: &dirs[1]}}
+}
+
+// start makes the given game live so the loop advances it every tick. Versus
+// games only start advancing once the opponent has joined.
+func (l *gameLoop) start(ctx context.Context, gameID string) (GameState, error) {
+	state, err := games.Update(ctx, gameID, func(state GameState) GameState {
+		state.Live = true
+		return touch(state)
+	})
+	if err != nil {
+		return state, err
+	}
+	l.resume(state)
+	return state, nil
+}
+
+// resume makes the loop advance a live game again after it was paused. Games
+// that are over, paused or still waiting for their opponent are left out.
+func (l *gameLoop) resume(state GameState) {
+	if !state.Live || state.GameOverReason != "" || state.Status == GamePaused ||
+		state.Versus && state.Opponent == nil {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, live := l.directions[state.GameID]; !live {
+		l.directions[state.GameID] = directionQueues{}
+	}
+}
+
+// pause stops advancing a live game and drops its queued direction changes
+// until it is resumed. Its subscribers keep watching it.
+func (l *gameLoop) pause(gameID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	delete(l.directions, gameID)
+}
+
+// setDirection queues a direction change of the given player's snake, to be
+// applied on the tick after the changes already queued. It may not reverse
+// the direction the snake will have by then; repeating it is a no-op. Only
+// the account of ctx steering the snake may change it, else errNotOwner.
+func (l *gameLoop) setDirection(ctx context.Context, gameID string, player int, tick Tick) error {
+	state, err := games.Get(ctx, gameID)
+	if err != nil {
+		return err
+	}
+	if !controlledBy(state, ctx, player) {
+		return errNotOwner
+	}
+	if !state.Live {
+		return errGameNotLive
+	}
+	if state.Status == GamePaused {
+		return errGamePaused
+	}
+
+	if !isUnitTick(tick) || tick == (Tick{}) {
+		return errBadVelocity
+	}
+
+	snake := state.Snake
+	if player == 2 {
+		if state.Opponent == nil {
+			return errNoOpponent
+		}
+		snake = *state.Opponent
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	queues, live := l.directions[gameID]
+	if !live {
+		return errGameNotLive
+	}
+	queue := queues[player-1]
+	last := heading(snake)
+	if len(queue) > 0 {
+		last = queue[len(queue)-1]
+	}
+	if tick == last {
+		return nil
+	}
+	if !isValidMove(GameState{Snake: Snake{VelX: last.VelX, VelY: last.VelY}}, GameState{Snake: Snake{VelX: tick.VelX, VelY: tick.VelY}}) {
+		return errReversal
+	}
+	if len(queue) >= maxQueuedDirections {
+		return errQueueFull
+	}
+	if isDryRun(ctx) {
+		return nil
+	}
+	queues[player-1] = append(queue, tick)
+	l.directions[gameID] = queues
+	return nil
+}
+
+// subscribe returns a channel receiving the state of the given game after
+// every tick. Slow subscribers only see the latest state. The channel is
+// closed once the game ends; call the returned function to stop listening.
+func (l *gameLoop) subscribe(gameID string) (<-chan GameState, func()) {
+	ch := make(chan GameState, 1)
+
+	l.mu.Lock()
+	if l.subscribers[gameID] == nil {
+		l.subscribers[gameID] = make(map[chan GameState]struct{})
+	}
+	l.subscribers[gameID][ch] = struct{}{}
+	l.mu.Unlock()
+
+	return ch, func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		if _, ok := l.subscribers[gameID][ch]; ok {
+			delete(l.subscribers[gameID], ch)
+			close(ch)
+		}
+	}
+}
+
+// publish delivers a state update to the game's subscribers, and stops
+// tracking the game once it has ended or disappeared from the store. Besides
+// the loop's own ticks it is called for updates submitted through /validate.
+func (l *gameLoop) publish(gameID string, state GameState, ok bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for ch := range l.subscribers[gameID] {
+		if ok {
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- state
+		}
+		if !ok || state.GameOverReason != "" {
+			close(ch)
+		}
+	}
+
+	if !ok || state.GameOverReason != "" {
+		delete(l.directions, gameID)
+		delete(l.subscribers, gameID)
+	}
+}
+
+// heading returns the tick that keeps the snake moving in its current direction
+func heading(snake Snake) Tick {
+	return Tick{VelX: snake.VelX, VelY: snake.VelY}
+}
+
+// directionHandler queues a direction change for the next tick of a live game
+func directionHandler(w http.ResponseWriter, r *http.Request) {
+	var req DirectionRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if req.Player == 0 {
+		req.Player = 1
+	}
+	if req.Player != 1 && req.Player != 2 {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, "player must be 1 or 2")
+		return
+	}
+
+	ctx, err := withDryRun(r.Context(), r)
+	if err != nil {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, "dryRun must be true or false")
+		return
+	}
+
+	gameID := chi.URLParam(r, "id")
+	switch err := loop.setDirection(ctx, gameID, req.Player, req.Tick); err {
+	case nil:
+		if isDryRun(ctx) {
+			// Nothing was queued, so there is nothing to accept
+			jsonResponseWithStatus(w, req, http.StatusOK)
+			return
+		}
+		jsonResponseWithStatus(w, req, http.StatusAccepted)
+	case errNoOpponent:
+		jsonError(w, http.StatusConflict, CodeNoOpponent, "No opponent has joined the game yet")
+	case errReversal:
+		jsonError(w, http.StatusBadRequest, CodeInvalidTick, "The snake cannot reverse onto itself")
+	case errBadVelocity:
+		jsonError(w, http.StatusBadRequest, CodeInvalidTick,
+			"Velocity must move one cell up, down, left or right")
+	case errGamePaused:
+		jsonError(w, http.StatusConflict, CodeGamePaused, "Game is paused; resume it before changing direction")
+	case errQueueFull:
+		jsonError(w, http.StatusTooManyRequests, CodeQueueFull, fmt.Sprintf(
+			"At most %d direction changes can be queued; wait for the next tick", maxQueuedDirections))
+	case errGameNotLive:
+		jsonError(w, http.StatusConflict, CodeGameNotLive,
+			"Game is not live; connect over WebSocket or create it with live=true")
+	case errNotOwner:
+		steerError(w, req.Player)
+	default:
+		storeError(w, err, gameID)
+	}
+}
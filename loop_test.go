@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPausedGamesLeaveTheLoop(t *testing.T) {
+	h := newTestAPI(t)
+	game := newGame(t, h, "", GameConfig{Width: 10, Height: 10, Live: true, Mode: ModeWrap})
+	path := "/v1/games/" + game.GameID
+	ticksPlayed := func() int {
+		state, err := games.Get(context.Background(), game.GameID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return state.TicksPlayed
+	}
+
+	if rec := do(t, h, http.MethodPost, path+"/pause", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("pausing: %d %s", rec.Code, rec.Body)
+	}
+	loop.advance()
+	if n := loop.active(); n != 0 {
+		t.Errorf("loop advances %d games while the only one is paused", n)
+	}
+	if n := ticksPlayed(); n != 0 {
+		t.Errorf("paused game advanced to tick %d", n)
+	}
+
+	if rec := do(t, h, http.MethodPost, path+"/resume", "", nil); rec.Code != http.StatusOK {
+		t.Fatalf("resuming: %d %s", rec.Code, rec.Body)
+	}
+	loop.advance()
+	if n := ticksPlayed(); n != 1 {
+		t.Errorf("resumed game at tick %d, want 1", n)
+	}
+}
+
+func TestUnsteeredLiveGamesExpire(t *testing.T) {
+	h := newTestAPI(t)
+	defer func(now func() time.Time) { clock = now }(clock)
+	now := time.Now()
+	clock = func() time.Time { return now }
+	elapse := func(d time.Duration) {
+		now = now.Add(d)
+		loop.advance()
+	}
+
+	config := GameConfig{Width: 10, Height: 10, Live: true, Mode: ModeWrap, TTLSeconds: 2}
+	idle := newGame(t, h, "", config)
+	steered := newGame(t, h, "", config)
+	for _, tick := range []Tick{{VelY: 1}, {VelX: 1}} {
+		if err := loop.setDirection(context.Background(), steered.GameID, 1, tick); err != nil {
+			t.Fatal(err)
+		}
+		elapse(1500 * time.Millisecond)
+	}
+
+	if _, err := games.Get(context.Background(), idle.GameID); !errors.Is(err, errGameNotFound) {
+		t.Errorf("game nobody steered for its TTL: err = %v, want errGameNotFound", err)
+	}
+	if _, err := games.Get(context.Background(), steered.GameID); err != nil {
+		t.Errorf("steered game: %v", err)
+	}
+	if n := loop.active(); n != 1 {
+		t.Errorf("loop advances %d games, want only the steered one", n)
+	}
+}
@@ -1,16 +1,91 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"flag"
 	"fmt"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	"log"
 	"math/rand"
 	"net/http"
+	"os"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// maxBoardSize is the largest width or height accepted by /new
+const maxBoardSize = 1000
+
+// defaultMaxTicks is the default cap on ticks per validate request
+const defaultMaxTicks = 10000
+
+// maxTicks is the most ticks a single validate request may submit
+var maxTicks = defaultMaxTicks
+
+// defaultMaxBodyBytes is the default size limit of validate request bodies
+const defaultMaxBodyBytes = 1 << 20
+
+// maxBodyBytes is the largest validate request body read before giving up
+var maxBodyBytes int64 = defaultMaxBodyBytes
+
+// GameOverReason tells clients why a game ended. ReasonInvalidMove is only
+// reported on rejected tick batches, which leave the stored game running.
+type GameOverReason string
+
+const (
+	ReasonWall        GameOverReason = "wall"
+	ReasonSelf        GameOverReason = "self"
+	ReasonCollision   GameOverReason = "collision"
+	ReasonObstacle    GameOverReason = "obstacle"
+	ReasonPoison      GameOverReason = "poison"
+	ReasonTimeout     GameOverReason = "timeout"
+	ReasonInvalidMove GameOverReason = "invalid_move"
+	ReasonAbandoned   GameOverReason = "abandoned"
+)
+
+// GameStatus tells whether a game that is not over accepts ticks
+type GameStatus string
+
+const (
+	GameActive GameStatus = "active"
+	GamePaused GameStatus = "paused"
+)
+
+// ValidateStatus tells clients whether a validated game is still running
+type ValidateStatus string
+
+const (
+	ResultOK       ValidateStatus = "ok"
+	ResultGameOver ValidateStatus = "game_over"
+)
+
+// legacyGameOver answers validations that end the game with the 418
+// E_GAME_OVER error of earlier versions instead of a game_over result
+var legacyGameOver bool
+
+// GameMode selects the board rules; the empty mode is classic
+type GameMode string
+
+const (
+	// ModeClassic ends the game when a snake leaves the board
+	ModeClassic GameMode = "classic"
+	// ModeWrap moves a snake leaving the board to the opposite edge
+	ModeWrap GameMode = "wrap"
+)
+
+// valid returns true if the mode is known; empty means classic
+func (m GameMode) valid() bool {
+	return m == "" || m == ModeClassic || m == ModeWrap
+}
+
 type (
 	Position struct {
 		X int `json:"x"`
@@ -23,156 +98,899 @@ type (
 	}
 
 	GameState struct {
-		GameID string   `json:"gameId"`
-		Width  int      `json:"width"`
-		Height int      `json:"height"`
-		Score  int      `json:"score"`
-		Fruit  Position `json:"fruit"`
-		Snake  Snake    `json:"snake"`
-		Ticks  []Tick   `json:"ticks"`
+		GameID string  `json:"gameId"`
+		Width  int     `json:"width"`
+		Height int     `json:"height"`
+		Score  int     `json:"score"`
+		Fruits []Fruit `json:"fruits"`
+		Snake  Snake   `json:"snake"`
+		Ticks  []Tick  `json:"ticks"`
+
+		FruitSpawn FruitSpawn `json:"fruitSpawn,omitempty"`
+		Mode       GameMode   `json:"mode,omitempty"`
+
+		// FruitChances decide how likely each new fruit is golden or poison
+		FruitChances FruitChances `json:"fruitChances"`
+
+		// Obstacles are cells that end the game when a snake moves into them
+		Obstacles []Position `json:"obstacles,omitempty"`
+
+		// PowerUps lie on the board until taken, and Effects are the ones
+		// the snake has taken that have not yet worn off. PowerUpChance is
+		// the percentage of fruit spawns that also drop a power-up.
+		PowerUps      []PowerUp `json:"powerUps,omitempty"`
+		Effects       []Effect  `json:"effects,omitempty"`
+		PowerUpChance int       `json:"powerUpChance,omitempty"`
+
+		// Seed and FruitSpawns determine where the next fruit appears, so a
+		// game can be replayed exactly
+		Seed        int64 `json:"seed"`
+		FruitSpawns int   `json:"fruitSpawns"`
+
+		// TicksPlayed counts the applied ticks; the game times out once it
+		// reaches a non-zero TickLimit
+		TicksPlayed int `json:"ticksPlayed"`
+		TickLimit   int `json:"tickLimit,omitempty"`
+
+		// FruitsEaten counts the fruits the snake has eaten, and
+		// OpponentFruitsEaten those of the opponent in versus games
+		FruitsEaten         int `json:"fruitsEaten,omitempty"`
+		OpponentFruitsEaten int `json:"opponentFruitsEaten,omitempty"`
+
+		// Achievements are those the players reached in this game
+		Achievements []UnlockedAchievement `json:"achievements,omitempty"`
+
+		// Daily is the date of the daily challenge the game was created for
+		Daily string `json:"daily,omitempty"`
+
+		// LastActivity is when the game was created or a client last acted
+		// on it; it is removed once TTLSeconds, or the server default, pass
+		// without activity
+		LastActivity time.Time `json:"lastActivity"`
+		TTLSeconds   int       `json:"ttlSeconds,omitempty"`
+
+		// Version counts the changes to the game since it was created
+		Version int `json:"version"`
+
+		// Live games are advanced by the server's game loop
+		Live bool `json:"live,omitempty"`
+
+		// Versus games pit the Snake against a second player's Opponent.
+		// Winner is 1 or 2 once a versus game is over, or 0 for a draw.
+		Versus        bool   `json:"versus,omitempty"`
+		Opponent      *Snake `json:"opponent,omitempty"`
+		OpponentScore int    `json:"opponentScore,omitempty"`
+		Winner        int    `json:"winner,omitempty"`
+
+		// Bot steers the Opponent when a bot plays it
+		Bot *Bot `json:"bot,omitempty"`
+
+		// Players names the players of a versus game, snake first, who were
+		// paired by matchmaking or joined it logged in; an anonymous player
+		// is empty. Each named player alone steers their snake.
+		Players []string `json:"players,omitempty"`
+
+		// Owner is the account that created the game; only it may change
+		// the game, and the game's score is submitted under its name
+		Owner string `json:"owner,omitempty"`
+
+		// Status is paused while the game clock is frozen and ticks are
+		// rejected; GameOverReason is set once the game has ended
+		Status         GameStatus     `json:"status"`
+		GameOverReason GameOverReason `json:"gameOverReason,omitempty"`
+
+		// Signature authenticates the state in stateless mode
+		Signature string `json:"signature,omitempty"`
+	}
+
+	// ValidateRequest submits ticks to be applied to a stored game
+	ValidateRequest struct {
+		GameID string `json:"gameId"`
+		Ticks  []Tick `json:"ticks"`
+	}
+
+	// ValidateResponse is the simulated state after a validation. Status is
+	// game_over once the snake has died, with the reason in the state; it
+	// replaces the game status, which is always active when ticks apply.
+	ValidateResponse struct {
+		GameState
+		TicksApplied int            `json:"ticksApplied"`
+		Status       ValidateStatus `json:"status"`
+
+		// DryRun is set if the ticks were only checked; the stored game
+		// is unchanged
+		DryRun bool `json:"dryRun,omitempty"`
+	}
+
+	// GameConfig describes a game to create
+	GameConfig struct {
+		Width      int        `json:"width"`
+		Height     int        `json:"height"`
+		FruitSpawn FruitSpawn `json:"fruitSpawn,omitempty"`
+		Mode       GameMode   `json:"mode,omitempty"`
+		Live       bool       `json:"live,omitempty"`
+		Versus     bool       `json:"versus,omitempty"`
+
+		// Obstacles is the number of obstacle cells to place on the board
+		Obstacles int `json:"obstacles,omitempty"`
+
+		// FruitCount is the number of fruits on the board at once; 0 means 1
+		FruitCount int `json:"fruitCount,omitempty"`
+
+		// FruitChances are the percentages of golden and poison fruits
+		FruitChances FruitChances `json:"fruitChances"`
+
+		// PowerUpChance is the percentage of fruit spawns dropping a power-up
+		PowerUpChance int `json:"powerUpChance,omitempty"`
+
+		// TickLimit ends the game with a timeout after this many ticks
+		TickLimit int `json:"tickLimit,omitempty"`
+
+		// TTLSeconds is how long the game is kept without activity; 0 uses
+		// the server default
+		TTLSeconds int `json:"ttlSeconds,omitempty"`
+
+		// Seed makes fruit placement reproducible; a random seed is used if 0
+		Seed int64 `json:"seed,omitempty"`
+
+		// daily is the date of the daily challenge the game is created for
+		daily string
 	}
 
 	Snake struct {
 		Position
 		VelX int `json:"velX"`
 		VelY int `json:"velY"`
+
+		// Body holds the segments behind the head, from neck to tail
+		Body []Position `json:"body"`
 	}
 )
 
-// initializeGame creates a new game with the given board size
-func initializeGame(boardSize Position) GameState {
+// initializeGame creates a new game with the given config
+func initializeGame(config GameConfig) GameState {
 	snake := Snake{
 		Position: Position{X: 0, Y: 0},
 		VelX:     1,
 		VelY:     0,
+		Body:     []Position{},
 	}
-	fruit := generateRandomPosition(boardSize.X, boardSize.Y)
 	gameID := generateGameID()
 
-	return GameState{
-		GameID: gameID,
-		Width:  boardSize.X,
-		Height: boardSize.Y,
-		Score:  0,
-		Fruit:  fruit,
-		Snake:  snake,
-		Ticks:  nil,
+	state := GameState{
+		GameID:     gameID,
+		Width:      config.Width,
+		Height:     config.Height,
+		Score:      0,
+		Snake:      snake,
+		Ticks:      nil,
+		FruitSpawn: config.FruitSpawn,
+		Mode:       config.Mode,
+
+		FruitChances:  config.FruitChances,
+		PowerUpChance: config.PowerUpChance,
+		TickLimit:     config.TickLimit,
+		TTLSeconds:    config.TTLSeconds,
+		LastActivity:  clock().UTC(),
+		Status:        GameActive,
+		Versus:        config.Versus,
+		Seed:          config.Seed,
+		Daily:         config.daily,
+	}
+	if state.Seed == 0 {
+		state.Seed = newSeed()
+	}
+	state.Obstacles = placeObstacles(state, config.Obstacles)
+	for i := 0; i < max(config.FruitCount, 1); i++ {
+		state = spawnFruit(state, i)
 	}
+
+	return state
 }
 
-// generateGameID generates a new game ID
-func generateGameID() string {
-	return fmt.Sprintf("game-%d", time.Now().UnixNano())
+// generateGameID generates a new, random UUIDv4 game ID
+var generateGameID = uuid.NewString
+
+// gameIDFormat matches the IDs returned by generateGameID
+var gameIDFormat = regexp.MustCompile(
+	`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+// validGameID returns true if id could have been generated by this server
+func validGameID(id string) bool {
+	return gameIDFormat.MatchString(id)
 }
 
-// isValidMove returns true if the given move is valid
+// isValidMove returns true unless the next state reverses the snake straight
+// back onto itself
 func isValidMove(currentState, nextState GameState) bool {
-	velChangeX := nextState.Snake.VelX - currentState.Snake.VelX
-	velChangeY := nextState.Snake.VelY - currentState.Snake.VelY
-
-	if (velChangeX == -currentState.Snake.VelX && velChangeY == 0) ||
-		(velChangeX == 0 && velChangeY == -currentState.Snake.VelY) {
-		return false
+	current, next := currentState.Snake, nextState.Snake
+	if current.VelX == 0 && current.VelY == 0 {
+		return true
 	}
 
-	return true
+	return next.VelX != -current.VelX || next.VelY != -current.VelY
 }
 
+// newSeed returns the seed of a new game; replaced in mock mode
+var newSeed = rand.Int63
+
 // generateRandomPosition generates a random position within the given bounds
-func generateRandomPosition(maxX, maxY int) Position {
+func generateRandomPosition(rng *rand.Rand, maxX, maxY int) Position {
 	return Position{
-		X: rand.Intn(maxX),
-		Y: rand.Intn(maxY),
+		X: rng.Intn(maxX),
+		Y: rng.Intn(maxY),
 	}
 }
 
 // newGameHandler creates a new game with the given width and height
 func newGameHandler(w http.ResponseWriter, r *http.Request) {
-	width, err := strconv.Atoi(chi.URLParam(r, "w"))
+	ctx, span := tracer.Start(r.Context(), "newGameHandler")
+	defer span.End()
+
+	width, err := strconv.Atoi(r.URL.Query().Get("w"))
 	if err != nil {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf("Invalid width: %s", err.Error())))
+		jsonError(w, http.StatusBadRequest, CodeInvalidBoard,
+			fmt.Sprintf("Invalid width: %s", err.Error()))
 		return
 	}
-	height, err := strconv.Atoi(chi.URLParam(r, "h"))
+	height, err := strconv.Atoi(r.URL.Query().Get("h"))
 	if err != nil {
-		http.Error(w, "Invalid height", http.StatusBadRequest)
+		jsonError(w, http.StatusBadRequest, CodeInvalidBoard,
+			fmt.Sprintf("Invalid height: %s", err.Error()))
 		return
 	}
 
-	if width <= 0 || height <= 0 {
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(fmt.Sprintf(
-			"Invalid width or height: width=%d, height=%d", width, height)))
+	var seed int64
+	if v := r.URL.Query().Get("seed"); v != "" {
+		seed, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid seed: %s", err.Error()))
+			return
+		}
+	}
+
+	obstacles := 0
+	if v := r.URL.Query().Get("obstacles"); v != "" {
+		obstacles, err = strconv.Atoi(v)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid obstacles: %s", err.Error()))
+			return
+		}
+	}
+
+	fruitCount := 0
+	if v := r.URL.Query().Get("fruitCount"); v != "" {
+		fruitCount, err = strconv.Atoi(v)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid fruitCount: %s", err.Error()))
+			return
+		}
+	}
+
+	var chances FruitChances
+	if v := r.URL.Query().Get("golden"); v != "" {
+		chances.Golden, err = strconv.Atoi(v)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid golden: %s", err.Error()))
+			return
+		}
+	}
+	if v := r.URL.Query().Get("poison"); v != "" {
+		chances.Poison, err = strconv.Atoi(v)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid poison: %s", err.Error()))
+			return
+		}
+	}
+
+	powerUpChance := 0
+	if v := r.URL.Query().Get("powerUps"); v != "" {
+		powerUpChance, err = strconv.Atoi(v)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid powerUps: %s", err.Error()))
+			return
+		}
+	}
+
+	tickLimit := 0
+	if v := r.URL.Query().Get("tickLimit"); v != "" {
+		tickLimit, err = strconv.Atoi(v)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid tickLimit: %s", err.Error()))
+			return
+		}
+	}
+
+	ttl := 0
+	if v := r.URL.Query().Get("ttl"); v != "" {
+		ttl, err = strconv.Atoi(v)
+		if err != nil {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Invalid ttl: %s", err.Error()))
+			return
+		}
+	}
+
+	config := GameConfig{
+		Seed:       seed,
+		Obstacles:  obstacles,
+		FruitCount: fruitCount,
+		Width:      width,
+		Height:     height,
+		FruitSpawn: FruitSpawn(r.URL.Query().Get("fruitSpawn")),
+		Mode:       GameMode(r.URL.Query().Get("mode")),
+
+		FruitChances:  chances,
+		PowerUpChance: powerUpChance,
+		TickLimit:     tickLimit,
+		TTLSeconds:    ttl,
+		Live:          r.URL.Query().Get("live") == "true",
+		Versus:        r.URL.Query().Get("versus") == "true",
+	}
+	span.SetAttributes(
+		attribute.Int("game.width", config.Width),
+		attribute.Int("game.height", config.Height),
+	)
+	if code, message := gameConfigError(config); code != "" {
+		jsonError(w, http.StatusBadRequest, code, message)
 		return
 	}
 
-	boardSize := Position{X: width, Y: height}
-	gameState := initializeGame(boardSize)
+	gameState, err := createGame(ctx, config)
+	if err != nil {
+		span.RecordError(err)
+		storeError(w, err, gameState.GameID)
+		return
+	}
+	span.SetAttributes(attribute.String("game.id", gameState.GameID))
 
+	if wantsProtobuf(r) {
+		protoResponse(w, gameStateToProto(gameState), http.StatusOK)
+		return
+	}
 	jsonResponse(w, gameState)
 }
 
-// validateHandler validates the given game state
+// createGameHandler creates a new game from the JSON config in the body
+func createGameHandler(w http.ResponseWriter, r *http.Request) {
+	var config GameConfig
+	if err := decodeBody(r, &config); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if code, message := gameConfigError(config); code != "" {
+		jsonError(w, http.StatusBadRequest, code, message)
+		return
+	}
+
+	gameState, err := createGame(r.Context(), config)
+	if err != nil {
+		storeError(w, err, gameState.GameID)
+		return
+	}
+
+	jsonResponseWithStatus(w, gameState, http.StatusCreated)
+}
+
+// createGame initializes and stores a new game, starting it if it is live.
+// Versus games are always live.
+func createGame(ctx context.Context, config GameConfig) (GameState, error) {
+	gameState := initializeGame(config)
+	gameState.Owner = accountFrom(ctx)
+	gameState, err := persistNewGame(ctx, gameState)
+	if err != nil {
+		return gameState, err
+	}
+	gamesCreated.Inc()
+	publishEvent(GameEvent{Type: GameCreated}, gameState)
+	if config.Live || config.Versus {
+		return loop.start(ctx, gameState.GameID)
+	}
+
+	return gameState, nil
+}
+
+// gameConfigError returns the error code and message describing why the
+// given config cannot be used to create a game, or an empty code if it can
+func gameConfigError(config GameConfig) (ErrorCode, string) {
+	if code, message := boardSizeError(config.Width, config.Height); code != "" {
+		return code, message
+	}
+	if !config.FruitSpawn.valid() {
+		return CodeInvalidRequest, fmt.Sprintf("Unknown fruitSpawn strategy: %s", config.FruitSpawn)
+	}
+	if !config.Mode.valid() {
+		return CodeInvalidRequest, fmt.Sprintf("Unknown mode: %s", config.Mode)
+	}
+	if config.Versus && (config.Width < 2 || config.Height < 2) {
+		return CodeInvalidBoard, "Versus games need a board of at least 2x2"
+	}
+	if code, message := obstacleCountError(config); code != "" {
+		return code, message
+	}
+	if code, message := fruitCountError(config); code != "" {
+		return code, message
+	}
+	if code, message := fruitChancesError(config.FruitChances); code != "" {
+		return code, message
+	}
+	if code, message := powerUpChanceError(config); code != "" {
+		return code, message
+	}
+	if config.TickLimit < 0 {
+		return CodeInvalidRequest, fmt.Sprintf("Invalid tickLimit: %d", config.TickLimit)
+	}
+	if code, message := ttlError(config); code != "" {
+		return code, message
+	}
+	if signingKey != nil && (config.Live || config.Versus) {
+		return CodeInvalidRequest, "Live and versus games are not available in stateless mode"
+	}
+	return "", ""
+}
+
+// boardSizeError returns the error code and message describing why the given
+// board size is not allowed, or an empty code if it is valid
+func boardSizeError(width, height int) (ErrorCode, string) {
+	if width <= 0 || height <= 0 {
+		return CodeInvalidBoard, fmt.Sprintf(
+			"Invalid width or height: width=%d, height=%d", width, height)
+	}
+	if width > maxBoardSize || height > maxBoardSize {
+		return CodeBoardTooLarge, fmt.Sprintf(
+			"Board too large: width=%d, height=%d, max=%d", width, height, maxBoardSize)
+	}
+	return "", ""
+}
+
+// validateHandler applies the given ticks to the stored game state
 func validateHandler(w http.ResponseWriter, r *http.Request) {
-	var currentState GameState
-	decoder := json.NewDecoder(r.Body)
-	err := decoder.Decode(&currentState)
+	ctx, span := tracer.Start(r.Context(), "validateHandler")
+	defer span.End()
+	r = r.WithContext(ctx)
+	r.Body = http.MaxBytesReader(w, r.Body, maxBodyBytes)
+
+	if signingKey != nil {
+		if isProtobuf(r) {
+			jsonResponseWithStatus(w, ErrorResponse{
+				Code:      CodeUnsupportedType,
+				Message:   "Signed states are only accepted as JSON in stateless mode",
+				Supported: []string{"application/json"},
+			}, http.StatusUnsupportedMediaType)
+			return
+		}
+		statelessValidateHandler(w, r)
+		return
+	}
+
+	var req ValidateRequest
+	var err error
+	if isProtobuf(r) {
+		req, err = decodeProtoValidateRequest(r)
+	} else {
+		err = decodeBody(r, &req)
+	}
 	if err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		bodyError(w, err)
 		return
 	}
 	defer r.Body.Close()
 
-	newGameState, statusCode := validateTicks(currentState)
-	jsonResponseWithStatus(w, newGameState, statusCode)
+	if gameID := chi.URLParam(r, "id"); gameID != "" {
+		req.GameID = gameID
+	}
+	auditGame(ctx, req.GameID, len(req.Ticks))
+	if !validGameID(req.GameID) {
+		gameIDError(w, req.GameID)
+		return
+	}
+	span.SetAttributes(
+		attribute.String("game.id", req.GameID),
+		attribute.Int("validate.ticks", len(req.Ticks)),
+	)
+	if tooManyTicks(w, len(req.Ticks)) {
+		return
+	}
+
+	ctx = withIfMatch(ctx, w, r)
+	if ctx, err = withDryRun(ctx, r); err != nil {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, "dryRun must be true or false")
+		return
+	}
+	r = r.WithContext(ctx)
+	newGameState, ticksApplied, statusCode, err := validateStored(ctx, req.GameID, req.Ticks)
+	if err == errGamePaused {
+		jsonError(w, http.StatusConflict, CodeGamePaused, "Game is paused; resume it before sending ticks")
+		return
+	}
+	if err == errVersionConflict {
+		jsonResponseWithStatus(w, ErrorResponse{
+			Code:    CodeVersionConflict,
+			Message: "Game state has changed since it was read; apply the ticks to the current state",
+			State:   &newGameState,
+		}, http.StatusPreconditionFailed)
+		return
+	}
+	if err == errNotOwner {
+		ownerError(w)
+		return
+	}
+	if err != nil {
+		storeError(w, err, req.GameID)
+		return
+	}
+
+	writeValidateResult(w, r, newGameState, ticksApplied, statusCode)
+}
+
+// validateStored applies ticks to the stored game and records them for its
+// replay, returning the result like validateTicks. Live games are left
+// untouched with a 409, paused games with errGamePaused, games of another
+// account with errNotOwner and games failing the If-Match precondition of
+// ctx with errVersionConflict. The submission is recorded in the audit log.
+func validateStored(ctx context.Context, gameID string, ticks []Tick) (GameState, int, int, error) {
+	ctx, audited := auditStored(ctx, gameID, len(ticks))
+	newGameState, ticksApplied, statusCode, err := applyStored(ctx, gameID, ticks)
+	audited(statusCode, ticksApplied, err)
+	return newGameState, ticksApplied, statusCode, err
 }
 
-// validateTicks validates the given ticks and returns the new game state
-func validateTicks(currentState GameState) (GameState, int) {
-	if isGameOver(currentState) {
-		return currentState, http.StatusTeapot
+// applyStored implements validateStored
+func applyStored(ctx context.Context, gameID string, ticks []Tick) (GameState, int, int, error) {
+	var statusCode, ticksApplied int
+	var paused, owned, finished, conflict bool
+	var unlocked []UnlockedAchievement
+	var before, preview GameState
+	dryRun := isDryRun(ctx)
+	apply := func(state GameState) (GameState, []ReplayTick) {
+		// Redis retries fn on conflicts, so nothing may carry over from an
+		// earlier attempt
+		statusCode, ticksApplied = 0, 0
+		finished, unlocked, before = false, nil, state
+		owned = ownedBy(state, ctx)
+		paused = state.Status == GamePaused && state.GameOverReason == ""
+		conflict = !preconditionHolds(ctx, state)
+		if !owned || paused || conflict {
+			return state, nil
+		}
+		if state.Live && state.GameOverReason == "" {
+			statusCode = http.StatusConflict
+			return state, nil
+		}
+
+		state.Ticks = ticks
+		var newState GameState
+		newState, ticksApplied, statusCode = validateTicks(ctx, state)
+		newState.Ticks = nil
+		switch {
+		case statusCode == http.StatusBadRequest:
+			// Invalid tick sequences are rejected as a whole
+			state.Ticks = nil
+			return state, nil
+		case statusCode == http.StatusTeapot && state.GameOverReason == "":
+			finished = true
+			newState, unlocked = unlockAchievements(touch(newState))
+			return newState, replayTicks(ticks[:ticksApplied])
+		case statusCode == http.StatusOK:
+			newState, unlocked = unlockAchievements(touch(newState))
+			return newState, replayTicks(ticks)
+		}
+		return newState, nil
+	}
+	newGameState, err := games.UpdateRecorded(ctx, gameID, func(state GameState) (GameState, []ReplayTick) {
+		newState, recorded := apply(state)
+		if dryRun {
+			// A dry run keeps the outcome for the response but stores
+			// the game as it was
+			preview = newState
+			return state, nil
+		}
+		return newState, recorded
+	})
+	if err != nil {
+		return newGameState, 0, 0, err
+	}
+	if !owned {
+		return newGameState, 0, 0, errNotOwner
+	}
+	if paused {
+		return newGameState, 0, 0, errGamePaused
+	}
+	if conflict {
+		return newGameState, 0, 0, errVersionConflict
 	}
+	if dryRun {
+		return preview, ticksApplied, statusCode, nil
+	}
+	if statusCode == http.StatusOK || statusCode == http.StatusTeapot {
+		loop.publish(gameID, newGameState, true)
+		publishProgress(before, newGameState)
+	}
+	if finished {
+		recordGame(newGameState)
+		notifyGameOver(newGameState)
+	}
+	recordAchievements(newGameState, unlocked)
+	return newGameState, ticksApplied, statusCode, nil
+}
 
-	if isFruitEaten(currentState) {
-		currentState.Score++
-		currentState.Fruit = generateRandomPosition(currentState.Width, currentState.Height)
+// tooManyTicks writes a 413 and returns true if a validate request submits
+// more than maxTicks ticks
+func tooManyTicks(w http.ResponseWriter, n int) bool {
+	if n <= maxTicks {
+		return false
 	}
+	jsonError(w, http.StatusRequestEntityTooLarge, CodeTooManyTicks,
+		fmt.Sprintf("Too many ticks: %d, max=%d; split them over several requests", n, maxTicks))
+	return true
+}
 
-	newGameState := currentState
-	for _, tick := range currentState.Ticks {
-		newSnake := Snake{
-			Position: Position{
-				X: newGameState.Snake.X + tick.VelX,
-				Y: newGameState.Snake.Y + tick.VelY,
-			},
-			VelX: tick.VelX,
-			VelY: tick.VelY,
+// writeValidateResult writes the response for a validation that ended with
+// the given state, number of applied ticks and status code, as protobuf if
+// the request accepts it
+func writeValidateResult(w http.ResponseWriter, r *http.Request, newGameState GameState, ticksApplied, statusCode int) {
+	switch statusCode {
+	case http.StatusConflict:
+		observeValidation(statusCode, "live", ticksApplied)
+		auditResult(r.Context(), "live", ticksApplied)
+		jsonError(w, statusCode, CodeGameLive,
+			"Game is advanced by the server; send direction changes instead")
+	case http.StatusBadRequest:
+		observeValidation(statusCode, "invalid_tick", ticksApplied)
+		auditResult(r.Context(), "invalid_tick", ticksApplied)
+		jsonResponseWithStatus(w, ErrorResponse{
+			Code:    CodeInvalidTick,
+			Message: fmt.Sprintf("Invalid move at tick %d", ticksApplied),
+			State:   &newGameState,
+			Reason:  ReasonInvalidMove,
+		}, statusCode)
+	case http.StatusTeapot:
+		if legacyGameOver {
+			observeValidation(statusCode, string(ResultGameOver), ticksApplied)
+			auditResult(r.Context(), string(ResultGameOver), ticksApplied)
+			jsonResponseWithStatus(w, ErrorResponse{
+				Code:    CodeGameOver,
+				Message: fmt.Sprintf("Game over: %s", newGameState.GameOverReason),
+				State:   &newGameState,
+			}, statusCode)
+			return
 		}
+		observeValidation(http.StatusOK, string(ResultGameOver), ticksApplied)
+		auditResult(r.Context(), string(ResultGameOver), ticksApplied)
+		validateResponse(w, r, ValidateResponse{
+			GameState:    newGameState,
+			TicksApplied: ticksApplied,
+			Status:       ResultGameOver,
+			DryRun:       isDryRun(r.Context()),
+		}, http.StatusOK)
+	default:
+		observeValidation(statusCode, string(ResultOK), ticksApplied)
+		auditResult(r.Context(), string(ResultOK), ticksApplied)
+		validateResponse(w, r, ValidateResponse{
+			GameState:    newGameState,
+			TicksApplied: ticksApplied,
+			Status:       ResultOK,
+			DryRun:       isDryRun(r.Context()),
+		}, statusCode)
+	}
+}
+
+// validateResponse writes a ValidateResponse in the format the request accepts
+func validateResponse(w http.ResponseWriter, r *http.Request, response ValidateResponse, statusCode int) {
+	if wantsProtobuf(r) {
+		protoResponse(w, validateResponseToProto(response), statusCode)
+		return
+	}
+	jsonResponseWithStatus(w, response, statusCode)
+}
+
+// validationOutcome records the metrics of a validation served over an API
+// other than JSON and returns its status, or the error code and message
+// describing why the ticks were not applied
+func validationOutcome(statusCode, ticksApplied int) (ValidateStatus, ErrorCode, string) {
+	switch statusCode {
+	case http.StatusConflict:
+		observeValidation(statusCode, "live", ticksApplied)
+		return "", CodeGameLive, "Game is advanced by the server; send direction changes instead"
+	case http.StatusBadRequest:
+		observeValidation(statusCode, "invalid_tick", ticksApplied)
+		return "", CodeInvalidTick, fmt.Sprintf("Invalid move at tick %d", ticksApplied)
+	case http.StatusTeapot:
+		observeValidation(http.StatusOK, string(ResultGameOver), ticksApplied)
+		return ResultGameOver, "", ""
+	}
+	observeValidation(statusCode, string(ResultOK), ticksApplied)
+	return ResultOK, "", ""
+}
+
+// validateTicks applies the given ticks in order and returns the resulting
+// game state together with the number of ticks that were applied. The tick
+// that ends the game counts as applied; an invalid tick does not.
+func validateTicks(ctx context.Context, currentState GameState) (GameState, int, int) {
+	_, span := tracer.Start(ctx, "validateTicks",
+		trace.WithAttributes(attribute.Int("validate.ticks", len(currentState.Ticks))))
+	defer span.End()
 
-		if !isValidMove(newGameState, GameState{Snake: newSnake}) {
-			return currentState, http.StatusBadRequest
+	newGameState, ticksApplied, statusCode := simulateTicks(currentState)
+	span.SetAttributes(
+		attribute.Int("validate.ticks_applied", ticksApplied),
+		attribute.Int("validate.status", statusCode),
+	)
+	return newGameState, ticksApplied, statusCode
+}
+
+// simulateTicks is the tick loop of validateTicks
+func simulateTicks(currentState GameState) (GameState, int, int) {
+	newGameState := currentState
+	for i, tick := range currentState.Ticks {
+		over := newGameState.GameOverReason != ""
+		var statusCode int
+		newGameState, statusCode = stepGame(newGameState, tick)
+		if statusCode == http.StatusTeapot && !over {
+			return newGameState, i + 1, statusCode
 		}
+		if statusCode != http.StatusOK {
+			return newGameState, i, statusCode
+		}
+	}
 
-		newGameState.Snake = newSnake
+	return newGameState, len(currentState.Ticks), http.StatusOK
+}
+
+// stepGame advances the game by a single tick: the snake moves, the game ends
+// if it hits a wall or itself, and otherwise it eats the fruit and takes the
+// power-up it lands on. Active effects decide how many cells it moves.
+func stepGame(state GameState, tick Tick) (GameState, int) {
+	if state.GameOverReason != "" {
+		return state, http.StatusTeapot
+	}
 
-		if isGameOver(newGameState) {
-			return currentState, http.StatusTeapot
+	if !isUnitTick(tick) || !isValidMove(state, GameState{Snake: Snake{VelX: tick.VelX, VelY: tick.VelY}}) {
+		return state, http.StatusBadRequest
+	}
+
+	state.TicksPlayed++
+	steps := moveSteps(state)
+	if steps == 0 {
+		state.Snake.VelX, state.Snake.VelY = tick.VelX, tick.VelY
+	}
+	for ; steps > 0; steps-- {
+		state.Snake = wrapSnake(state, moveSnake(state.Snake, tick))
+
+		if reason := gameOverReason(state); reason != "" {
+			state.GameOverReason = reason
+			return state, http.StatusTeapot
+		}
+
+		if i := fruitAt(state, state.Snake.Position); i >= 0 {
+			points := state.Fruits[i].points()
+			if hasEffect(state, PowerUpSpeed) {
+				points *= 2
+			}
+			state.Score += points
+			state.FruitsEaten++
+			state.Snake = growSnake(state.Snake)
+			state = spawnFruit(state, i)
 		}
 	}
 
-	return currentState, http.StatusOK
+	state = wearEffects(state)
+	state = takePowerUp(state)
+	if timedOut(state) {
+		state.GameOverReason = ReasonTimeout
+		return state, http.StatusTeapot
+	}
+	return state, http.StatusOK
+}
+
+// timedOut returns true if the game has used up its tick limit
+func timedOut(state GameState) bool {
+	return state.TickLimit > 0 && state.TicksPlayed >= state.TickLimit
+}
+
+// isUnitTick returns true if the tick moves at most one cell and never
+// diagonally
+func isUnitTick(tick Tick) bool {
+	return abs(tick.VelX)+abs(tick.VelY) <= 1
+}
+
+// wrapSnake moves a head that left the board to the opposite edge in wrap
+// mode or while phasing; otherwise the snake is returned unchanged
+func wrapSnake(state GameState, snake Snake) Snake {
+	if state.Mode != ModeWrap && !hasEffect(state, PowerUpPhase) {
+		return snake
+	}
+	snake.X = ((snake.X % state.Width) + state.Width) % state.Width
+	snake.Y = ((snake.Y % state.Height) + state.Height) % state.Height
+	return snake
+}
+
+// moveSnake moves the snake head by the tick velocity, with every body
+// segment following the one ahead of it
+func moveSnake(snake Snake, tick Tick) Snake {
+	body := make([]Position, len(snake.Body))
+	if len(body) > 0 {
+		body[0] = snake.Position
+		copy(body[1:], snake.Body[:len(snake.Body)-1])
+	}
+
+	return Snake{
+		Position: Position{
+			X: snake.X + tick.VelX,
+			Y: snake.Y + tick.VelY,
+		},
+		VelX: tick.VelX,
+		VelY: tick.VelY,
+		Body: body,
+	}
+}
+
+// growSnake adds a segment at the tail of the snake. The new segment overlaps
+// the tail until the next move, when the tail advances and leaves it behind.
+func growSnake(snake Snake) Snake {
+	tail := snake.Position
+	if len(snake.Body) > 0 {
+		tail = snake.Body[len(snake.Body)-1]
+	}
+
+	body := make([]Position, len(snake.Body), len(snake.Body)+1)
+	copy(body, snake.Body)
+	snake.Body = append(body, tail)
+	return snake
 }
 
-// isGameOver returns true if the snake has hit a wall
+// isGameOver returns true if the snake has hit a wall or itself
 func isGameOver(state GameState) bool {
-	return state.Snake.X >= state.Width || state.Snake.Y >= state.Height ||
-		state.Snake.X < 0 || state.Snake.Y < 0
+	return gameOverReason(state) != ""
+}
+
+// gameOverReason returns why the game is over, or an empty reason if the
+// snake is still alive
+func gameOverReason(state GameState) GameOverReason {
+	return snakeCollision(state, state.Snake)
+}
+
+// snakeCollision returns why the given snake has died on the board, or an
+// empty reason if it has not hit a wall or its own body
+func snakeCollision(state GameState, snake Snake) GameOverReason {
+	if snake.X >= state.Width || snake.Y >= state.Height || snake.X < 0 || snake.Y < 0 {
+		return ReasonWall
+	}
+
+	for _, segment := range snake.Body {
+		if segment == snake.Position {
+			return ReasonSelf
+		}
+	}
+
+	if isObstacle(state, snake.Position) {
+		return ReasonObstacle
+	}
+
+	if i := fruitAt(state, snake.Position); i >= 0 && state.Fruits[i].Type == FruitPoison {
+		return ReasonPoison
+	}
+
+	return ""
 }
 
-// isFruitEaten returns true if the snake has eaten the fruit
-func isFruitEaten(state GameState) bool {
-	return state.Snake.X == state.Fruit.X && state.Snake.Y == state.Fruit.Y
+// fruitAt returns the index of the fruit at pos, or -1 if there is none
+func fruitAt(state GameState, pos Position) int {
+	for i, fruit := range state.Fruits {
+		if fruit.Position == pos {
+			return i
+		}
+	}
+	return -1
 }
 
 // parseQueryParam parses the given query parameter from the request
@@ -193,23 +1011,333 @@ func parseQueryParam(r *http.Request, param string) int {
 
 // jsonResponse writes the given response as JSON
 func jsonResponse(w http.ResponseWriter, response any) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	encodeBody(w, response)
 }
 
 // jsonResponseWithStatus writes the given response as JSON with the given status code
 func jsonResponseWithStatus(w http.ResponseWriter, response any, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", responseCodec(w).mediaType)
 	w.WriteHeader(statusCode)
 	jsonResponse(w, response)
 }
 
+// commands are the subcommands of the snake binary
+var commands = map[string]func(args []string){
+	"serve":    serveCommand,
+	"play":     playCommand,
+	"simulate": simulateCommand,
+}
+
+const usage = `usage: snake [command] [flags]
+
+Commands:
+  serve      run the API server (default)
+  play       play a game against a server in the terminal
+  simulate   replay tick files offline and verify their outcome
+
+Run snake <command> -h for the flags of a command.
+`
+
 func main() {
+	args := os.Args[1:]
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help" {
+		// Flags without a command configure the server, as before commands
+		serveCommand(args)
+		return
+	}
+
+	command, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprint(os.Stderr, usage)
+		if args[0] != "-h" && args[0] != "--help" && args[0] != "help" {
+			os.Exit(2)
+		}
+		return
+	}
+	command(args[1:])
+}
+
+// serveCommand runs the API server; it is the default command
+func serveCommand(args []string) {
+	flags := flag.NewFlagSet("serve", flag.ExitOnError)
+	mock := flags.Bool("mock", false,
+		"serve deterministic responses and honor "+mockFailureHeader+" for client development")
+	tickInterval := flags.Duration("tick-interval", defaultTickInterval,
+		"how often the game loop advances live games")
+	storeKind := flags.String("store", "memory", "where game sessions are kept: memory or redis")
+	redisURL := flags.String("redis-url", "redis://localhost:6379/0", "Redis server used by -store=redis")
+	dbPath := flags.String("db", "snake.db", "path of the SQLite database holding the leaderboard")
+	stateless := flags.Bool("stateless", false,
+		"keep no games server-side; clients round-trip HMAC-signed states (key from $"+signingKeyEnv+")")
+	dev := flags.Bool("dev", false, "enable development-only endpoints such as /dev/fixtures")
+	addr := flags.String("addr", envOr("ADDR", defaultAddr), "address to listen on ($ADDR)")
+	grpcAddr := flags.String("grpc-addr", envOr("GRPC_ADDR", defaultGRPCAddr),
+		"address the gRPC service listens on, empty to disable ($GRPC_ADDR)")
+	readTimeout := flags.Duration("read-timeout", envDuration("READ_TIMEOUT", defaultReadTimeout),
+		"the longest a client may take to send a request ($READ_TIMEOUT)")
+	writeTimeout := flags.Duration("write-timeout", envDuration("WRITE_TIMEOUT", defaultWriteTimeout),
+		"the longest a response may take to write, except streams ($WRITE_TIMEOUT)")
+	idleTimeout := flags.Duration("idle-timeout", envDuration("IDLE_TIMEOUT", defaultIdleTimeout),
+		"how long idle keep-alive connections stay open ($IDLE_TIMEOUT)")
+	traceExporter := flags.String("trace-exporter", envOr("TRACE_EXPORTER", "none"),
+		"where spans are sent: none, stdout, or otlp via $OTEL_EXPORTER_OTLP_ENDPOINT ($TRACE_EXPORTER)")
+	rateLimitPerSecond := flags.Float64("rate-limit", envFloat("RATE_LIMIT", 0),
+		"requests per second accepted across all clients, 0 for no limit ($RATE_LIMIT)")
+	rateBurst := flags.Int("rate-burst", envInt("RATE_BURST", 50),
+		"requests accepted at once above -rate-limit ($RATE_BURST)")
+	hintRate := flags.Float64("hint-rate", envFloat("HINT_RATE", defaultHintRate),
+		"hints per second given for a single game ($HINT_RATE)")
+	hintBurst := flags.Int("hint-burst", envInt("HINT_BURST", defaultHintBurst),
+		"hints given at once for a single game above -hint-rate ($HINT_BURST)")
+	flags.Int64Var(&maxBodyBytes, "max-body-bytes", int64(envInt("MAX_BODY_BYTES", defaultMaxBodyBytes)),
+		"the largest validate request body accepted, in bytes ($MAX_BODY_BYTES)")
+	flags.DurationVar(&gameTTL, "game-ttl", envDuration("GAME_TTL", defaultGameTTL),
+		"how long games are kept without activity unless created with a ttl ($GAME_TTL)")
+	flags.BoolVar(&legacyGameOver, "teapot", false,
+		"answer validations that end the game with the legacy 418 E_GAME_OVER error")
+	flags.DurationVar(&tokenTTL, "token-ttl", envDuration("TOKEN_TTL", defaultTokenTTL),
+		"how long login tokens stay valid ($TOKEN_TTL)")
+	flags.BoolVar(&requireAuth, "require-auth", false,
+		"reject game requests without a login token instead of playing them anonymously")
+	oauthConfig := OAuthConfig{
+		OIDCClientSecret:   os.Getenv(oidcClientSecretEnv),
+		GitHubClientSecret: os.Getenv(githubClientSecretEnv),
+	}
+	flags.StringVar(&oauthConfig.RedirectURL, "oauth-redirect-url", envOr("OAUTH_REDIRECT_URL", ""),
+		"public base URL identity providers redirect players back to ($OAUTH_REDIRECT_URL)")
+	flags.StringVar(&oauthConfig.OIDCProvider, "oidc-provider", envOr("OIDC_PROVIDER", "oidc"),
+		"name of the OIDC provider in login URLs, such as google ($OIDC_PROVIDER)")
+	flags.StringVar(&oauthConfig.OIDCIssuer, "oidc-issuer", envOr("OIDC_ISSUER", "https://accounts.google.com"),
+		"OpenID Connect issuer players can log in with ($OIDC_ISSUER)")
+	flags.StringVar(&oauthConfig.OIDCClientID, "oidc-client-id", envOr("OIDC_CLIENT_ID", ""),
+		"client ID at the OIDC issuer, empty to disable; the secret is read from $"+oidcClientSecretEnv+" ($OIDC_CLIENT_ID)")
+	flags.StringVar(&oauthConfig.GitHubClientID, "github-client-id", envOr("GITHUB_CLIENT_ID", ""),
+		"client ID of the GitHub OAuth app, empty to disable; the secret is read from $"+githubClientSecretEnv+" ($GITHUB_CLIENT_ID)")
+	var eventBusConfig EventBusConfig
+	flags.StringVar(&eventBusConfig.Kind, "event-bus", envOr("EVENT_BUS", "none"),
+		"where game events are published: none, nats or kafka ($EVENT_BUS)")
+	flags.StringVar(&eventBusConfig.NATSURL, "nats-url", envOr("NATS_URL", "nats://localhost:4222"),
+		"NATS server used by -event-bus=nats ($NATS_URL)")
+	flags.StringVar(&eventBusConfig.KafkaBrokers, "kafka-brokers", envOr("KAFKA_BROKERS", "localhost:9092"),
+		"comma-separated Kafka brokers used by -event-bus=kafka ($KAFKA_BROKERS)")
+	flags.StringVar(&eventBusConfig.Topic, "event-topic", envOr("EVENT_TOPIC", "snake.games"),
+		"Kafka topic, or prefix of the NATS subjects, game events are published on ($EVENT_TOPIC)")
+	flags.IntVar(&maxTicks, "max-ticks", defaultMaxTicks, "the most ticks a single validate request may submit")
+	flags.Parse(args)
+	hintLimiter = newGameLimiter(rate.Limit(*hintRate), *hintBurst)
+
+	switch *storeKind {
+	case "memory":
+	case "redis":
+		store, err := newRedisStore(context.Background(), *redisURL)
+		if err != nil {
+			log.Fatalf("connecting to redis: %v", err)
+		}
+		games = store
+	default:
+		log.Fatalf("unknown store %q, expected memory or redis", *storeKind)
+	}
+
+	shutdownTracing, err := setupTracing(context.Background(), *traceExporter)
+	if err != nil {
+		log.Fatalf("setting up tracing: %v", err)
+	}
+	defer shutdownTracing(context.Background())
+
+	closeEventBus, err := setupEventBus(eventBusConfig)
+	if err != nil {
+		log.Fatalf("setting up event bus: %v", err)
+	}
+	defer closeEventBus()
+
+	if *stateless {
+		enableStatelessMode(os.Getenv(signingKeyEnv))
+	}
+	setJWTSecret(os.Getenv(jwtSecretEnv))
+
+	scores, err = openLeaderboard(*dbPath)
+	if err != nil {
+		log.Fatalf("opening leaderboard database: %v", err)
+	}
+	if tournaments, err = openTournaments(scores.db); err != nil {
+		log.Fatalf("opening tournament tables: %v", err)
+	}
+	if ratings, err = openRatings(scores.db); err != nil {
+		log.Fatalf("opening rating tables: %v", err)
+	}
+	if history, err = openHistory(scores.db); err != nil {
+		log.Fatalf("opening game history tables: %v", err)
+	}
+	if playerAchievements, err = openAchievements(scores.db); err != nil {
+		log.Fatalf("opening achievement tables: %v", err)
+	}
+	if err = openDailyScores(scores.db); err != nil {
+		log.Fatalf("opening daily leaderboard tables: %v", err)
+	}
+	if err = openRanks(scores.db); err != nil {
+		log.Fatalf("opening rank tables: %v", err)
+	}
+	if accounts, err = openAccounts(scores.db); err != nil {
+		log.Fatalf("opening account tables: %v", err)
+	}
+	if err = openIdentities(scores.db); err != nil {
+		log.Fatalf("opening identity tables: %v", err)
+	}
+	if err = setupIdentityProviders(context.Background(), oauthConfig); err != nil {
+		log.Fatalf("setting up identity providers: %v", err)
+	}
+	if apiKeys, err = openAPIKeys(scores.db, os.Getenv(adminKeyEnv)); err != nil {
+		log.Fatalf("opening API key tables: %v", err)
+	}
+	if webhooks, err = openWebhooks(scores.db); err != nil {
+		log.Fatalf("opening webhook tables: %v", err)
+	}
+	if audits, err = openAudits(scores.db); err != nil {
+		log.Fatalf("opening audit tables: %v", err)
+	}
+
+	loop = newGameLoop(*tickInterval)
+	go loop.run()
+	go runReaper(reapInterval)
+	go matchmaking.run(matchInterval)
+	go webhooks.run(webhookInterval)
+
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
+	r.Use(instrument)
+	if *rateLimitPerSecond > 0 {
+		r.Use(rateLimit(rate.Limit(*rateLimitPerSecond), *rateBurst))
+	}
+	r.Use(requireContentType)
+	r.Use(negotiate)
+	r.Use(authenticateRequest)
+	r.Use(identifyClient)
+	if *mock {
+		enableMockMode()
+		r.Use(mockFailureInjector)
+	}
+
+	r.Route("/v1", v1Routes)
+
+	// Unversioned aliases kept for existing challenge clients
+	r.Group(func(r chi.Router) {
+		r.Use(deprecated("/v1"))
+		r.Group(func(r chi.Router) {
+			r.Use(requireAccount(ScopePlay))
+			r.Get("/new", newGameHandler)
+			r.Post("/new/batch", newBatchHandler)
+			r.With(auditValidation).Post("/validate", validateHandler)
+			r.With(requireGameID, requirePlayer).Get("/games/{id}/ws", wsHandler)
+			r.With(requireGameID).Get("/games/{id}/events", eventsHandler)
+			r.With(requireGameID).Get("/games/{id}/replay", replayHandler)
+			r.With(requireGameID).Get("/games/{id}/render", renderHandler)
+			r.With(requireGameID).Get("/games/{id}/replay.gif", replayGIFHandler)
+			r.With(requireGameID).Get("/games/{id}/hint", hintHandler)
+			r.With(requireGameID).Post("/replays/{id}/play", playReplayHandler)
+			r.With(requireGameID, requirePlayer).Patch("/games/{id}/direction", directionHandler)
+			r.With(requireGameID).Post("/games/{id}/join", joinHandler)
+			r.With(requireGameID, requireOwner).Post("/games/{id}/bot", botHandler)
+		})
+		r.With(requireAccount(ScopeScores)).Post("/scores", submitScoreHandler)
+		r.Get("/leaderboard", leaderboardHandler)
+	})
+	if *dev {
+		r.Get("/dev/fixtures/{name}", fixtureHandler)
+	}
+	r.With(requireAccount(ScopePlay)).Post("/graphql", graphqlHandler)
+	r.With(requireAccount(ScopePlay)).Get("/graphql", graphqlWSHandler)
+	r.Method(http.MethodGet, "/metrics", promhttp.Handler())
+	r.Get("/healthz", healthzHandler)
+	r.Get("/readyz", readyzHandler)
 
-	r.Get("/new", newGameHandler)
-	r.Post("/validate", validateHandler)
+	var spec openAPIDocument
+	r.Get("/openapi.json", openAPIHandler(&spec))
+	r.Handle("/docs", docsHandler())
+	r.Handle("/docs/*", docsHandler())
+	if spec, err = openAPISpec(r); err != nil {
+		log.Fatalf("describing routes: %v", err)
+	}
+
+	server := newServer(*addr, r, *readTimeout, *writeTimeout, *idleTimeout)
+	if err := serve(server, newGRPCServer(), *grpcAddr); err != nil {
+		log.Fatal(err)
+	}
+}
 
-	http.ListenAndServe(":8080", r)
+// v1Routes registers the versioned API on r
+func v1Routes(r chi.Router) {
+	r.With(requireAccount(ScopePlay)).Post("/games", createGameHandler)
+	r.With(requireAccount(ScopePlay)).Post("/games/batch", newBatchHandler)
+	r.Route("/games/{id}", func(r chi.Router) {
+		r.Use(requireGameID)
+		r.Use(requireAccount(ScopePlay))
+		r.Get("/", getGameHandler)
+		r.Get("/wait", waitHandler)
+		r.With(requireOwner).Delete("/", deleteGameHandler)
+		r.With(requireOwner).Post("/pause", pauseHandler)
+		r.With(requireOwner).Post("/resume", resumeHandler)
+		r.With(auditValidation).Post("/validate", validateHandler)
+		r.Post("/join", joinHandler)
+		r.With(requireOwner).Post("/bot", botHandler)
+		r.With(requirePlayer).Get("/ws", wsHandler)
+		r.Get("/events", eventsHandler)
+		r.Get("/replay", replayHandler)
+		r.Get("/state", stateAtHandler)
+		r.Get("/render", renderHandler)
+		r.Get("/replay.gif", replayGIFHandler)
+		r.Get("/hint", hintHandler)
+		r.With(requirePlayer).Patch("/direction", directionHandler)
+	})
+	r.With(requireGameID, requireAccount(ScopePlay)).Post("/replays/{id}/play", playReplayHandler)
+	r.With(requireAccount(ScopePlay)).Post("/replays/diff", replayDiffHandler)
+	r.With(requireAccount(ScopeScores)).Post("/scores", submitScoreHandler)
+	r.Get("/leaderboard", leaderboardHandler)
+	r.Get("/leaderboards", leaderboardsHandler)
+	r.With(requireAccount(ScopePlay)).Get("/daily", dailyHandler)
+	r.Get("/daily/leaderboard", dailyLeaderboardHandler)
+	r.Get("/bots", botsHandler)
+	r.Get("/players/{id}/rating", playerRatingHandler)
+	r.Get("/players/{id}/stats", playerStatsHandler)
+	r.Get("/players/{id}/rank", playerRankHandler)
+	r.Get("/players/{id}/achievements", playerAchievementsHandler)
+	r.Get("/achievements", achievementsHandler)
+	r.Get("/ratings", ratingsHandler)
+	r.Get("/stats", serverStatsHandler)
+	r.With(requireLogin(ScopePlay)).Post("/matchmaking/queue", queueHandler)
+	r.Route("/matchmaking/queue/{ticket}", func(r chi.Router) {
+		r.Use(requireLogin(ScopePlay))
+		r.Get("/", ticketHandler)
+		r.Delete("/", cancelTicketHandler)
+		r.Get("/events", ticketEventsHandler)
+	})
+	r.With(requireLogin(ScopePlay)).Post("/tournaments", createTournamentHandler)
+	r.Route("/tournaments/{id}", func(r chi.Router) {
+		r.Get("/", getTournamentHandler)
+		r.With(requireLogin(ScopePlay)).Post("/players", registerPlayerHandler)
+		r.With(requireLogin(ScopePlay)).Post("/bracket", bracketHandler)
+		r.With(requireLogin(ScopePlay)).Post("/results", matchResultHandler)
+		r.Get("/standings", standingsHandler)
+	})
+	r.Post("/auth/register", registerHandler)
+	r.Post("/auth/login", loginHandler)
+	r.Get("/auth/me", meHandler)
+	r.Get("/auth/providers", providersHandler)
+	r.Get("/auth/{provider}/login", oauthLoginHandler)
+	r.Get("/auth/{provider}/callback", oauthCallbackHandler)
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Use(requireLogin(ScopePlay))
+		r.Post("/", createWebhookHandler)
+		r.Get("/", webhooksHandler)
+		r.Delete("/{id}", deleteWebhookHandler)
+		r.Get("/{id}/deliveries", deliveriesHandler)
+	})
+	r.With(requireAdmin).Get("/admin/audit", auditHandler)
+	r.With(requireAdmin).Get("/admin/deliveries/failed", deadLettersHandler)
+	r.With(requireAdmin).Post("/admin/deliveries/{id}/redeliver", redeliverHandler)
+	r.Route("/admin/keys", func(r chi.Router) {
+		r.Use(requireAdmin)
+		r.Post("/", createAPIKeyHandler)
+		r.Get("/", apiKeysHandler)
+		r.Delete("/{key}", revokeAPIKeyHandler)
+	})
 }
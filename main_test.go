@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// newTestAPI opens every store in a fresh database and returns the
+// versioned API behind the middleware the server runs it with
+func newTestAPI(t *testing.T) http.Handler {
+	t.Helper()
+
+	var err error
+	if scores, err = openLeaderboard(filepath.Join(t.TempDir(), "snake.db")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { scores.db.Close() })
+	if tournaments, err = openTournaments(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	if ratings, err = openRatings(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	if history, err = openHistory(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	if playerAchievements, err = openAchievements(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	if err = openDailyScores(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	if err = openRanks(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	if accounts, err = openAccounts(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	if err = openIdentities(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	if apiKeys, err = openAPIKeys(scores.db, ""); err != nil {
+		t.Fatal(err)
+	}
+	if webhooks, err = openWebhooks(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	if audits, err = openAudits(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	jwtSecret = []byte("test secret")
+	games = newMemoryStore()
+	loop = newGameLoop(time.Hour)
+	matchmaking = newMatchmaker()
+
+	r := chi.NewRouter()
+	r.Use(requireContentType)
+	r.Use(negotiate)
+	r.Use(authenticateRequest)
+	r.Use(identifyClient)
+	r.Route("/v1", v1Routes)
+	return r
+}
+
+// do sends a request with body encoded as JSON, if any, and the token as
+// bearer, if any
+func do(t *testing.T, h http.Handler, method, path, token string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	return send(h, newRequest(t, method, path, token, body))
+}
+
+// newRequest returns the request do sends, for tests to add headers to
+func newRequest(t *testing.T, method, path, token string, body any) *http.Request {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return req
+}
+
+// send serves a request and returns the recorded response
+func send(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+// decode reads the JSON body of a response into v
+func decode(t *testing.T, rec *httptest.ResponseRecorder, v any) {
+	t.Helper()
+	if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+		t.Fatalf("decoding %q: %v", rec.Body.String(), err)
+	}
+}
+
+// register creates an account and returns its token
+func register(t *testing.T, h http.Handler, username string) string {
+	t.Helper()
+	rec := do(t, h, http.MethodPost, "/v1/auth/register", "",
+		map[string]string{"username": username, "password": "password1"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("registering %s: %d %s", username, rec.Code, rec.Body)
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	decode(t, rec, &resp)
+	return resp.Token
+}
+
+// newGame creates a game with the given config and returns its state
+func newGame(t *testing.T, h http.Handler, token string, config GameConfig) GameState {
+	t.Helper()
+	rec := do(t, h, http.MethodPost, "/v1/games", token, config)
+	if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+		t.Fatalf("creating game: %d %s", rec.Code, rec.Body)
+	}
+	var state GameState
+	decode(t, rec, &state)
+	return state
+}
+
+// board returns a game of the given size with only the snake on it, and
+// the fruits if any
+func board(width, height int, snake Snake, fruits ...Fruit) GameState {
+	if snake.Body == nil {
+		snake.Body = []Position{}
+	}
+	return GameState{Width: width, Height: height, Snake: snake, Fruits: fruits, Seed: 1, Status: GameActive}
+}
+
+// at returns a snake with its head at x, y heading velX, velY, followed by
+// the body segments
+func at(x, y, velX, velY int, body ...Position) Snake {
+	return Snake{Position: Position{X: x, Y: y}, VelX: velX, VelY: velY, Body: body}
+}
+
+func TestStepGame(t *testing.T) {
+	right, up := Tick{VelX: 1}, Tick{VelY: -1}
+	withObstacle := board(5, 5, at(0, 0, 1, 0))
+	withObstacle.Obstacles = []Position{{X: 1, Y: 0}}
+	wrapped := board(5, 5, at(4, 0, 1, 0))
+	wrapped.Mode = ModeWrap
+	limited := board(5, 5, at(0, 0, 1, 0))
+	limited.TickLimit = 1
+	over := board(5, 5, at(0, 0, 1, 0))
+	over.GameOverReason = ReasonWall
+	fast := board(5, 5, at(0, 0, 1, 0))
+	fast.Effects = []Effect{{Type: PowerUpSpeed, TicksLeft: 5}}
+
+	tests := []struct {
+		name   string
+		state  GameState
+		tick   Tick
+		status int
+		reason GameOverReason
+		head   Position
+		score  int
+		length int
+	}{
+		{name: "moves", state: board(5, 5, at(0, 0, 1, 0)), tick: right,
+			status: http.StatusOK, head: Position{X: 1}},
+		{name: "turns", state: board(5, 5, at(1, 1, 1, 0)), tick: up,
+			status: http.StatusOK, head: Position{X: 1}},
+		{name: "eats and grows", state: board(5, 5, at(0, 0, 1, 0), Fruit{Position{X: 1}, FruitNormal}), tick: right,
+			status: http.StatusOK, head: Position{X: 1}, score: 1, length: 1},
+		{name: "golden fruit", state: board(5, 5, at(0, 0, 1, 0), Fruit{Position{X: 1}, FruitGolden}), tick: right,
+			status: http.StatusOK, head: Position{X: 1}, score: goldenFruitPoints, length: 1},
+		{name: "body follows", state: board(5, 5, at(2, 0, 1, 0, Position{X: 1}, Position{X: 0})), tick: right,
+			status: http.StatusOK, head: Position{X: 3}, length: 2},
+		{name: "hits wall", state: board(5, 5, at(4, 0, 1, 0)), tick: right,
+			status: http.StatusTeapot, reason: ReasonWall, head: Position{X: 5}},
+		{name: "hits itself",
+			state: board(5, 5, at(2, 2, 0, -1,
+				Position{X: 2, Y: 3}, Position{X: 3, Y: 3}, Position{X: 3, Y: 2}, Position{X: 3, Y: 1}, Position{X: 2, Y: 1})),
+			tick: right, status: http.StatusTeapot, reason: ReasonSelf, head: Position{X: 3, Y: 2}, length: 5},
+		{name: "hits obstacle", state: withObstacle, tick: right,
+			status: http.StatusTeapot, reason: ReasonObstacle, head: Position{X: 1}},
+		{name: "eats poison", state: board(5, 5, at(0, 0, 1, 0), Fruit{Position{X: 1}, FruitPoison}), tick: right,
+			status: http.StatusTeapot, reason: ReasonPoison, head: Position{X: 1}},
+		{name: "wraps around", state: wrapped, tick: right, status: http.StatusOK, head: Position{X: 0}},
+		{name: "times out", state: limited, tick: right,
+			status: http.StatusTeapot, reason: ReasonTimeout, head: Position{X: 1}},
+		{name: "speed moves two cells", state: fast, tick: right, status: http.StatusOK, head: Position{X: 2}},
+		{name: "reversal", state: board(5, 5, at(1, 0, 1, 0)), tick: Tick{VelX: -1},
+			status: http.StatusBadRequest, head: Position{X: 1}},
+		{name: "diagonal", state: board(5, 5, at(0, 0, 1, 0)), tick: Tick{VelX: 1, VelY: 1},
+			status: http.StatusBadRequest},
+		{name: "too fast", state: board(5, 5, at(0, 0, 1, 0)), tick: Tick{VelX: 2},
+			status: http.StatusBadRequest},
+		{name: "already over", state: over, tick: right,
+			status: http.StatusTeapot, reason: ReasonWall},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, status := stepGame(tt.state, tt.tick)
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if state.GameOverReason != tt.reason {
+				t.Errorf("reason = %q, want %q", state.GameOverReason, tt.reason)
+			}
+			if state.Snake.Position != tt.head {
+				t.Errorf("head = %+v, want %+v", state.Snake.Position, tt.head)
+			}
+			if state.Score != tt.score {
+				t.Errorf("score = %d, want %d", state.Score, tt.score)
+			}
+			if len(state.Snake.Body) != tt.length {
+				t.Errorf("body = %v, want %d segments", state.Snake.Body, tt.length)
+			}
+		})
+	}
+}
+
+func TestStepGameRespawnsEatenFruit(t *testing.T) {
+	state, _ := stepGame(board(5, 5, at(0, 0, 1, 0), Fruit{Position{X: 1}, FruitNormal}), Tick{VelX: 1})
+	if len(state.Fruits) != 1 || state.Fruits[0].Position == (Position{X: 1}) {
+		t.Errorf("fruits = %+v, want one fruit away from the eaten one", state.Fruits)
+	}
+	if state.FruitsEaten != 1 || state.FruitSpawns != 1 {
+		t.Errorf("fruitsEaten = %d, fruitSpawns = %d, want 1 and 1", state.FruitsEaten, state.FruitSpawns)
+	}
+}
+
+func TestIsValidMove(t *testing.T) {
+	tests := []struct {
+		name          string
+		current, next Snake
+		want          bool
+	}{
+		{name: "straight on", current: at(0, 0, 1, 0), next: at(0, 0, 1, 0), want: true},
+		{name: "turn", current: at(0, 0, 1, 0), next: at(0, 0, 0, 1), want: true},
+		{name: "reverse horizontally", current: at(0, 0, 1, 0), next: at(0, 0, -1, 0), want: false},
+		{name: "reverse vertically", current: at(0, 0, 0, -1), next: at(0, 0, 0, 1), want: false},
+		{name: "standing still", current: at(0, 0, 0, 0), next: at(0, 0, -1, 0), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := isValidMove(GameState{Snake: tt.current}, GameState{Snake: tt.next})
+			if got != tt.want {
+				t.Errorf("isValidMove = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSimulateTicks(t *testing.T) {
+	right, down := Tick{VelX: 1}, Tick{VelY: 1}
+	tests := []struct {
+		name         string
+		ticks        []Tick
+		status       int
+		ticksApplied int
+		reason       GameOverReason
+		head         Position
+	}{
+		{name: "all applied", ticks: []Tick{right, down, down}, status: http.StatusOK, ticksApplied: 3,
+			head: Position{X: 1, Y: 2}},
+		{name: "stops at the fatal tick", ticks: []Tick{right, right, right, right, right, right},
+			status: http.StatusTeapot, ticksApplied: 5, reason: ReasonWall, head: Position{X: 5}},
+		{name: "rejects at the invalid tick", ticks: []Tick{right, down, {VelY: -1}},
+			status: http.StatusBadRequest, ticksApplied: 2, head: Position{X: 1, Y: 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := board(5, 5, at(0, 0, 1, 0))
+			state.Ticks = tt.ticks
+			state, ticksApplied, status := simulateTicks(state)
+			if status != tt.status || ticksApplied != tt.ticksApplied {
+				t.Errorf("status = %d with %d ticks applied, want %d with %d",
+					status, ticksApplied, tt.status, tt.ticksApplied)
+			}
+			if state.GameOverReason != tt.reason || state.Snake.Position != tt.head {
+				t.Errorf("final state: reason %q, head %+v; want %q, %+v",
+					state.GameOverReason, state.Snake.Position, tt.reason, tt.head)
+			}
+		})
+	}
+}
+
+func TestGameConfigError(t *testing.T) {
+	tests := []struct {
+		name   string
+		config GameConfig
+		code   ErrorCode
+	}{
+		{name: "valid", config: GameConfig{Width: 10, Height: 10}},
+		{name: "no board", config: GameConfig{}, code: CodeInvalidBoard},
+		{name: "negative height", config: GameConfig{Width: 10, Height: -1}, code: CodeInvalidBoard},
+		{name: "too wide", config: GameConfig{Width: maxBoardSize + 1, Height: 1}, code: CodeBoardTooLarge},
+		{name: "unknown spawn", config: GameConfig{Width: 10, Height: 10, FruitSpawn: "corner"}, code: CodeInvalidRequest},
+		{name: "unknown mode", config: GameConfig{Width: 10, Height: 10, Mode: "maze"}, code: CodeInvalidRequest},
+		{name: "tiny versus board", config: GameConfig{Width: 1, Height: 5, Versus: true}, code: CodeInvalidBoard},
+		{name: "too many obstacles", config: GameConfig{Width: 4, Height: 4, Obstacles: 9}, code: CodeInvalidBoard},
+		{name: "too many fruits", config: GameConfig{Width: 10, Height: 10, FruitCount: maxFruits + 1},
+			code: CodeInvalidRequest},
+		{name: "crowded board", config: GameConfig{Width: 4, Height: 4, FruitCount: 5, Obstacles: 4},
+			code: CodeInvalidBoard},
+		{name: "fruit chances over 100", config: GameConfig{Width: 10, Height: 10,
+			FruitChances: FruitChances{Golden: 60, Poison: 50}}, code: CodeInvalidRequest},
+		{name: "power-ups in versus", config: GameConfig{Width: 10, Height: 10, Versus: true, PowerUpChance: 10},
+			code: CodeInvalidRequest},
+		{name: "negative tick limit", config: GameConfig{Width: 10, Height: 10, TickLimit: -1},
+			code: CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if code, message := gameConfigError(tt.config); code != tt.code {
+				t.Errorf("code = %q (%s), want %q", code, message, tt.code)
+			}
+		})
+	}
+}
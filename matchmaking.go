@@ -0,0 +1,425 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultRating is the rating of players who have not finished a rated
+	// game yet
+	defaultRating = 1500
+	// defaultMatchBoardSize is the side of the board of players queueing
+	// without a board preference
+	defaultMatchBoardSize = 20
+
+	// matchRatingWindow is the largest rating difference of two players
+	// paired as soon as they queue; it grows by matchWindowGrowth every
+	// second a player waits, up to maxMatchRatingWindow
+	matchRatingWindow    = 100
+	matchWindowGrowth    = 10
+	maxMatchRatingWindow = 1000
+
+	// matchInterval is how often waiting players are paired again with
+	// their wider windows
+	matchInterval = time.Second
+	// maxQueueWait is how long a player waits before the ticket expires,
+	// and how long matched and expired tickets are kept for clients to see
+	maxQueueWait = 10 * time.Minute
+)
+
+var errTicketNotFound = errors.New("ticket not found")
+
+// TicketStatus tells whether a matchmaking ticket is still waiting
+type TicketStatus string
+
+const (
+	TicketWaiting   TicketStatus = "waiting"
+	TicketMatched   TicketStatus = "matched"
+	TicketCancelled TicketStatus = "cancelled"
+	TicketExpired   TicketStatus = "expired"
+)
+
+// Event names sent on the matchmaking stream
+const (
+	eventWaiting = "waiting"
+	eventMatched = "matched"
+)
+
+type (
+	// QueueRequest puts the caller's account in the matchmaking queue.
+	// Players are only paired with players who want the same board,
+	// starting with those closest to their rating.
+	QueueRequest struct {
+		Width  int      `json:"width,omitempty"`
+		Height int      `json:"height,omitempty"`
+		Mode   GameMode `json:"mode,omitempty"`
+	}
+
+	// MatchTicket is a player's place in the matchmaking queue. Once
+	// matched, GameID is the shared versus game, in which Player is the
+	// snake if PlayerNumber is 1 and the opponent if it is 2.
+	MatchTicket struct {
+		ID           string       `json:"id"`
+		Player       string       `json:"player"`
+		Rating       int          `json:"rating"`
+		Width        int          `json:"width"`
+		Height       int          `json:"height"`
+		Mode         GameMode     `json:"mode,omitempty"`
+		Status       TicketStatus `json:"status"`
+		GameID       string       `json:"gameId,omitempty"`
+		PlayerNumber int          `json:"playerNumber,omitempty"`
+		Opponent     string       `json:"opponent,omitempty"`
+		QueuedAt     time.Time    `json:"queuedAt"`
+	}
+)
+
+// queuedTicket is a ticket with the channel closed once it stops waiting
+type queuedTicket struct {
+	MatchTicket
+	done chan struct{}
+}
+
+// matchmaker pairs the players waiting in the queue into versus games
+type matchmaker struct {
+	mu      sync.Mutex
+	tickets map[string]*queuedTicket
+	// waiting holds the waiting tickets, longest waiting first
+	waiting []*queuedTicket
+}
+
+// matchmaking is the server-wide matchmaking queue
+var matchmaking = newMatchmaker()
+
+func newMatchmaker() *matchmaker {
+	return &matchmaker{tickets: make(map[string]*queuedTicket)}
+}
+
+// run pairs waiting players and expires old tickets every interval, forever
+func (m *matchmaker) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		m.expire(time.Now())
+		m.match(context.Background(), time.Now())
+	}
+}
+
+// enqueue adds a ticket to the queue and pairs it right away if it can be
+func (m *matchmaker) enqueue(ctx context.Context, ticket MatchTicket) MatchTicket {
+	queued := &queuedTicket{MatchTicket: ticket, done: make(chan struct{})}
+	m.mu.Lock()
+	m.tickets[ticket.ID] = queued
+	m.waiting = append(m.waiting, queued)
+	m.mu.Unlock()
+
+	m.match(ctx, time.Now())
+	ticket, _ = m.get(ticket.ID, ticket.Player)
+	return ticket
+}
+
+// get returns the current state of a ticket of player, and a channel closed
+// once it stops waiting. Tickets of other players are not found.
+func (m *matchmaker) get(id, player string) (MatchTicket, <-chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued, ok := m.tickets[id]
+	if !ok || queued.Player != player {
+		return MatchTicket{}, nil
+	}
+	return queued.MatchTicket, queued.done
+}
+
+// cancel takes a waiting ticket of player out of the queue. Matched tickets
+// are returned unchanged, tickets of other players are not found.
+func (m *matchmaker) cancel(id, player string) (MatchTicket, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	queued, ok := m.tickets[id]
+	if !ok || queued.Player != player {
+		return MatchTicket{}, errTicketNotFound
+	}
+	if queued.Status == TicketWaiting {
+		m.stopWaiting(queued, TicketCancelled)
+	}
+	return queued.MatchTicket, nil
+}
+
+// stopWaiting removes a ticket from the waiting list with the given status.
+// The caller holds m.mu.
+func (m *matchmaker) stopWaiting(queued *queuedTicket, status TicketStatus) {
+	for i, waiting := range m.waiting {
+		if waiting == queued {
+			m.waiting = append(m.waiting[:i], m.waiting[i+1:]...)
+			break
+		}
+	}
+	queued.Status = status
+	close(queued.done)
+}
+
+// expire ends the tickets that waited for longer than maxQueueWait, and
+// forgets the others once they were queued twice as long ago
+func (m *matchmaker) expire(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for id, queued := range m.tickets {
+		if now.Sub(queued.QueuedAt) < maxQueueWait {
+			continue
+		}
+		if queued.Status == TicketWaiting {
+			m.stopWaiting(queued, TicketExpired)
+			continue
+		}
+		if now.Sub(queued.QueuedAt) >= 2*maxQueueWait {
+			delete(m.tickets, id)
+		}
+	}
+}
+
+// ratingWindow returns the largest rating difference a ticket accepts after
+// waiting until now
+func ratingWindow(ticket MatchTicket, now time.Time) int {
+	waited := int(now.Sub(ticket.QueuedAt) / time.Second)
+	return min(maxMatchRatingWindow, matchRatingWindow+matchWindowGrowth*waited)
+}
+
+// compatible returns true if two tickets of different players want the same
+// board and their ratings are within the window of the one waiting longest
+func compatible(a, b MatchTicket, now time.Time) bool {
+	if a.Player == b.Player || a.Width != b.Width || a.Height != b.Height || a.Mode != b.Mode {
+		return false
+	}
+	return abs(a.Rating-b.Rating) <= max(ratingWindow(a, now), ratingWindow(b, now))
+}
+
+// match pairs waiting tickets, longest waiting first, each with the
+// compatible ticket closest in rating, and creates a game for every pair.
+// The games are created without holding m.mu; pairs whose game cannot be
+// created keep waiting for the next attempt.
+func (m *matchmaker) match(ctx context.Context, now time.Time) {
+	for _, pair := range m.pair(now) {
+		first, second := pair[0], pair[1]
+		state, err := startMatch(ctx, first.MatchTicket, second.MatchTicket)
+
+		m.mu.Lock()
+		switch {
+		case err != nil:
+			log.Printf("creating matchmaking game: %v", err)
+			m.requeue(first, second)
+		case first.Status != TicketWaiting || second.Status != TicketWaiting:
+			// A player left the queue while the game was created
+			if err := games.Delete(ctx, state.GameID); err != nil {
+				log.Printf("deleting matchmaking game %s: %v", state.GameID, err)
+			}
+			m.requeue(first, second)
+		default:
+			first.GameID, first.PlayerNumber, first.Opponent = state.GameID, 1, second.Player
+			second.GameID, second.PlayerNumber, second.Opponent = state.GameID, 2, first.Player
+			m.stopWaiting(second, TicketMatched)
+			m.stopWaiting(first, TicketMatched)
+		}
+		m.mu.Unlock()
+	}
+}
+
+// pair takes the pairs match creates games for out of the waiting list.
+// Their tickets keep waiting until the game is created.
+func (m *matchmaker) pair(now time.Time) [][2]*queuedTicket {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var pairs [][2]*queuedTicket
+	for i := 0; i < len(m.waiting); i++ {
+		first := m.waiting[i]
+		best := -1
+		for j := i + 1; j < len(m.waiting); j++ {
+			if !compatible(first.MatchTicket, m.waiting[j].MatchTicket, now) {
+				continue
+			}
+			if best < 0 || abs(m.waiting[j].Rating-first.Rating) < abs(m.waiting[best].Rating-first.Rating) {
+				best = j
+			}
+		}
+		if best < 0 {
+			continue
+		}
+
+		pairs = append(pairs, [2]*queuedTicket{first, m.waiting[best]})
+		m.waiting = append(m.waiting[:best], m.waiting[best+1:]...)
+		m.waiting = append(m.waiting[:i], m.waiting[i+1:]...)
+		i--
+	}
+	return pairs
+}
+
+// requeue puts the tickets of a pair that are still waiting back in the
+// waiting list, in the order they were queued. The caller holds m.mu.
+func (m *matchmaker) requeue(tickets ...*queuedTicket) {
+	for _, queued := range tickets {
+		if queued.Status != TicketWaiting {
+			continue
+		}
+		i := len(m.waiting)
+		for i > 0 && m.waiting[i-1].QueuedAt.After(queued.QueuedAt) {
+			i--
+		}
+		m.waiting = slices.Insert(m.waiting, i, queued)
+	}
+}
+
+// startMatch creates the versus game of a pair on the board they asked for,
+// with the opponent already playing. The game is owned by the snake's
+// player, as if they had created it and the opponent had joined.
+func startMatch(ctx context.Context, ticket, opponent MatchTicket) (GameState, error) {
+	state, err := createGame(ctx, GameConfig{
+		Width:  ticket.Width,
+		Height: ticket.Height,
+		Mode:   ticket.Mode,
+		Versus: true,
+	})
+	if err != nil {
+		return state, err
+	}
+	_, err = games.Update(ctx, state.GameID, func(state GameState) GameState {
+		state = addOpponent(state)
+		state.Players = []string{ticket.Player, opponent.Player}
+		state.Owner = ticket.Player
+		return state
+	})
+	if err != nil {
+		return state, err
+	}
+	return loop.start(ctx, state.GameID)
+}
+
+// queueHandler puts the caller in the matchmaking queue. It answers with 201
+// and the matched ticket if an opponent was waiting, and with 202 and the
+// waiting ticket otherwise; its events stream tells when it is matched.
+func queueHandler(w http.ResponseWriter, r *http.Request) {
+	var req QueueRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	player := accountFrom(r.Context())
+	if req.Width == 0 && req.Height == 0 {
+		req.Width, req.Height = defaultMatchBoardSize, defaultMatchBoardSize
+	}
+	if code, message := gameConfigError(GameConfig{Width: req.Width, Height: req.Height, Mode: req.Mode, Versus: true}); code != "" {
+		jsonError(w, http.StatusBadRequest, code, message)
+		return
+	}
+
+	rating, err := ratings.get(player)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load rating")
+		return
+	}
+
+	ticket := matchmaking.enqueue(r.Context(), MatchTicket{
+		ID:       uuid.NewString(),
+		Player:   player,
+		Rating:   rating.Rating,
+		Width:    req.Width,
+		Height:   req.Height,
+		Mode:     req.Mode,
+		Status:   TicketWaiting,
+		QueuedAt: time.Now().UTC(),
+	})
+	if ticket.Status == TicketMatched {
+		jsonResponseWithStatus(w, ticket, http.StatusCreated)
+		return
+	}
+	jsonResponseWithStatus(w, ticket, http.StatusAccepted)
+}
+
+// ticketHandler returns the current state of a matchmaking ticket
+func ticketHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "ticket")
+	ticket, done := matchmaking.get(id, accountFrom(r.Context()))
+	if done == nil {
+		ticketError(w, id)
+		return
+	}
+	jsonResponse(w, ticket)
+}
+
+// cancelTicketHandler leaves the matchmaking queue
+func cancelTicketHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "ticket")
+	ticket, err := matchmaking.cancel(id, accountFrom(r.Context()))
+	if err != nil {
+		ticketError(w, id)
+		return
+	}
+	jsonResponse(w, ticket)
+}
+
+// ticketEventsHandler streams a matchmaking ticket as Server-Sent Events: a
+// waiting event on connect, then a single matched event, or the ticket's
+// final status, once it stops waiting
+func ticketEventsHandler(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Streaming unsupported")
+		return
+	}
+
+	id := chi.URLParam(r, "ticket")
+	ticket, done := matchmaking.get(id, accountFrom(r.Context()))
+	if done == nil {
+		ticketError(w, id)
+		return
+	}
+
+	clearDeadlines(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	if ticket.Status == TicketWaiting {
+		writeEvent(w, eventWaiting, ticket)
+		flusher.Flush()
+	}
+	for ticket.Status == TicketWaiting {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			flusher.Flush()
+		case <-done:
+			ticket, _ = matchmaking.get(id, accountFrom(r.Context()))
+		}
+	}
+	if ticket.Status == TicketMatched {
+		writeEvent(w, eventMatched, ticket)
+	} else {
+		writeEvent(w, string(ticket.Status), ticket)
+	}
+	flusher.Flush()
+}
+
+// ticketError writes the 404 for an unknown matchmaking ticket
+func ticketError(w http.ResponseWriter, id string) {
+	jsonError(w, http.StatusNotFound, CodeTicketNotFound, fmt.Sprintf("Ticket not found: %s", id))
+}
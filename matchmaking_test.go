@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestTicketsBelongToTheirPlayer(t *testing.T) {
+	h := newTestAPI(t)
+	alice := register(t, h, "alice")
+	bob := register(t, h, "bob")
+
+	rec := do(t, h, http.MethodPost, "/v1/matchmaking/queue", alice, QueueRequest{Width: 10, Height: 10})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("queueing: %d %s", rec.Code, rec.Body)
+	}
+	var ticket MatchTicket
+	decode(t, rec, &ticket)
+	path := "/v1/matchmaking/queue/" + ticket.ID
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+	}{
+		{name: "anonymous", method: http.MethodGet, path: path, status: http.StatusUnauthorized},
+		{name: "another player reads", method: http.MethodGet, path: path, token: bob, status: http.StatusNotFound},
+		{name: "another player cancels", method: http.MethodDelete, path: path, token: bob, status: http.StatusNotFound},
+		{name: "another player watches", method: http.MethodGet, path: path + "/events", token: bob,
+			status: http.StatusNotFound},
+		{name: "player reads", method: http.MethodGet, path: path, token: alice, status: http.StatusOK},
+		{name: "player cancels", method: http.MethodDelete, path: path, token: alice, status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, h, tt.method, tt.path, tt.token, nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			var got MatchTicket
+			if tt.status == http.StatusOK {
+				decode(t, rec, &got)
+				if tt.method == http.MethodGet && got.Status != TicketWaiting {
+					t.Errorf("ticket status = %s, want %s", got.Status, TicketWaiting)
+				}
+			}
+		})
+	}
+	if ticket, _ := matchmaking.get(ticket.ID, "alice"); ticket.Status != TicketCancelled {
+		t.Errorf("ticket status = %s, want %s", ticket.Status, TicketCancelled)
+	}
+}
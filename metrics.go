@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	gamesCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "snake_games_created_total",
+		Help: "Games created through the API.",
+	})
+	gamesAbandoned = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "snake_games_abandoned_total",
+		Help: "Games deleted by their clients before they were over.",
+	})
+	validations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "snake_validations_total",
+		Help: "Tick validations by HTTP status code and result.",
+	}, []string{"code", "result"})
+	validateTicksApplied = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "snake_validate_ticks",
+		Help:    "Ticks applied per validate request.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 8),
+	})
+	requestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "snake_http_request_duration_seconds",
+		Help:    "HTTP request latency by route, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+	eventsPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "snake_events_published_total",
+		Help: "Game events handed to the event bus by type and result.",
+	}, []string{"type", "result"})
+)
+
+// observeValidation records the outcome of a validate request
+func observeValidation(statusCode int, result string, ticksApplied int) {
+	validations.WithLabelValues(strconv.Itoa(statusCode), result).Inc()
+	validateTicksApplied.Observe(float64(ticksApplied))
+}
+
+// instrument records the latency of every request under its chi route
+// pattern, so requests to different games share one series
+func instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		start := time.Now()
+		next.ServeHTTP(ww, r)
+
+		route := chi.RouteContext(r.Context()).RoutePattern()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		requestDuration.WithLabelValues(route, r.Method, strconv.Itoa(status)).
+			Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/time/rate"
+)
+
+// acceptedMediaTypes are the request body types the API knows how to decode
+var acceptedMediaTypes = []string{"application/json", msgpackMediaType, "application/x-msgpack", protobufMediaType}
+
+// requireContentType rejects requests carrying a body whose Content-Type is
+// not one of the accepted media types with 415. Requests without a body, such
+// as joining a game, need no Content-Type.
+func requireContentType(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost && r.Method != http.MethodPut &&
+			r.Method != http.MethodPatch || r.ContentLength == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if err == nil {
+			for _, accepted := range acceptedMediaTypes {
+				if mediaType == accepted {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+		}
+
+		jsonResponseWithStatus(w, ErrorResponse{
+			Code:      CodeUnsupportedType,
+			Message:   "Unsupported Content-Type: " + r.Header.Get("Content-Type"),
+			Supported: acceptedMediaTypes,
+		}, http.StatusUnsupportedMediaType)
+	})
+}
+
+// deprecated marks responses of legacy routes as deprecated and points
+// clients at the successor API version
+func deprecated(successor string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// unlimitedPaths are exempt from rate limiting, so probes and metrics
+// scrapes keep working while clients are being throttled
+var unlimitedPaths = map[string]bool{
+	"/healthz": true,
+	"/readyz":  true,
+	"/metrics": true,
+}
+
+// rateLimit answers with 429 once requests across all clients exceed limit
+// per second, allowing bursts of up to burst requests
+func rateLimit(limit rate.Limit, burst int) func(http.Handler) http.Handler {
+	limiter := rate.NewLimiter(limit, burst)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if unlimitedPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reservation := limiter.Reserve()
+			if delay := reservation.Delay(); delay > 0 {
+				reservation.Cancel()
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+				jsonError(w, http.StatusTooManyRequests, CodeRateLimited,
+					fmt.Sprintf("Rate limit exceeded; retry in %s", delay.Round(time.Millisecond)))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requireGameID rejects requests whose {id} route parameter is not a well
+// formed game ID before any store lookup
+func requireGameID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := chi.URLParam(r, "id"); !validGameID(id) {
+			gameIDError(w, id)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
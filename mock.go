@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// mockFailureHeader lets clients in mock mode ask for a canned failure
+const mockFailureHeader = "X-Mock-Status"
+
+// mockFailures maps the statuses that can be injected in mock mode to the
+// error body returned for them
+var mockFailures = map[int]ErrorResponse{
+	http.StatusConflict: {
+		Code:    CodeVersionConflict,
+		Message: "Mock: game state was modified concurrently",
+	},
+	http.StatusTooManyRequests: {
+		Code:    CodeRateLimited,
+		Message: "Mock: rate limit exceeded",
+	},
+	http.StatusServiceUnavailable: {
+		Code:    CodeUnavailable,
+		Message: "Mock: service unavailable",
+	},
+}
+
+var mockGameCounter atomic.Int64
+
+// mockEpoch is the fixed time of every timestamp in mock mode
+var mockEpoch = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// enableMockMode makes game IDs and seeds deterministic, so every run of
+// the server produces the same sequence of responses
+func enableMockMode() {
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(1))
+	newSeed = func() int64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return rng.Int63()
+	}
+	generateGameID = func() string {
+		return fmt.Sprintf("mock-%d", mockGameCounter.Add(1))
+	}
+	gameIDFormat = regexp.MustCompile(`^mock-[0-9]+$`)
+	clock = func() time.Time { return mockEpoch }
+}
+
+// mockFailureInjector answers with a canned error when the request carries
+// a supported X-Mock-Status header, and passes it through otherwise
+func mockFailureInjector(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get(mockFailureHeader)
+		if value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		status, err := strconv.Atoi(value)
+		failure, ok := mockFailures[status]
+		if err != nil || !ok {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("Unsupported %s: %s", mockFailureHeader, value))
+			return
+		}
+
+		jsonResponseWithStatus(w, failure, status)
+	})
+}
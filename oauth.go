@@ -0,0 +1,399 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/go-chi/chi/v5"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+const (
+	// oidcClientSecretEnv and githubClientSecretEnv name the environment
+	// variables holding the client secrets; like signingKeyEnv they are
+	// kept out of the flags
+	oidcClientSecretEnv   = "OIDC_CLIENT_SECRET"
+	githubClientSecretEnv = "GITHUB_CLIENT_SECRET"
+
+	// oauthCookie carries the state and PKCE verifier of a login from its
+	// redirect to the provider until the provider redirects back
+	oauthCookie = "snake_oauth"
+
+	// oauthLoginTimeout is how long a login at the provider may take
+	oauthLoginTimeout = 10 * time.Minute
+
+	// githubUserURL returns the GitHub user a token was issued to
+	githubUserURL = "https://api.github.com/user"
+)
+
+// unsafeNameChars are the characters dropped from names suggested by a
+// provider to make them usernames
+var unsafeNameChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+var errUnknownProvider = errors.New("unknown identity provider")
+
+// OAuthConfig configures the external identity providers. A provider is
+// enabled by setting its client ID; RedirectURL is the public base URL of
+// the API that providers send players back to.
+type OAuthConfig struct {
+	RedirectURL string
+
+	// OIDC is any OpenID Connect issuer, such as Google, listed as
+	// OIDCProvider in login URLs
+	OIDCProvider     string
+	OIDCIssuer       string
+	OIDCClientID     string
+	OIDCClientSecret string
+
+	GitHubClientID     string
+	GitHubClientSecret string
+}
+
+// externalIdentity is a player as an identity provider knows them
+type externalIdentity struct {
+	// Subject identifies the player at the provider and never changes
+	Subject string
+	// Name is the name the player goes by, suggested as their username
+	Name string
+}
+
+// identityProvider logs players in with OAuth2
+type identityProvider interface {
+	oauth() *oauth2.Config
+	// identify returns the player a token was issued to
+	identify(ctx context.Context, token *oauth2.Token) (externalIdentity, error)
+}
+
+// oidcProvider identifies players by the ID token of an OpenID Connect
+// issuer
+type oidcProvider struct {
+	config   oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+func (p *oidcProvider) oauth() *oauth2.Config {
+	return &p.config
+}
+
+func (p *oidcProvider) identify(ctx context.Context, token *oauth2.Token) (externalIdentity, error) {
+	raw, ok := token.Extra("id_token").(string)
+	if !ok {
+		return externalIdentity{}, errors.New("token response has no id_token")
+	}
+	idToken, err := p.verifier.Verify(ctx, raw)
+	if err != nil {
+		return externalIdentity{}, err
+	}
+	var claims struct {
+		PreferredUsername string `json:"preferred_username"`
+		Nickname          string `json:"nickname"`
+		Email             string `json:"email"`
+		Name              string `json:"name"`
+	}
+	if err := idToken.Claims(&claims); err != nil {
+		return externalIdentity{}, err
+	}
+
+	name := claims.PreferredUsername
+	if name == "" {
+		name = claims.Nickname
+	}
+	if name == "" {
+		name, _, _ = strings.Cut(claims.Email, "@")
+	}
+	if name == "" {
+		name = claims.Name
+	}
+	return externalIdentity{Subject: idToken.Subject, Name: name}, nil
+}
+
+// githubProvider identifies players by their GitHub user, as GitHub does
+// not speak OpenID Connect
+type githubProvider struct {
+	config oauth2.Config
+}
+
+func (p *githubProvider) oauth() *oauth2.Config {
+	return &p.config
+}
+
+func (p *githubProvider) identify(ctx context.Context, token *oauth2.Token) (externalIdentity, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, githubUserURL, nil)
+	if err != nil {
+		return externalIdentity{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := p.config.Client(ctx, token).Do(req)
+	if err != nil {
+		return externalIdentity{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return externalIdentity{}, fmt.Errorf("GitHub user: %s", resp.Status)
+	}
+
+	var user struct {
+		ID    int64  `json:"id"`
+		Login string `json:"login"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return externalIdentity{}, err
+	}
+	return externalIdentity{Subject: strconv.FormatInt(user.ID, 10), Name: user.Login}, nil
+}
+
+// identityProviders are the enabled providers by name, set up in main
+var identityProviders = map[string]identityProvider{}
+
+// ProvidersResponse is the body of GET /v1/auth/providers
+type ProvidersResponse struct {
+	Providers []string `json:"providers"`
+}
+
+// setupIdentityProviders enables the providers with a client ID. OIDC
+// issuers are discovered up front, so a wrong issuer fails at startup.
+func setupIdentityProviders(ctx context.Context, config OAuthConfig) error {
+	if config.OIDCClientID == "" && config.GitHubClientID == "" {
+		return nil
+	}
+	if config.RedirectURL == "" {
+		return errors.New("-oauth-redirect-url is required to log in with an identity provider")
+	}
+	redirect := func(name string) string {
+		return strings.TrimSuffix(config.RedirectURL, "/") + "/v1/auth/" + name + "/callback"
+	}
+
+	if config.OIDCClientID != "" {
+		provider, err := oidc.NewProvider(ctx, config.OIDCIssuer)
+		if err != nil {
+			return fmt.Errorf("discovering OIDC issuer %s: %w", config.OIDCIssuer, err)
+		}
+		identityProviders[config.OIDCProvider] = &oidcProvider{
+			config: oauth2.Config{
+				ClientID:     config.OIDCClientID,
+				ClientSecret: config.OIDCClientSecret,
+				Endpoint:     provider.Endpoint(),
+				RedirectURL:  redirect(config.OIDCProvider),
+				Scopes:       []string{oidc.ScopeOpenID, "profile", "email"},
+			},
+			verifier: provider.Verifier(&oidc.Config{ClientID: config.OIDCClientID}),
+		}
+	}
+	if config.GitHubClientID != "" {
+		identityProviders["github"] = &githubProvider{config: oauth2.Config{
+			ClientID:     config.GitHubClientID,
+			ClientSecret: config.GitHubClientSecret,
+			Endpoint:     github.Endpoint,
+			RedirectURL:  redirect("github"),
+		}}
+	}
+	for name := range identityProviders {
+		log.Printf("accounts: logging in with %s enabled", name)
+	}
+	return nil
+}
+
+// openIdentities creates the table linking external identities to accounts
+func openIdentities(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS identities (
+			provider TEXT NOT NULL,
+			subject  TEXT NOT NULL,
+			username TEXT NOT NULL REFERENCES accounts (username),
+			PRIMARY KEY (provider, subject)
+		);
+	`)
+	return err
+}
+
+// link returns the account of an external identity, creating one the first
+// time the identity logs in. New accounts are named after the identity,
+// with a number appended if the name is taken; they have no password, so
+// only the provider can log them in.
+func (s *accountStore) link(provider string, identity externalIdentity) (string, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var username string
+	err = tx.QueryRow(`SELECT username FROM identities WHERE provider = ? AND subject = ?`,
+		provider, identity.Subject).Scan(&username)
+	if err == nil {
+		return username, nil
+	}
+	if err != sql.ErrNoRows {
+		return "", err
+	}
+
+	base := usernameFor(identity.Name)
+	now := time.Now().UnixMilli()
+	for n := 1; ; n++ {
+		username = base
+		if n > 1 {
+			suffix := "-" + strconv.Itoa(n)
+			username = base[:min(len(base), maxPlayerNameLength-len(suffix))] + suffix
+		}
+		result, err := tx.Exec(`INSERT INTO accounts (username, password_hash, created_at) VALUES (?, ?, ?)
+			ON CONFLICT DO NOTHING`, username, []byte{}, now)
+		if err != nil {
+			return "", err
+		}
+		if created, err := result.RowsAffected(); err != nil {
+			return "", err
+		} else if created == 1 {
+			break
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO identities (provider, subject, username) VALUES (?, ?, ?)`,
+		provider, identity.Subject, username)
+	if err != nil {
+		return "", err
+	}
+	return username, tx.Commit()
+}
+
+// usernameFor turns the name a provider suggests into a valid username
+func usernameFor(name string) string {
+	name = unsafeNameChars.ReplaceAllString(name, "")
+	if len(name) > maxPlayerNameLength {
+		name = name[:maxPlayerNameLength]
+	}
+	if len(name) < 3 {
+		name = "player"
+	}
+	return name
+}
+
+// providerFor returns the provider in the {provider} route parameter
+func providerFor(r *http.Request) (string, identityProvider, error) {
+	name := chi.URLParam(r, "provider")
+	provider, ok := identityProviders[name]
+	if !ok {
+		return name, nil, errUnknownProvider
+	}
+	return name, provider, nil
+}
+
+// unknownProvider writes the 404 for a provider that is not enabled
+func unknownProvider(w http.ResponseWriter, name string) {
+	jsonResponseWithStatus(w, ErrorResponse{
+		Code:      CodeProviderNotFound,
+		Message:   fmt.Sprintf("Identity provider not enabled: %s", name),
+		Supported: providerNames(),
+	}, http.StatusNotFound)
+}
+
+// providerNames lists the enabled providers
+func providerNames() []string {
+	names := make([]string, 0, len(identityProviders))
+	for name := range identityProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// providersHandler lists the providers players can log in with
+func providersHandler(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, ProvidersResponse{Providers: providerNames()})
+}
+
+// oauthLoginHandler redirects to the provider to log in. The state and PKCE
+// verifier are kept in a cookie scoped to the callback.
+func oauthLoginHandler(w http.ResponseWriter, r *http.Request) {
+	name, provider, err := providerFor(r)
+	if err != nil {
+		unknownProvider(w, name)
+		return
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to start login")
+		return
+	}
+	state, verifier := hex.EncodeToString(nonce), oauth2.GenerateVerifier()
+	config := provider.oauth()
+	http.SetCookie(w, &http.Cookie{
+		Name:     oauthCookie,
+		Value:    state + "." + verifier,
+		Path:     "/v1/auth/" + name + "/callback",
+		MaxAge:   int(oauthLoginTimeout.Seconds()),
+		HttpOnly: true,
+		Secure:   strings.HasPrefix(config.RedirectURL, "https://"),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier)), http.StatusFound)
+}
+
+// oauthCallbackHandler finishes a login the provider redirected back from,
+// linking the identity to an account, and answers with a token like
+// POST /v1/auth/login
+func oauthCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	name, provider, err := providerFor(r)
+	if err != nil {
+		unknownProvider(w, name)
+		return
+	}
+
+	query := r.URL.Query()
+	if reason := query.Get("error"); reason != "" {
+		unauthorized(w, fmt.Sprintf("Login with %s failed: %s", name, reason))
+		return
+	}
+	cookie, err := r.Cookie(oauthCookie)
+	if err != nil {
+		unauthorized(w, "Login expired; start it again")
+		return
+	}
+	state, verifier, _ := strings.Cut(cookie.Value, ".")
+	if state == "" || query.Get("state") != state {
+		unauthorized(w, "Login state does not match; start it again")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oauthCookie, Path: cookie.Path, MaxAge: -1})
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+	token, err := provider.oauth().Exchange(ctx, query.Get("code"), oauth2.VerifierOption(verifier))
+	if err != nil {
+		unauthorized(w, fmt.Sprintf("Login with %s failed: the code was not accepted", name))
+		return
+	}
+	identity, err := provider.identify(ctx, token)
+	if err != nil || identity.Subject == "" {
+		log.Printf("identifying %s login: %v", name, err)
+		jsonError(w, http.StatusBadGateway, CodeProviderFailed,
+			fmt.Sprintf("Login with %s failed: the player could not be identified", name))
+		return
+	}
+
+	username, err := accounts.link(name, identity)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to link account")
+		return
+	}
+	response, err := issueToken(username, time.Now())
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to issue token")
+		return
+	}
+	jsonResponse(w, response)
+}
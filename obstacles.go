@@ -0,0 +1,85 @@
+package main
+
+import "fmt"
+
+// maxObstacles bounds the obstacles of a single game, keeping collision
+// checks cheap on large boards
+const maxObstacles = 1000
+
+// obstacleStream is the seeded random stream obstacles are drawn from; fruit
+// spawns use the streams after it
+const obstacleStream = 0
+
+// obstacleCountError returns an error code and message if the config asks
+// for more obstacles than the board can hold
+func obstacleCountError(config GameConfig) (ErrorCode, string) {
+	if config.Obstacles < 0 {
+		return CodeInvalidRequest, fmt.Sprintf("Invalid obstacles: %d", config.Obstacles)
+	}
+	limit := config.Width * config.Height / 2
+	if free := config.Width*config.Height - len(reservedCells(config.Width, config.Height)); limit > free {
+		limit = free
+	}
+	if limit > maxObstacles {
+		limit = maxObstacles
+	}
+	if config.Obstacles > limit {
+		return CodeInvalidBoard,
+			fmt.Sprintf("At most %d obstacles fit on a %dx%d board", limit, config.Width, config.Height)
+	}
+	return "", ""
+}
+
+// placeObstacles draws count distinct obstacle cells from the game's seed.
+// The reserved cells are kept free so no game is lost on its first tick.
+func placeObstacles(state GameState, count int) []Position {
+	if count == 0 {
+		return nil
+	}
+
+	taken := reservedCells(state.Width, state.Height)
+	free := state.Width*state.Height - len(taken)
+	if count > free {
+		count = max(free, 0)
+	}
+
+	rng := seededRand(state.Seed, obstacleStream)
+	obstacles := make([]Position, 0, count)
+	for len(obstacles) < count {
+		pos := generateRandomPosition(rng, state.Width, state.Height)
+		if taken[pos] {
+			continue
+		}
+		taken[pos] = true
+		obstacles = append(obstacles, pos)
+	}
+	return obstacles
+}
+
+// reservedCells returns the cells of a width x height board no obstacle may
+// take: the starting cells of both snakes and the cells right in front of
+// them, leaving out those off narrow boards
+func reservedCells(width, height int) map[Position]bool {
+	reserved := map[Position]bool{}
+	for _, pos := range []Position{
+		{X: 0, Y: 0},
+		{X: 1, Y: 0},
+		{X: width - 1, Y: height - 1},
+		{X: width - 2, Y: height - 1},
+	} {
+		if pos.X >= 0 && pos.X < width && pos.Y >= 0 && pos.Y < height {
+			reserved[pos] = true
+		}
+	}
+	return reserved
+}
+
+// isObstacle returns true if pos holds an obstacle
+func isObstacle(state GameState, pos Position) bool {
+	for _, obstacle := range state.Obstacles {
+		if obstacle == pos {
+			return true
+		}
+	}
+	return false
+}
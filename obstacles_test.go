@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestPlaceObstacles(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		count, want   int
+	}{
+		{name: "none", width: 10, height: 10, count: 0, want: 0},
+		{name: "some", width: 10, height: 10, count: 20, want: 20},
+		{name: "single column", width: 1, height: 3, count: 1, want: 1},
+		{name: "single row", width: 5, height: 1, count: 2, want: 1},
+		{name: "no free cell", width: 1, height: 2, count: 1, want: 0},
+		{name: "single cell", width: 1, height: 1, count: 1, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := GameState{Width: tt.width, Height: tt.height, Seed: 1}
+			obstacles := placeObstacles(state, tt.count)
+			if len(obstacles) != tt.want {
+				t.Fatalf("placed %d obstacles, want %d", len(obstacles), tt.want)
+			}
+			reserved := reservedCells(tt.width, tt.height)
+			seen := map[Position]bool{}
+			for _, pos := range obstacles {
+				if pos.X < 0 || pos.X >= tt.width || pos.Y < 0 || pos.Y >= tt.height || reserved[pos] || seen[pos] {
+					t.Errorf("obstacle at %+v is off the board, reserved or repeated in %v", pos, obstacles)
+				}
+				seen[pos] = true
+			}
+		})
+	}
+}
+
+func TestNarrowBoardObstacles(t *testing.T) {
+	h := newTestAPI(t)
+	tests := []struct {
+		name   string
+		config GameConfig
+		status int
+	}{
+		{name: "one free cell", config: GameConfig{Width: 1, Height: 3, Obstacles: 1}, status: http.StatusCreated},
+		{name: "no free cell", config: GameConfig{Width: 1, Height: 2, Obstacles: 1}, status: http.StatusBadRequest},
+		{name: "row without room", config: GameConfig{Width: 4, Height: 1, Obstacles: 2}, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, h, http.MethodPost, "/v1/games", "", tt.config)
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,531 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	swaggerFiles "github.com/swaggo/files"
+)
+
+type (
+	// openAPIDocument is the OpenAPI 3 description of the API served at
+	// /openapi.json
+	openAPIDocument struct {
+		OpenAPI    string                                 `json:"openapi"`
+		Info       openAPIInfo                            `json:"info"`
+		Paths      map[string]map[string]openAPIOperation `json:"paths"`
+		Components openAPIComponents                      `json:"components"`
+	}
+
+	openAPIInfo struct {
+		Title   string `json:"title"`
+		Version string `json:"version"`
+	}
+
+	openAPIComponents struct {
+		Schemas map[string]any `json:"schemas"`
+	}
+
+	openAPIOperation struct {
+		Summary     string                     `json:"summary"`
+		Tags        []string                   `json:"tags,omitempty"`
+		Deprecated  bool                       `json:"deprecated,omitempty"`
+		Parameters  []openAPIParameter         `json:"parameters,omitempty"`
+		RequestBody *openAPIRequestBody        `json:"requestBody,omitempty"`
+		Responses   map[string]openAPIResponse `json:"responses"`
+	}
+
+	openAPIParameter struct {
+		Name        string         `json:"name"`
+		In          string         `json:"in"`
+		Description string         `json:"description,omitempty"`
+		Required    bool           `json:"required,omitempty"`
+		Schema      map[string]any `json:"schema"`
+	}
+
+	openAPIRequestBody struct {
+		Required bool                        `json:"required"`
+		Content  map[string]openAPIMediaType `json:"content"`
+	}
+
+	openAPIResponse struct {
+		Description string                      `json:"description"`
+		Content     map[string]openAPIMediaType `json:"content,omitempty"`
+	}
+
+	openAPIMediaType struct {
+		Schema map[string]any `json:"schema"`
+	}
+)
+
+// routeDoc describes an operation of the router. Request and Response are
+// zero values of the body types, or nil for none.
+type routeDoc struct {
+	summary  string
+	tag      string
+	query    []openAPIParameter
+	request  any
+	response any
+	status   int
+
+	// contentType replaces the negotiated formats as the only response
+	// media type; protobuf routes also exchange proto/snake/v1 messages
+	contentType string
+	protobuf    bool
+
+	// unversioned routes have no /v1 counterpart and are not deprecated
+	unversioned bool
+}
+
+// queryParam documents a query parameter of the given JSON schema type
+func queryParam(name, schemaType, description string) openAPIParameter {
+	return openAPIParameter{Name: name, In: "query", Description: description,
+		Schema: map[string]any{"type": schemaType}}
+}
+
+// dryRunParam documents the query parameter checking a change without making
+// it
+var dryRunParam = queryParam("dryRun", "boolean", "check the change without making it")
+
+// newGameQuery are the query parameters of GET /new
+var newGameQuery = []openAPIParameter{
+	{Name: "w", In: "query", Required: true, Description: "board width", Schema: map[string]any{"type": "integer"}},
+	{Name: "h", In: "query", Required: true, Description: "board height", Schema: map[string]any{"type": "integer"}},
+	queryParam("seed", "integer", "seed of the fruit placement; random if omitted"),
+	queryParam("obstacles", "integer", "number of obstacle cells"),
+	queryParam("fruitCount", "integer", "fruits on the board at once"),
+	queryParam("golden", "integer", "percentage of golden fruits"),
+	queryParam("poison", "integer", "percentage of poison fruits"),
+	queryParam("powerUps", "integer", "percentage of fruit spawns dropping a power-up"),
+	queryParam("tickLimit", "integer", "ticks after which the game times out"),
+	queryParam("ttl", "integer", "seconds the game is kept without activity"),
+	queryParam("fruitSpawn", "string", "fruit spawn strategy"),
+	queryParam("mode", "string", "classic or wrap"),
+	queryParam("live", "boolean", "advance the game on the server"),
+	queryParam("versus", "boolean", "wait for a second player"),
+}
+
+// routeDocs document the routes by method and path, with the /v1 prefix
+// removed so versioned routes share the docs of their legacy aliases
+var routeDocs = map[string]routeDoc{
+	"GET /new": {summary: "Create a game from query parameters", tag: "games",
+		query: newGameQuery, response: GameState{}, protobuf: true},
+	"POST /games": {summary: "Create a game", tag: "games",
+		request: GameConfig{}, response: GameState{}, status: http.StatusCreated},
+	"POST /games/batch": {summary: "Create several games", tag: "games",
+		request: BatchRequest{}, response: BatchResponse{}},
+	"POST /new/batch": {summary: "Create several games", tag: "games",
+		request: BatchRequest{}, response: BatchResponse{}},
+	"GET /games/{id}": {summary: "Get the current state of a game", tag: "games",
+		response: GameState{}},
+	"GET /games/{id}/wait": {summary: "Wait for the next version of a game, or 304 on timeout", tag: "games",
+		query: []openAPIParameter{
+			queryParam("sinceVersion", "integer", "answer once the version is past this one"),
+			queryParam("timeout", "string", "how long to wait, such as 30s; at most 1m"),
+		},
+		response: GameState{}},
+	"DELETE /games/{id}": {summary: "Abandon and remove a game", tag: "games",
+		status: http.StatusNoContent},
+	"POST /games/{id}/pause":  {summary: "Pause a game", tag: "games", response: GameState{}},
+	"POST /games/{id}/resume": {summary: "Resume a paused game", tag: "games", response: GameState{}},
+	"POST /games/{id}/validate": {summary: "Apply ticks to a game", tag: "games",
+		query:   []openAPIParameter{dryRunParam},
+		request: ValidateRequest{}, response: ValidateResponse{}, protobuf: true},
+	"POST /validate": {summary: "Apply ticks to the game named in the body", tag: "games",
+		query:   []openAPIParameter{dryRunParam},
+		request: ValidateRequest{}, response: ValidateResponse{}, protobuf: true},
+	"POST /games/{id}/join": {summary: "Join a versus game as player 2", tag: "live",
+		response: GameState{}},
+	"POST /games/{id}/bot": {summary: "Let a bot play the opponent of a versus game", tag: "live",
+		request: BotRequest{}, response: GameState{}},
+	"GET /bots": {summary: "List the bot difficulties", tag: "live", response: BotsResponse{}},
+	"GET /games/{id}/ws": {summary: "Play a live game over WebSocket", tag: "live",
+		query:  []openAPIParameter{queryParam("player", "integer", "2 steers the opponent")},
+		status: http.StatusSwitchingProtocols},
+	"GET /games/{id}/events": {summary: "Stream the state of a live game as Server-Sent Events", tag: "live",
+		response: GameState{}, contentType: "text/event-stream"},
+	"PATCH /games/{id}/direction": {summary: "Change the direction of a live game", tag: "live",
+		query:   []openAPIParameter{dryRunParam},
+		request: DirectionRequest{}, response: DirectionRequest{}, status: http.StatusAccepted},
+	"GET /games/{id}/replay": {summary: "Get the tick history of a game", tag: "replays",
+		response: Replay{}},
+	"GET /games/{id}/state": {summary: "Rebuild the state of a game after one of its recorded ticks", tag: "replays",
+		query: []openAPIParameter{{Name: "atTick", In: "query", Required: true,
+			Description: "ticks to replay; 0 gives the initial state", Schema: map[string]any{"type": "integer"}}},
+		response: GameState{}},
+	"GET /games/{id}/render": {summary: "Render the board as a PNG, or an SVG with format=svg", tag: "games",
+		query: []openAPIParameter{
+			queryParam("format", "string", "png or svg"),
+			queryParam("cell", "integer", "side of a cell in pixels"),
+		},
+		response: "", contentType: "image/png"},
+	"GET /games/{id}/replay.gif": {summary: "Render the tick history of a game as an animated GIF", tag: "replays",
+		query: []openAPIParameter{
+			queryParam("fps", "integer", "frames per second"),
+			queryParam("cell", "integer", "side of a cell in pixels"),
+		},
+		response: "", contentType: "image/gif"},
+	"GET /games/{id}/hint": {summary: "Suggest the next tick towards the nearest fruit", tag: "games",
+		response: HintResponse{}},
+	"POST /replays/{id}/play": {summary: "Re-simulate a recorded game", tag: "replays",
+		query:    []openAPIParameter{queryParam("frames", "boolean", "include the state after every tick")},
+		response: PlaybackResponse{}},
+	"POST /replays/diff": {summary: "Compare two replays on the same seed", tag: "replays",
+		request: ReplayDiffRequest{}, response: ReplayDiffResponse{}},
+	"GET /players/{id}/rating": {summary: "Get the Elo rating of a player", tag: "ratings",
+		response: PlayerRating{}},
+	"GET /players/{id}/rank": {summary: "Get the rank and percentile of a player in every leaderboard partition", tag: "leaderboard",
+		response: PlayerRankResponse{}},
+	"GET /players/{id}/stats": {summary: "Get the statistics of a player from their finished games", tag: "stats",
+		response: PlayerStats{}},
+	"GET /players/{id}/achievements": {summary: "List the achievements a player has unlocked", tag: "achievements",
+		response: PlayerAchievementsResponse{}},
+	"GET /achievements": {summary: "List every achievement", tag: "achievements",
+		response: AchievementsResponse{}},
+	"GET /stats": {summary: "Get the participation totals of the server", tag: "stats",
+		response: ServerStats{}},
+	"GET /ratings": {summary: "Get the highest rated players", tag: "ratings",
+		query:    []openAPIParameter{queryParam("limit", "integer", "number of players")},
+		response: RatingsResponse{}},
+	"POST /matchmaking/queue": {summary: "Queue for a versus game against a player of similar rating", tag: "matchmaking",
+		request: QueueRequest{}, response: MatchTicket{}, status: http.StatusAccepted},
+	"GET /matchmaking/queue/{ticket}": {summary: "Get a matchmaking ticket", tag: "matchmaking",
+		response: MatchTicket{}},
+	"DELETE /matchmaking/queue/{ticket}": {summary: "Leave the matchmaking queue", tag: "matchmaking",
+		response: MatchTicket{}},
+	"GET /matchmaking/queue/{ticket}/events": {summary: "Stream a matchmaking ticket until it is matched",
+		tag: "matchmaking", response: MatchTicket{}, contentType: "text/event-stream"},
+	"POST /tournaments": {summary: "Create a tournament", tag: "tournaments",
+		request: TournamentRequest{}, response: Tournament{}, status: http.StatusCreated},
+	"GET /tournaments/{id}": {summary: "Get a tournament with its bracket", tag: "tournaments",
+		response: Tournament{}},
+	"POST /tournaments/{id}/players": {summary: "Register the caller for a tournament", tag: "tournaments",
+		response: Tournament{}, status: http.StatusCreated},
+	"POST /tournaments/{id}/bracket": {summary: "Close registration and generate the single-elimination bracket",
+		tag: "tournaments", response: Tournament{}},
+	"POST /tournaments/{id}/results": {summary: "Decide a match with the finished versus game of its players",
+		tag: "tournaments", request: MatchResultRequest{}, response: Tournament{}},
+	"GET /tournaments/{id}/standings": {summary: "Rank the players of a tournament", tag: "tournaments",
+		response: StandingsResponse{}},
+	"POST /auth/register": {summary: "Create an account and log in", tag: "accounts",
+		request: Credentials{}, response: TokenResponse{}, status: http.StatusCreated},
+	"POST /auth/login": {summary: "Log in and get a bearer token", tag: "accounts",
+		request: Credentials{}, response: TokenResponse{}},
+	"GET /auth/me": {summary: "Get the account of the bearer token", tag: "accounts",
+		response: Account{}},
+	"GET /auth/providers": {summary: "List the identity providers players can log in with", tag: "accounts",
+		response: ProvidersResponse{}},
+	"GET /auth/{provider}/login": {summary: "Redirect to an identity provider to log in", tag: "accounts",
+		status: http.StatusFound},
+	"GET /auth/{provider}/callback": {summary: "Finish logging in with an identity provider", tag: "accounts",
+		query: []openAPIParameter{
+			queryParam("code", "string", "authorization code from the provider"),
+			queryParam("state", "string", "state sent to the provider"),
+		},
+		response: TokenResponse{}},
+	"POST /webhooks": {summary: "Register a webhook for game, score or tournament match events", tag: "webhooks",
+		request: WebhookRequest{}, response: Webhook{}, status: http.StatusCreated},
+	"GET /webhooks": {summary: "List the webhooks of the caller", tag: "webhooks",
+		response: WebhooksResponse{}},
+	"DELETE /webhooks/{id}": {summary: "Delete a webhook", tag: "webhooks",
+		status: http.StatusNoContent},
+	"GET /webhooks/{id}/deliveries": {summary: "Get the recent deliveries of a webhook", tag: "webhooks",
+		response: DeliveriesResponse{}},
+	"GET /admin/audit": {summary: "Search the audit log of validate requests", tag: "admin",
+		query: []openAPIParameter{
+			queryParam("gameId", "string", "only requests for this game"),
+			queryParam("caller", "string", "only requests of this account or bot"),
+			queryParam("status", "integer", "only requests answered with this HTTP status"),
+			queryParam("since", "string", "only requests from this RFC 3339 time on"),
+			queryParam("before", "integer", "only entries older than this ID, from the next field of a page"),
+			queryParam("limit", "integer", "number of entries"),
+		},
+		response: AuditResponse{}},
+	"GET /admin/deliveries/failed": {summary: "List the webhook deliveries that ran out of attempts", tag: "admin",
+		response: DeliveriesResponse{}},
+	"POST /admin/deliveries/{id}/redeliver": {summary: "Queue a webhook delivery again", tag: "admin",
+		response: Delivery{}},
+	"POST /admin/keys": {summary: "Issue an API key for a bot client", tag: "admin",
+		request: APIKeyRequest{}, response: APIKey{}, status: http.StatusCreated},
+	"GET /admin/keys": {summary: "List the issued API keys", tag: "admin",
+		response: APIKeysResponse{}},
+	"DELETE /admin/keys/{key}": {summary: "Revoke an API key", tag: "admin",
+		response: APIKey{}},
+	"POST /scores": {summary: "Submit the final score of a game", tag: "leaderboard",
+		request: ScoreRequest{}, response: ScoreEntry{}, status: http.StatusCreated},
+	"GET /leaderboard": {summary: "Get the top scores", tag: "leaderboard",
+		query: []openAPIParameter{
+			queryParam("limit", "integer", "number of scores"),
+			queryParam("window", "string", "daily, weekly or alltime"),
+			queryParam("board", "string", "only scores on this board, such as 10x10"),
+			queryParam("difficulty", "string", "only scores against solo, versus or a bot difficulty"),
+			queryParam("rules", "string", "only scores by this rule profile, such as wrap+obstacles"),
+		},
+		response: LeaderboardResponse{}},
+	"GET /leaderboards": {summary: "List the leaderboard partitions by board, difficulty and rules", tag: "leaderboard",
+		query:    []openAPIParameter{queryParam("window", "string", "daily, weekly or alltime")},
+		response: LeaderboardIndexResponse{}},
+	"GET /daily": {summary: "Create a game of today's challenge, the same for every player", tag: "daily",
+		response: GameState{}},
+	"GET /daily/leaderboard": {summary: "Get the best players of a daily challenge", tag: "daily",
+		query: []openAPIParameter{
+			queryParam("date", "string", "day of the challenge as YYYY-MM-DD, today by default"),
+			queryParam("limit", "integer", "number of players"),
+		},
+		response: DailyLeaderboardResponse{}},
+	"GET /dev/fixtures/{name}": {summary: "Create a game in a named fixture state", tag: "dev",
+		query:    []openAPIParameter{queryParam("w", "integer", "board width"), queryParam("h", "integer", "board height")},
+		response: GameState{}, unversioned: true},
+	"POST /graphql": {summary: "Run a GraphQL query or mutation", tag: "graphql",
+		request: GraphQLRequest{}, response: map[string]any{}, contentType: "application/json", unversioned: true},
+	"GET /graphql": {summary: "Run GraphQL subscriptions over WebSocket with graphql-transport-ws", tag: "graphql",
+		status: http.StatusSwitchingProtocols, unversioned: true},
+	"GET /metrics": {summary: "Prometheus metrics", tag: "ops",
+		contentType: "text/plain", response: "", unversioned: true},
+	"GET /healthz": {summary: "Liveness probe", tag: "ops", response: HealthResponse{}, unversioned: true},
+	"GET /readyz":  {summary: "Readiness probe", tag: "ops", response: HealthResponse{}, unversioned: true},
+	"GET /openapi.json": {summary: "This document", tag: "ops", response: map[string]any{},
+		contentType: "application/json", unversioned: true},
+}
+
+// enumValues lists the values of string types with a fixed set of them
+var enumValues = map[reflect.Type][]string{
+	reflect.TypeOf(GameOverReason("")): {string(ReasonWall), string(ReasonSelf), string(ReasonCollision),
+		string(ReasonObstacle), string(ReasonPoison), string(ReasonTimeout), string(ReasonInvalidMove),
+		string(ReasonAbandoned)},
+	reflect.TypeOf(GameStatus("")):     {string(GameActive), string(GamePaused)},
+	reflect.TypeOf(ValidateStatus("")): {string(ResultOK), string(ResultGameOver)},
+	reflect.TypeOf(GameMode("")):       {string(ModeClassic), string(ModeWrap)},
+	reflect.TypeOf(FruitSpawn("")): {string(SpawnUniform), string(SpawnCenter), string(SpawnAwayFromWalls),
+		string(SpawnNearSnake)},
+	reflect.TypeOf(FruitType("")):   {string(FruitNormal), string(FruitGolden), string(FruitPoison)},
+	reflect.TypeOf(PowerUpType("")): {string(PowerUpSpeed), string(PowerUpSlow), string(PowerUpPhase)},
+}
+
+// openAPISpec describes every route of the router. Routes missing from
+// routeDocs are listed with a generic response.
+func openAPISpec(routes chi.Routes) (openAPIDocument, error) {
+	doc := openAPIDocument{
+		OpenAPI: "3.0.3",
+		Info:    openAPIInfo{Title: "Snake Game API", Version: "v1"},
+		Paths:   make(map[string]map[string]openAPIOperation),
+	}
+	schemas := schemaBuilder{schemas: make(map[string]any)}
+
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		path := route
+		if path != "/" {
+			path = strings.TrimSuffix(path, "/")
+		}
+		if strings.HasPrefix(path, "/docs") {
+			return nil
+		}
+		versioned := strings.HasPrefix(path, "/v1/")
+		routeDoc := routeDocs[method+" "+strings.TrimPrefix(path, "/v1")]
+
+		op := openAPIOperation{
+			Summary:    routeDoc.summary,
+			Deprecated: !versioned && !routeDoc.unversioned && routeDoc.summary != "",
+			Responses:  make(map[string]openAPIResponse),
+		}
+		if op.Summary == "" {
+			op.Summary = method + " " + path
+		}
+		if routeDoc.tag != "" {
+			op.Tags = []string{routeDoc.tag}
+		}
+		for _, name := range pathParams(path) {
+			op.Parameters = append(op.Parameters, openAPIParameter{
+				Name: name, In: "path", Required: true, Schema: map[string]any{"type": "string"},
+			})
+		}
+		op.Parameters = append(op.Parameters, routeDoc.query...)
+
+		mediaTypes := []string{"application/json", msgpackMediaType}
+		if routeDoc.protobuf {
+			mediaTypes = append(mediaTypes, protobufMediaType)
+		}
+		if routeDoc.request != nil {
+			op.RequestBody = &openAPIRequestBody{Required: true,
+				Content: schemas.content(routeDoc.request, mediaTypes)}
+		}
+
+		status := routeDoc.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		response := openAPIResponse{Description: http.StatusText(status)}
+		if routeDoc.contentType != "" {
+			mediaTypes = []string{routeDoc.contentType}
+		}
+		if routeDoc.response != nil {
+			response.Content = schemas.content(routeDoc.response, mediaTypes)
+		}
+		op.Responses[fmt.Sprint(status)] = response
+		op.Responses["default"] = openAPIResponse{
+			Description: "Error",
+			Content:     schemas.content(ErrorResponse{}, []string{"application/json", msgpackMediaType}),
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]openAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(method)] = op
+		return nil
+	})
+	doc.Components.Schemas = schemas.schemas
+	return doc, err
+}
+
+// pathParams returns the names of the {param} segments of a route
+func pathParams(path string) []string {
+	var names []string
+	for _, segment := range strings.Split(path, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, strings.Trim(segment, "{}"))
+		}
+	}
+	return names
+}
+
+// schemaBuilder derives JSON schemas from Go types and their json tags,
+// collecting named structs as components
+type schemaBuilder struct {
+	schemas map[string]any
+}
+
+// content describes a body of the type of v in each of the media types
+func (b *schemaBuilder) content(v any, mediaTypes []string) map[string]openAPIMediaType {
+	schema := b.schemaOf(reflect.TypeOf(v))
+	content := make(map[string]openAPIMediaType, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		content[mediaType] = openAPIMediaType{Schema: schema}
+	}
+	return content
+}
+
+func (b *schemaBuilder) schemaOf(t reflect.Type) map[string]any {
+	if t == reflect.TypeOf(time.Time{}) {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	if values, ok := enumValues[t]; ok {
+		return map[string]any{"type": "string", "enum": values}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return map[string]any{"allOf": []any{b.schemaOf(t.Elem())}, "nullable": true}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		if _, ok := b.schemas[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			b.schemas[t.Name()] = nil
+			b.schemas[t.Name()] = b.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": b.schemaOf(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": b.schemaOf(t.Elem())}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int64:
+		return map[string]any{"type": "integer", "format": "int64"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	}
+	return map[string]any{}
+}
+
+// structSchema describes the JSON object of a struct. Embedded structs
+// without a json name are inlined like encoding/json does.
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	b.addFields(t, properties, &required)
+	sort.Strings(required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (b *schemaBuilder) addFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			b.addFields(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = b.schemaOf(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+}
+
+// openAPIHandler serves the OpenAPI document built from the router once
+// all routes are registered
+func openAPIHandler(doc *openAPIDocument) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// The document is JSON whatever the request accepts
+		w.Header().Set("Content-Type", "application/json")
+		jsonResponse(w, doc)
+	}
+}
+
+// swaggerInitializer points the embedded Swagger UI at /openapi.json
+const swaggerInitializer = `window.onload = function() {
+  window.ui = SwaggerUIBundle({
+    url: "/openapi.json",
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+    presets: [SwaggerUIBundle.presets.apis, SwaggerUIStandalonePreset],
+    layout: "StandaloneLayout"
+  });
+};
+`
+
+// docsHandler serves the Swagger UI under /docs/
+func docsHandler() http.Handler {
+	files := http.StripPrefix("/docs", http.FileServer(swaggerFiles.HTTP))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs":
+			http.Redirect(w, r, "/docs/", http.StatusMovedPermanently)
+		case "/docs/swagger-initializer.js":
+			w.Header().Set("Content-Type", "text/javascript")
+			w.Write([]byte(swaggerInitializer))
+		default:
+			// The file server picks the Content-Type from the file name
+			w.Header().Del("Content-Type")
+			files.ServeHTTP(w, r)
+		}
+	})
+}
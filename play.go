@@ -0,0 +1,276 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// apiClient calls the JSON API of a snake server
+type apiClient struct {
+	baseURL string
+	http    *http.Client
+}
+
+// newAPIClient returns a client of the server at baseURL
+func newAPIClient(baseURL string) *apiClient {
+	return &apiClient{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		http:    &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// apiError is an error response of the server
+type apiError struct {
+	status   int
+	response ErrorResponse
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.response.Code, e.response.Message)
+}
+
+// do sends body as JSON and decodes a successful response into out
+func (c *apiClient) do(method, path string, body, out any) error {
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		apiErr := &apiError{status: resp.StatusCode}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr.response); err != nil {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		return apiErr
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// newGame creates a game with the given config
+func (c *apiClient) newGame(config GameConfig) (GameState, error) {
+	var state GameState
+	err := c.do(http.MethodPost, "/v1/games", config, &state)
+	return state, err
+}
+
+// validate applies ticks to a game. The legacy 418 of servers run with
+// -teapot is reported as a game_over result.
+func (c *apiClient) validate(gameID string, ticks []Tick) (ValidateResponse, error) {
+	var response ValidateResponse
+	err := c.do(http.MethodPost, "/v1/games/"+gameID+"/validate", ValidateRequest{Ticks: ticks}, &response)
+	if apiErr, ok := err.(*apiError); ok && apiErr.status == http.StatusTeapot && apiErr.response.State != nil {
+		return ValidateResponse{GameState: *apiErr.response.State, Status: ResultGameOver}, nil
+	}
+	return response, err
+}
+
+// playSession advances a game by one tick at a time, on a server or with
+// the embedded engine
+type playSession interface {
+	step(tick Tick) (GameState, ValidateStatus, error)
+}
+
+// remoteSession plays a game stored on a server
+type remoteSession struct {
+	client *apiClient
+	gameID string
+}
+
+func (s *remoteSession) step(tick Tick) (GameState, ValidateStatus, error) {
+	result, err := s.client.validate(s.gameID, []Tick{tick})
+	return result.GameState, result.Status, err
+}
+
+// localSession plays a game with the embedded engine, without a server
+type localSession struct {
+	state GameState
+}
+
+func (s *localSession) step(tick Tick) (GameState, ValidateStatus, error) {
+	state, statusCode := stepGame(s.state, tick)
+	switch statusCode {
+	case http.StatusBadRequest:
+		return s.state, "", errors.New("velocity must turn the snake one cell up, down, left or right")
+	case http.StatusTeapot:
+		s.state = state
+		return state, ResultGameOver, nil
+	}
+	s.state = state
+	return state, ResultOK, nil
+}
+
+// playCommand plays a game in the terminal, against a server or with the
+// embedded engine with -local. On a terminal the snake moves every
+// -interval and the arrow keys steer it; otherwise every line read from
+// stdin is one move.
+func playCommand(args []string) {
+	flags := flag.NewFlagSet("play", flag.ExitOnError)
+	server := flags.String("server", "http://localhost"+defaultAddr, "base URL of the snake server")
+	local := flags.Bool("local", false, "play with the embedded engine instead of a server")
+	interval := flags.Duration("interval", 200*time.Millisecond, "how often the snake moves on a terminal")
+	var config GameConfig
+	flags.IntVar(&config.Width, "w", 20, "board width")
+	flags.IntVar(&config.Height, "h", 20, "board height")
+	flags.Int64Var(&config.Seed, "seed", 0, "seed of the fruit placement; random if 0")
+	flags.IntVar(&config.Obstacles, "obstacles", 0, "number of obstacle cells")
+	flags.IntVar(&config.FruitCount, "fruits", 0, "fruits on the board at once")
+	flags.IntVar(&config.FruitChances.Golden, "golden", 0, "percentage of golden fruits")
+	flags.IntVar(&config.FruitChances.Poison, "poison", 0, "percentage of poison fruits")
+	flags.IntVar(&config.PowerUpChance, "power-ups", 0, "percentage of fruit spawns dropping a power-up")
+	mode := flags.String("mode", "", "board rules: classic or wrap")
+	flags.Parse(args)
+	config.Mode = GameMode(*mode)
+
+	var session playSession
+	var state GameState
+	if *local {
+		if code, message := gameConfigError(config); code != "" {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", code, message)
+			os.Exit(2)
+		}
+		state = initializeGame(config)
+		session = &localSession{state: state}
+	} else {
+		client := newAPIClient(*server)
+		var err error
+		if state, err = client.newGame(config); err != nil {
+			fmt.Fprintf(os.Stderr, "creating game: %v\n", err)
+			os.Exit(1)
+		}
+		session = &remoteSession{client: client, gameID: state.GameID}
+	}
+
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		if err := playTUI(session, state, *interval); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	playLines(session, state)
+}
+
+// playLines plays one move per line of stdin and prints the board after each
+func playLines(session playSession, state GameState) {
+	input := bufio.NewScanner(os.Stdin)
+	for {
+		fmt.Print(renderText(state))
+		fmt.Printf("score %d  w/a/s/d turns, enter goes straight, q quits > ", state.Score)
+		if !input.Scan() {
+			fmt.Println()
+			return
+		}
+		key := strings.TrimSpace(input.Text())
+		if key == "q" {
+			return
+		}
+		tick, ok := keyTick(key, state.Snake)
+		if !ok {
+			fmt.Printf("unknown key %q\n", key)
+			continue
+		}
+
+		next, status, err := session.step(tick)
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		state = next
+		if status == ResultGameOver {
+			fmt.Print(renderText(state))
+			fmt.Printf("game over: %s, score %d\n", state.GameOverReason, state.Score)
+			return
+		}
+	}
+}
+
+// keyTick returns the tick a w/a/s/d key steers the snake to; no key keeps
+// its direction
+func keyTick(key string, snake Snake) (Tick, bool) {
+	switch key {
+	case "":
+		return Tick{VelX: snake.VelX, VelY: snake.VelY}, true
+	case "w":
+		return Tick{VelX: 0, VelY: -1}, true
+	case "s":
+		return Tick{VelX: 0, VelY: 1}, true
+	case "a":
+		return Tick{VelX: -1, VelY: 0}, true
+	case "d":
+		return Tick{VelX: 1, VelY: 0}, true
+	}
+	return Tick{}, false
+}
+
+// boardCells returns the character of every cell of the board by row
+func boardCells(state GameState) [][]byte {
+	cells := make([][]byte, state.Height)
+	for y := range cells {
+		cells[y] = bytes.Repeat([]byte{'.'}, state.Width)
+	}
+	set := func(pos Position, c byte) {
+		if pos.X >= 0 && pos.X < state.Width && pos.Y >= 0 && pos.Y < state.Height {
+			cells[pos.Y][pos.X] = c
+		}
+	}
+
+	for _, pos := range state.Obstacles {
+		set(pos, '#')
+	}
+	for _, powerUp := range state.PowerUps {
+		set(powerUp.Position, '+')
+	}
+	for _, fruit := range state.Fruits {
+		switch fruit.Type {
+		case FruitGolden:
+			set(fruit.Position, '$')
+		case FruitPoison:
+			set(fruit.Position, '!')
+		default:
+			set(fruit.Position, '*')
+		}
+	}
+	if state.Opponent != nil {
+		for _, pos := range state.Opponent.Body {
+			set(pos, 'x')
+		}
+		set(state.Opponent.Position, '&')
+	}
+	for _, pos := range state.Snake.Body {
+		set(pos, 'o')
+	}
+	set(state.Snake.Position, '@')
+	return cells
+}
+
+// renderText draws the board as text: @ is the head, o the body, * a fruit,
+// $ a golden and ! a poison fruit, # an obstacle and + a power-up
+func renderText(state GameState) string {
+	var b strings.Builder
+	for _, row := range boardCells(state) {
+		b.Write(row)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// PowerUpType names the effect a power-up has on the snake that takes it
+type PowerUpType string
+
+const (
+	// PowerUpSpeed moves the snake two cells per tick and doubles fruit points
+	PowerUpSpeed PowerUpType = "speed"
+	// PowerUpSlow moves the snake only every other tick
+	PowerUpSlow PowerUpType = "slow"
+	// PowerUpPhase lets the snake pass through walls to the opposite edge
+	PowerUpPhase PowerUpType = "phase"
+)
+
+// powerUpTypes lists the power-ups in the order they are drawn
+var powerUpTypes = []PowerUpType{PowerUpSpeed, PowerUpSlow, PowerUpPhase}
+
+// powerUpDuration is the number of ticks an effect lasts once taken
+const powerUpDuration = 20
+
+// maxPowerUps bounds the power-ups lying on the board at once
+const maxPowerUps = 3
+
+// PowerUp is a power-up lying on the board
+type PowerUp struct {
+	Position
+	Type PowerUpType `json:"type"`
+}
+
+// Effect is a power-up the snake has taken; it wears off after TicksLeft
+type Effect struct {
+	Type      PowerUpType `json:"type"`
+	TicksLeft int         `json:"ticksLeft"`
+}
+
+// powerUpChanceError returns an error code and message if the config asks
+// for power-ups it cannot have
+func powerUpChanceError(config GameConfig) (ErrorCode, string) {
+	if config.PowerUpChance < 0 || config.PowerUpChance > 100 {
+		return CodeInvalidRequest, fmt.Sprintf("Invalid powerUpChance: %d", config.PowerUpChance)
+	}
+	if config.PowerUpChance > 0 && config.Versus {
+		return CodeInvalidRequest, "Power-ups are not available in versus games"
+	}
+	return "", ""
+}
+
+// maybeSpawnPowerUp places a power-up with the game's power-up chance. It
+// draws from the rng of the fruit spawned alongside it, so replays place
+// the same power-ups.
+func maybeSpawnPowerUp(state GameState, rng *rand.Rand) GameState {
+	if state.PowerUpChance == 0 || rng.Intn(100) >= state.PowerUpChance {
+		return state
+	}
+	blocked := blockedCells(state, -1)
+	if len(state.PowerUps) >= maxPowerUps || len(blocked)+1 >= state.Width*state.Height/2 {
+		return state
+	}
+
+	powerUp := PowerUp{
+		Position: freeRandomPosition(state, rng, blocked),
+		Type:     powerUpTypes[rng.Intn(len(powerUpTypes))],
+	}
+	state.PowerUps = append(append([]PowerUp(nil), state.PowerUps...), powerUp)
+	return state
+}
+
+// takePowerUp applies the power-up the snake head is on, if any, refreshing
+// the duration of an effect that is already active
+func takePowerUp(state GameState) GameState {
+	for i, powerUp := range state.PowerUps {
+		if powerUp.Position != state.Snake.Position {
+			continue
+		}
+
+		powerUps := append([]PowerUp(nil), state.PowerUps[:i]...)
+		state.PowerUps = append(powerUps, state.PowerUps[i+1:]...)
+
+		effects := make([]Effect, 0, len(state.Effects)+1)
+		for _, effect := range state.Effects {
+			if effect.Type != powerUp.Type {
+				effects = append(effects, effect)
+			}
+		}
+		state.Effects = append(effects, Effect{Type: powerUp.Type, TicksLeft: powerUpDuration})
+		return state
+	}
+	return state
+}
+
+// hasEffect returns true if the effect is active
+func hasEffect(state GameState, effectType PowerUpType) bool {
+	for _, effect := range state.Effects {
+		if effect.Type == effectType {
+			return true
+		}
+	}
+	return false
+}
+
+// moveSteps returns how many cells the snake moves this tick under its
+// active effects. Slow-down skips every other tick.
+func moveSteps(state GameState) int {
+	steps := 1
+	for _, effect := range state.Effects {
+		switch effect.Type {
+		case PowerUpSpeed:
+			steps *= 2
+		case PowerUpSlow:
+			if effect.TicksLeft%2 == 0 {
+				steps = 0
+			}
+		}
+	}
+	return steps
+}
+
+// wearEffects counts down the active effects by one tick, dropping the ones
+// that have run out
+func wearEffects(state GameState) GameState {
+	if len(state.Effects) == 0 {
+		return state
+	}
+
+	effects := make([]Effect, 0, len(state.Effects))
+	for _, effect := range state.Effects {
+		effect.TicksLeft--
+		if effect.TicksLeft > 0 {
+			effects = append(effects, effect)
+		}
+	}
+	if len(effects) == 0 {
+		effects = nil
+	}
+	state.Effects = effects
+	return state
+}
@@ -0,0 +1,1626 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.33.0
+// 	protoc        v4.25.3
+// source: snake/v1/snake.proto
+
+// The snake game service served over gRPC next to the HTTP API. Messages
+// mirror the JSON bodies of the HTTP endpoints; enums are kept as the same
+// strings the JSON uses.
+//
+// Regenerate the Go package from the proto directory with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//     snake/v1/snake.proto
+
+package snakepb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Position struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	X int32 `protobuf:"varint,1,opt,name=x,proto3" json:"x,omitempty"`
+	Y int32 `protobuf:"varint,2,opt,name=y,proto3" json:"y,omitempty"`
+}
+
+func (x *Position) Reset() {
+	*x = Position{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Position) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Position) ProtoMessage() {}
+
+func (x *Position) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Position.ProtoReflect.Descriptor instead.
+func (*Position) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Position) GetX() int32 {
+	if x != nil {
+		return x.X
+	}
+	return 0
+}
+
+func (x *Position) GetY() int32 {
+	if x != nil {
+		return x.Y
+	}
+	return 0
+}
+
+type Tick struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	VelX int32 `protobuf:"varint,1,opt,name=vel_x,json=velX,proto3" json:"vel_x,omitempty"`
+	VelY int32 `protobuf:"varint,2,opt,name=vel_y,json=velY,proto3" json:"vel_y,omitempty"`
+}
+
+func (x *Tick) Reset() {
+	*x = Tick{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Tick) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Tick) ProtoMessage() {}
+
+func (x *Tick) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Tick.ProtoReflect.Descriptor instead.
+func (*Tick) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Tick) GetVelX() int32 {
+	if x != nil {
+		return x.VelX
+	}
+	return 0
+}
+
+func (x *Tick) GetVelY() int32 {
+	if x != nil {
+		return x.VelY
+	}
+	return 0
+}
+
+type Snake struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Head *Position `protobuf:"bytes,1,opt,name=head,proto3" json:"head,omitempty"`
+	VelX int32     `protobuf:"varint,2,opt,name=vel_x,json=velX,proto3" json:"vel_x,omitempty"`
+	VelY int32     `protobuf:"varint,3,opt,name=vel_y,json=velY,proto3" json:"vel_y,omitempty"`
+	// Body holds the segments behind the head, from neck to tail
+	Body []*Position `protobuf:"bytes,4,rep,name=body,proto3" json:"body,omitempty"`
+}
+
+func (x *Snake) Reset() {
+	*x = Snake{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Snake) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Snake) ProtoMessage() {}
+
+func (x *Snake) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Snake.ProtoReflect.Descriptor instead.
+func (*Snake) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Snake) GetHead() *Position {
+	if x != nil {
+		return x.Head
+	}
+	return nil
+}
+
+func (x *Snake) GetVelX() int32 {
+	if x != nil {
+		return x.VelX
+	}
+	return 0
+}
+
+func (x *Snake) GetVelY() int32 {
+	if x != nil {
+		return x.VelY
+	}
+	return 0
+}
+
+func (x *Snake) GetBody() []*Position {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+type Fruit struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Position *Position `protobuf:"bytes,1,opt,name=position,proto3" json:"position,omitempty"`
+	Type     string    `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+}
+
+func (x *Fruit) Reset() {
+	*x = Fruit{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Fruit) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Fruit) ProtoMessage() {}
+
+func (x *Fruit) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Fruit.ProtoReflect.Descriptor instead.
+func (*Fruit) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Fruit) GetPosition() *Position {
+	if x != nil {
+		return x.Position
+	}
+	return nil
+}
+
+func (x *Fruit) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+type FruitChances struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Golden int32 `protobuf:"varint,1,opt,name=golden,proto3" json:"golden,omitempty"`
+	Poison int32 `protobuf:"varint,2,opt,name=poison,proto3" json:"poison,omitempty"`
+}
+
+func (x *FruitChances) Reset() {
+	*x = FruitChances{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FruitChances) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FruitChances) ProtoMessage() {}
+
+func (x *FruitChances) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FruitChances.ProtoReflect.Descriptor instead.
+func (*FruitChances) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FruitChances) GetGolden() int32 {
+	if x != nil {
+		return x.Golden
+	}
+	return 0
+}
+
+func (x *FruitChances) GetPoison() int32 {
+	if x != nil {
+		return x.Poison
+	}
+	return 0
+}
+
+type PowerUp struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Position *Position `protobuf:"bytes,1,opt,name=position,proto3" json:"position,omitempty"`
+	Type     string    `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+}
+
+func (x *PowerUp) Reset() {
+	*x = PowerUp{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *PowerUp) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PowerUp) ProtoMessage() {}
+
+func (x *PowerUp) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PowerUp.ProtoReflect.Descriptor instead.
+func (*PowerUp) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PowerUp) GetPosition() *Position {
+	if x != nil {
+		return x.Position
+	}
+	return nil
+}
+
+func (x *PowerUp) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+type Effect struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Type      string `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"`
+	TicksLeft int32  `protobuf:"varint,2,opt,name=ticks_left,json=ticksLeft,proto3" json:"ticks_left,omitempty"`
+}
+
+func (x *Effect) Reset() {
+	*x = Effect{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Effect) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Effect) ProtoMessage() {}
+
+func (x *Effect) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Effect.ProtoReflect.Descriptor instead.
+func (*Effect) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Effect) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Effect) GetTicksLeft() int32 {
+	if x != nil {
+		return x.TicksLeft
+	}
+	return 0
+}
+
+type GameState struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	GameId         string        `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	Width          int32         `protobuf:"varint,2,opt,name=width,proto3" json:"width,omitempty"`
+	Height         int32         `protobuf:"varint,3,opt,name=height,proto3" json:"height,omitempty"`
+	Score          int32         `protobuf:"varint,4,opt,name=score,proto3" json:"score,omitempty"`
+	Fruits         []*Fruit      `protobuf:"bytes,5,rep,name=fruits,proto3" json:"fruits,omitempty"`
+	Snake          *Snake        `protobuf:"bytes,6,opt,name=snake,proto3" json:"snake,omitempty"`
+	FruitSpawn     string        `protobuf:"bytes,7,opt,name=fruit_spawn,json=fruitSpawn,proto3" json:"fruit_spawn,omitempty"`
+	Mode           string        `protobuf:"bytes,8,opt,name=mode,proto3" json:"mode,omitempty"`
+	FruitChances   *FruitChances `protobuf:"bytes,9,opt,name=fruit_chances,json=fruitChances,proto3" json:"fruit_chances,omitempty"`
+	Obstacles      []*Position   `protobuf:"bytes,10,rep,name=obstacles,proto3" json:"obstacles,omitempty"`
+	PowerUps       []*PowerUp    `protobuf:"bytes,11,rep,name=power_ups,json=powerUps,proto3" json:"power_ups,omitempty"`
+	Effects        []*Effect     `protobuf:"bytes,12,rep,name=effects,proto3" json:"effects,omitempty"`
+	PowerUpChance  int32         `protobuf:"varint,13,opt,name=power_up_chance,json=powerUpChance,proto3" json:"power_up_chance,omitempty"`
+	Seed           int64         `protobuf:"varint,14,opt,name=seed,proto3" json:"seed,omitempty"`
+	FruitSpawns    int32         `protobuf:"varint,15,opt,name=fruit_spawns,json=fruitSpawns,proto3" json:"fruit_spawns,omitempty"`
+	TicksPlayed    int32         `protobuf:"varint,16,opt,name=ticks_played,json=ticksPlayed,proto3" json:"ticks_played,omitempty"`
+	TickLimit      int32         `protobuf:"varint,17,opt,name=tick_limit,json=tickLimit,proto3" json:"tick_limit,omitempty"`
+	TtlSeconds     int32         `protobuf:"varint,18,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	Live           bool          `protobuf:"varint,19,opt,name=live,proto3" json:"live,omitempty"`
+	Versus         bool          `protobuf:"varint,20,opt,name=versus,proto3" json:"versus,omitempty"`
+	Opponent       *Snake        `protobuf:"bytes,21,opt,name=opponent,proto3" json:"opponent,omitempty"`
+	OpponentScore  int32         `protobuf:"varint,22,opt,name=opponent_score,json=opponentScore,proto3" json:"opponent_score,omitempty"`
+	Winner         int32         `protobuf:"varint,23,opt,name=winner,proto3" json:"winner,omitempty"`
+	Status         string        `protobuf:"bytes,24,opt,name=status,proto3" json:"status,omitempty"`
+	GameOverReason string        `protobuf:"bytes,25,opt,name=game_over_reason,json=gameOverReason,proto3" json:"game_over_reason,omitempty"`
+}
+
+func (x *GameState) Reset() {
+	*x = GameState{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GameState) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameState) ProtoMessage() {}
+
+func (x *GameState) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameState.ProtoReflect.Descriptor instead.
+func (*GameState) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GameState) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *GameState) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *GameState) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *GameState) GetScore() int32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *GameState) GetFruits() []*Fruit {
+	if x != nil {
+		return x.Fruits
+	}
+	return nil
+}
+
+func (x *GameState) GetSnake() *Snake {
+	if x != nil {
+		return x.Snake
+	}
+	return nil
+}
+
+func (x *GameState) GetFruitSpawn() string {
+	if x != nil {
+		return x.FruitSpawn
+	}
+	return ""
+}
+
+func (x *GameState) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *GameState) GetFruitChances() *FruitChances {
+	if x != nil {
+		return x.FruitChances
+	}
+	return nil
+}
+
+func (x *GameState) GetObstacles() []*Position {
+	if x != nil {
+		return x.Obstacles
+	}
+	return nil
+}
+
+func (x *GameState) GetPowerUps() []*PowerUp {
+	if x != nil {
+		return x.PowerUps
+	}
+	return nil
+}
+
+func (x *GameState) GetEffects() []*Effect {
+	if x != nil {
+		return x.Effects
+	}
+	return nil
+}
+
+func (x *GameState) GetPowerUpChance() int32 {
+	if x != nil {
+		return x.PowerUpChance
+	}
+	return 0
+}
+
+func (x *GameState) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+func (x *GameState) GetFruitSpawns() int32 {
+	if x != nil {
+		return x.FruitSpawns
+	}
+	return 0
+}
+
+func (x *GameState) GetTicksPlayed() int32 {
+	if x != nil {
+		return x.TicksPlayed
+	}
+	return 0
+}
+
+func (x *GameState) GetTickLimit() int32 {
+	if x != nil {
+		return x.TickLimit
+	}
+	return 0
+}
+
+func (x *GameState) GetTtlSeconds() int32 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *GameState) GetLive() bool {
+	if x != nil {
+		return x.Live
+	}
+	return false
+}
+
+func (x *GameState) GetVersus() bool {
+	if x != nil {
+		return x.Versus
+	}
+	return false
+}
+
+func (x *GameState) GetOpponent() *Snake {
+	if x != nil {
+		return x.Opponent
+	}
+	return nil
+}
+
+func (x *GameState) GetOpponentScore() int32 {
+	if x != nil {
+		return x.OpponentScore
+	}
+	return 0
+}
+
+func (x *GameState) GetWinner() int32 {
+	if x != nil {
+		return x.Winner
+	}
+	return 0
+}
+
+func (x *GameState) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *GameState) GetGameOverReason() string {
+	if x != nil {
+		return x.GameOverReason
+	}
+	return ""
+}
+
+type GameConfig struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Width         int32         `protobuf:"varint,1,opt,name=width,proto3" json:"width,omitempty"`
+	Height        int32         `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	FruitSpawn    string        `protobuf:"bytes,3,opt,name=fruit_spawn,json=fruitSpawn,proto3" json:"fruit_spawn,omitempty"`
+	Mode          string        `protobuf:"bytes,4,opt,name=mode,proto3" json:"mode,omitempty"`
+	Live          bool          `protobuf:"varint,5,opt,name=live,proto3" json:"live,omitempty"`
+	Versus        bool          `protobuf:"varint,6,opt,name=versus,proto3" json:"versus,omitempty"`
+	Obstacles     int32         `protobuf:"varint,7,opt,name=obstacles,proto3" json:"obstacles,omitempty"`
+	FruitCount    int32         `protobuf:"varint,8,opt,name=fruit_count,json=fruitCount,proto3" json:"fruit_count,omitempty"`
+	FruitChances  *FruitChances `protobuf:"bytes,9,opt,name=fruit_chances,json=fruitChances,proto3" json:"fruit_chances,omitempty"`
+	PowerUpChance int32         `protobuf:"varint,10,opt,name=power_up_chance,json=powerUpChance,proto3" json:"power_up_chance,omitempty"`
+	TickLimit     int32         `protobuf:"varint,11,opt,name=tick_limit,json=tickLimit,proto3" json:"tick_limit,omitempty"`
+	TtlSeconds    int32         `protobuf:"varint,12,opt,name=ttl_seconds,json=ttlSeconds,proto3" json:"ttl_seconds,omitempty"`
+	Seed          int64         `protobuf:"varint,13,opt,name=seed,proto3" json:"seed,omitempty"`
+}
+
+func (x *GameConfig) Reset() {
+	*x = GameConfig{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GameConfig) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GameConfig) ProtoMessage() {}
+
+func (x *GameConfig) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GameConfig.ProtoReflect.Descriptor instead.
+func (*GameConfig) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GameConfig) GetWidth() int32 {
+	if x != nil {
+		return x.Width
+	}
+	return 0
+}
+
+func (x *GameConfig) GetHeight() int32 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
+func (x *GameConfig) GetFruitSpawn() string {
+	if x != nil {
+		return x.FruitSpawn
+	}
+	return ""
+}
+
+func (x *GameConfig) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *GameConfig) GetLive() bool {
+	if x != nil {
+		return x.Live
+	}
+	return false
+}
+
+func (x *GameConfig) GetVersus() bool {
+	if x != nil {
+		return x.Versus
+	}
+	return false
+}
+
+func (x *GameConfig) GetObstacles() int32 {
+	if x != nil {
+		return x.Obstacles
+	}
+	return 0
+}
+
+func (x *GameConfig) GetFruitCount() int32 {
+	if x != nil {
+		return x.FruitCount
+	}
+	return 0
+}
+
+func (x *GameConfig) GetFruitChances() *FruitChances {
+	if x != nil {
+		return x.FruitChances
+	}
+	return nil
+}
+
+func (x *GameConfig) GetPowerUpChance() int32 {
+	if x != nil {
+		return x.PowerUpChance
+	}
+	return 0
+}
+
+func (x *GameConfig) GetTickLimit() int32 {
+	if x != nil {
+		return x.TickLimit
+	}
+	return 0
+}
+
+func (x *GameConfig) GetTtlSeconds() int32 {
+	if x != nil {
+		return x.TtlSeconds
+	}
+	return 0
+}
+
+func (x *GameConfig) GetSeed() int64 {
+	if x != nil {
+		return x.Seed
+	}
+	return 0
+}
+
+type NewGameRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Config *GameConfig `protobuf:"bytes,1,opt,name=config,proto3" json:"config,omitempty"`
+}
+
+func (x *NewGameRequest) Reset() {
+	*x = NewGameRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *NewGameRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NewGameRequest) ProtoMessage() {}
+
+func (x *NewGameRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NewGameRequest.ProtoReflect.Descriptor instead.
+func (*NewGameRequest) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *NewGameRequest) GetConfig() *GameConfig {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+type ValidateRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	GameId string  `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	Ticks  []*Tick `protobuf:"bytes,2,rep,name=ticks,proto3" json:"ticks,omitempty"`
+}
+
+func (x *ValidateRequest) Reset() {
+	*x = ValidateRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidateRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateRequest) ProtoMessage() {}
+
+func (x *ValidateRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateRequest.ProtoReflect.Descriptor instead.
+func (*ValidateRequest) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ValidateRequest) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *ValidateRequest) GetTicks() []*Tick {
+	if x != nil {
+		return x.Ticks
+	}
+	return nil
+}
+
+// ValidateResponse is the simulated state after a validation. Status is
+// game_over once the snake has died, with the reason in the state.
+type ValidateResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	State        *GameState `protobuf:"bytes,1,opt,name=state,proto3" json:"state,omitempty"`
+	TicksApplied int32      `protobuf:"varint,2,opt,name=ticks_applied,json=ticksApplied,proto3" json:"ticks_applied,omitempty"`
+	Status       string     `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+}
+
+func (x *ValidateResponse) Reset() {
+	*x = ValidateResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ValidateResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateResponse) ProtoMessage() {}
+
+func (x *ValidateResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateResponse.ProtoReflect.Descriptor instead.
+func (*ValidateResponse) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *ValidateResponse) GetState() *GameState {
+	if x != nil {
+		return x.State
+	}
+	return nil
+}
+
+func (x *ValidateResponse) GetTicksApplied() int32 {
+	if x != nil {
+		return x.TicksApplied
+	}
+	return 0
+}
+
+func (x *ValidateResponse) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+type StreamGameRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// GameId and Player are read from the first request; player 2 steers the
+	// opponent of a versus game
+	GameId string `protobuf:"bytes,1,opt,name=game_id,json=gameId,proto3" json:"game_id,omitempty"`
+	Player int32  `protobuf:"varint,2,opt,name=player,proto3" json:"player,omitempty"`
+	Tick   *Tick  `protobuf:"bytes,3,opt,name=tick,proto3" json:"tick,omitempty"`
+}
+
+func (x *StreamGameRequest) Reset() {
+	*x = StreamGameRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamGameRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamGameRequest) ProtoMessage() {}
+
+func (x *StreamGameRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamGameRequest.ProtoReflect.Descriptor instead.
+func (*StreamGameRequest) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *StreamGameRequest) GetGameId() string {
+	if x != nil {
+		return x.GameId
+	}
+	return ""
+}
+
+func (x *StreamGameRequest) GetPlayer() int32 {
+	if x != nil {
+		return x.Player
+	}
+	return 0
+}
+
+func (x *StreamGameRequest) GetTick() *Tick {
+	if x != nil {
+		return x.Tick
+	}
+	return nil
+}
+
+// Error reports a rejected direction change without ending the stream
+type Error struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code    string `protobuf:"bytes,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Error) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type StreamGameResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Event:
+	//	*StreamGameResponse_State
+	//	*StreamGameResponse_Error
+	Event isStreamGameResponse_Event `protobuf_oneof:"event"`
+}
+
+func (x *StreamGameResponse) Reset() {
+	*x = StreamGameResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_snake_v1_snake_proto_msgTypes[14]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *StreamGameResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamGameResponse) ProtoMessage() {}
+
+func (x *StreamGameResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_snake_v1_snake_proto_msgTypes[14]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamGameResponse.ProtoReflect.Descriptor instead.
+func (*StreamGameResponse) Descriptor() ([]byte, []int) {
+	return file_snake_v1_snake_proto_rawDescGZIP(), []int{14}
+}
+
+func (m *StreamGameResponse) GetEvent() isStreamGameResponse_Event {
+	if m != nil {
+		return m.Event
+	}
+	return nil
+}
+
+func (x *StreamGameResponse) GetState() *GameState {
+	if x, ok := x.GetEvent().(*StreamGameResponse_State); ok {
+		return x.State
+	}
+	return nil
+}
+
+func (x *StreamGameResponse) GetError() *Error {
+	if x, ok := x.GetEvent().(*StreamGameResponse_Error); ok {
+		return x.Error
+	}
+	return nil
+}
+
+type isStreamGameResponse_Event interface {
+	isStreamGameResponse_Event()
+}
+
+type StreamGameResponse_State struct {
+	State *GameState `protobuf:"bytes,1,opt,name=state,proto3,oneof"`
+}
+
+type StreamGameResponse_Error struct {
+	Error *Error `protobuf:"bytes,2,opt,name=error,proto3,oneof"`
+}
+
+func (*StreamGameResponse_State) isStreamGameResponse_Event() {}
+
+func (*StreamGameResponse_Error) isStreamGameResponse_Event() {}
+
+var File_snake_v1_snake_proto protoreflect.FileDescriptor
+
+var file_snake_v1_snake_proto_rawDesc = []byte{
+	0x0a, 0x14, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2f, 0x76, 0x31, 0x2f, 0x73, 0x6e, 0x61, 0x6b, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x08, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31,
+	0x22, 0x26, 0x0a, 0x08, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x0c, 0x0a, 0x01,
+	0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x01, 0x79, 0x22, 0x30, 0x0a, 0x04, 0x54, 0x69, 0x63, 0x6b,
+	0x12, 0x13, 0x0a, 0x05, 0x76, 0x65, 0x6c, 0x5f, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x04, 0x76, 0x65, 0x6c, 0x58, 0x12, 0x13, 0x0a, 0x05, 0x76, 0x65, 0x6c, 0x5f, 0x79, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x76, 0x65, 0x6c, 0x59, 0x22, 0x81, 0x01, 0x0a, 0x05, 0x53,
+	0x6e, 0x61, 0x6b, 0x65, 0x12, 0x26, 0x0a, 0x04, 0x68, 0x65, 0x61, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f,
+	0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x04, 0x68, 0x65, 0x61, 0x64, 0x12, 0x13, 0x0a, 0x05,
+	0x76, 0x65, 0x6c, 0x5f, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x76, 0x65, 0x6c,
+	0x58, 0x12, 0x13, 0x0a, 0x05, 0x76, 0x65, 0x6c, 0x5f, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x04, 0x76, 0x65, 0x6c, 0x59, 0x12, 0x26, 0x0a, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x18, 0x04,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e,
+	0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x04, 0x62, 0x6f, 0x64, 0x79, 0x22, 0x4b,
+	0x0a, 0x05, 0x46, 0x72, 0x75, 0x69, 0x74, 0x12, 0x2e, 0x0a, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74,
+	0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73, 0x6e, 0x61, 0x6b,
+	0x65, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x70,
+	0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x22, 0x3e, 0x0a, 0x0c, 0x46,
+	0x72, 0x75, 0x69, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x67,
+	0x6f, 0x6c, 0x64, 0x65, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x67, 0x6f, 0x6c,
+	0x64, 0x65, 0x6e, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x6f, 0x69, 0x73, 0x6f, 0x6e, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x06, 0x70, 0x6f, 0x69, 0x73, 0x6f, 0x6e, 0x22, 0x4d, 0x0a, 0x07, 0x50,
+	0x6f, 0x77, 0x65, 0x72, 0x55, 0x70, 0x12, 0x2e, 0x0a, 0x08, 0x70, 0x6f, 0x73, 0x69, 0x74, 0x69,
+	0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65,
+	0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x08, 0x70, 0x6f,
+	0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x22, 0x3b, 0x0a, 0x06, 0x45, 0x66,
+	0x66, 0x65, 0x63, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x69, 0x63, 0x6b,
+	0x73, 0x5f, 0x6c, 0x65, 0x66, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x74, 0x69,
+	0x63, 0x6b, 0x73, 0x4c, 0x65, 0x66, 0x74, 0x22, 0xd4, 0x06, 0x0a, 0x09, 0x47, 0x61, 0x6d, 0x65,
+	0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x17, 0x0a, 0x07, 0x67, 0x61, 0x6d, 0x65, 0x5f, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x67, 0x61, 0x6d, 0x65, 0x49, 0x64, 0x12, 0x14,
+	0x0a, 0x05, 0x77, 0x69, 0x64, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x77,
+	0x69, 0x64, 0x74, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x14, 0x0a, 0x05,
+	0x73, 0x63, 0x6f, 0x72, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x73, 0x63, 0x6f,
+	0x72, 0x65, 0x12, 0x27, 0x0a, 0x06, 0x66, 0x72, 0x75, 0x69, 0x74, 0x73, 0x18, 0x05, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x72,
+	0x75, 0x69, 0x74, 0x52, 0x06, 0x66, 0x72, 0x75, 0x69, 0x74, 0x73, 0x12, 0x25, 0x0a, 0x05, 0x73,
+	0x6e, 0x61, 0x6b, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61,
+	0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6e, 0x61, 0x6b, 0x65, 0x52, 0x05, 0x73, 0x6e, 0x61,
+	0x6b, 0x65, 0x12, 0x1f, 0x0a, 0x0b, 0x66, 0x72, 0x75, 0x69, 0x74, 0x5f, 0x73, 0x70, 0x61, 0x77,
+	0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x66, 0x72, 0x75, 0x69, 0x74, 0x53, 0x70,
+	0x61, 0x77, 0x6e, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x12, 0x3b, 0x0a, 0x0d, 0x66, 0x72, 0x75, 0x69, 0x74,
+	0x5f, 0x63, 0x68, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16,
+	0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x72, 0x75, 0x69, 0x74, 0x43,
+	0x68, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x52, 0x0c, 0x66, 0x72, 0x75, 0x69, 0x74, 0x43, 0x68, 0x61,
+	0x6e, 0x63, 0x65, 0x73, 0x12, 0x30, 0x0a, 0x09, 0x6f, 0x62, 0x73, 0x74, 0x61, 0x63, 0x6c, 0x65,
+	0x73, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e,
+	0x76, 0x31, 0x2e, 0x50, 0x6f, 0x73, 0x69, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x09, 0x6f, 0x62, 0x73,
+	0x74, 0x61, 0x63, 0x6c, 0x65, 0x73, 0x12, 0x2e, 0x0a, 0x09, 0x70, 0x6f, 0x77, 0x65, 0x72, 0x5f,
+	0x75, 0x70, 0x73, 0x18, 0x0b, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73, 0x6e, 0x61, 0x6b,
+	0x65, 0x2e, 0x76, 0x31, 0x2e, 0x50, 0x6f, 0x77, 0x65, 0x72, 0x55, 0x70, 0x52, 0x08, 0x70, 0x6f,
+	0x77, 0x65, 0x72, 0x55, 0x70, 0x73, 0x12, 0x2a, 0x0a, 0x07, 0x65, 0x66, 0x66, 0x65, 0x63, 0x74,
+	0x73, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e,
+	0x76, 0x31, 0x2e, 0x45, 0x66, 0x66, 0x65, 0x63, 0x74, 0x52, 0x07, 0x65, 0x66, 0x66, 0x65, 0x63,
+	0x74, 0x73, 0x12, 0x26, 0x0a, 0x0f, 0x70, 0x6f, 0x77, 0x65, 0x72, 0x5f, 0x75, 0x70, 0x5f, 0x63,
+	0x68, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x70, 0x6f, 0x77,
+	0x65, 0x72, 0x55, 0x70, 0x43, 0x68, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x65,
+	0x65, 0x64, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04, 0x73, 0x65, 0x65, 0x64, 0x12, 0x21,
+	0x0a, 0x0c, 0x66, 0x72, 0x75, 0x69, 0x74, 0x5f, 0x73, 0x70, 0x61, 0x77, 0x6e, 0x73, 0x18, 0x0f,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x66, 0x72, 0x75, 0x69, 0x74, 0x53, 0x70, 0x61, 0x77, 0x6e,
+	0x73, 0x12, 0x21, 0x0a, 0x0c, 0x74, 0x69, 0x63, 0x6b, 0x73, 0x5f, 0x70, 0x6c, 0x61, 0x79, 0x65,
+	0x64, 0x18, 0x10, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x74, 0x69, 0x63, 0x6b, 0x73, 0x50, 0x6c,
+	0x61, 0x79, 0x65, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x69, 0x63, 0x6b, 0x5f, 0x6c, 0x69, 0x6d,
+	0x69, 0x74, 0x18, 0x11, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x74, 0x69, 0x63, 0x6b, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x74, 0x74, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e,
+	0x64, 0x73, 0x18, 0x12, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x74, 0x74, 0x6c, 0x53, 0x65, 0x63,
+	0x6f, 0x6e, 0x64, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69, 0x76, 0x65, 0x18, 0x13, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x04, 0x6c, 0x69, 0x76, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x76, 0x65, 0x72, 0x73,
+	0x75, 0x73, 0x18, 0x14, 0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x76, 0x65, 0x72, 0x73, 0x75, 0x73,
+	0x12, 0x2b, 0x0a, 0x08, 0x6f, 0x70, 0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x18, 0x15, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x6e,
+	0x61, 0x6b, 0x65, 0x52, 0x08, 0x6f, 0x70, 0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x12, 0x25, 0x0a,
+	0x0e, 0x6f, 0x70, 0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x5f, 0x73, 0x63, 0x6f, 0x72, 0x65, 0x18,
+	0x16, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x6f, 0x70, 0x70, 0x6f, 0x6e, 0x65, 0x6e, 0x74, 0x53,
+	0x63, 0x6f, 0x72, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x77, 0x69, 0x6e, 0x6e, 0x65, 0x72, 0x18, 0x17,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x77, 0x69, 0x6e, 0x6e, 0x65, 0x72, 0x12, 0x16, 0x0a, 0x06,
+	0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x18, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x12, 0x28, 0x0a, 0x10, 0x67, 0x61, 0x6d, 0x65, 0x5f, 0x6f, 0x76, 0x65,
+	0x72, 0x5f, 0x72, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x19, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e,
+	0x67, 0x61, 0x6d, 0x65, 0x4f, 0x76, 0x65, 0x72, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x22, 0x93,
+	0x03, 0x0a, 0x0a, 0x47, 0x61, 0x6d, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x12, 0x14, 0x0a,
+	0x05, 0x77, 0x69, 0x64, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x77, 0x69,
+	0x64, 0x74, 0x68, 0x12, 0x16, 0x0a, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x1f, 0x0a, 0x0b, 0x66,
+	0x72, 0x75, 0x69, 0x74, 0x5f, 0x73, 0x70, 0x61, 0x77, 0x6e, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x0a, 0x66, 0x72, 0x75, 0x69, 0x74, 0x53, 0x70, 0x61, 0x77, 0x6e, 0x12, 0x12, 0x0a, 0x04,
+	0x6d, 0x6f, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65,
+	0x12, 0x12, 0x0a, 0x04, 0x6c, 0x69, 0x76, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04,
+	0x6c, 0x69, 0x76, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x76, 0x65, 0x72, 0x73, 0x75, 0x73, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x06, 0x76, 0x65, 0x72, 0x73, 0x75, 0x73, 0x12, 0x1c, 0x0a, 0x09,
+	0x6f, 0x62, 0x73, 0x74, 0x61, 0x63, 0x6c, 0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x09, 0x6f, 0x62, 0x73, 0x74, 0x61, 0x63, 0x6c, 0x65, 0x73, 0x12, 0x1f, 0x0a, 0x0b, 0x66, 0x72,
+	0x75, 0x69, 0x74, 0x5f, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0a, 0x66, 0x72, 0x75, 0x69, 0x74, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x3b, 0x0a, 0x0d, 0x66,
+	0x72, 0x75, 0x69, 0x74, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x18, 0x09, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x16, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x46, 0x72,
+	0x75, 0x69, 0x74, 0x43, 0x68, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x52, 0x0c, 0x66, 0x72, 0x75, 0x69,
+	0x74, 0x43, 0x68, 0x61, 0x6e, 0x63, 0x65, 0x73, 0x12, 0x26, 0x0a, 0x0f, 0x70, 0x6f, 0x77, 0x65,
+	0x72, 0x5f, 0x75, 0x70, 0x5f, 0x63, 0x68, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28,
+	0x05, 0x52, 0x0d, 0x70, 0x6f, 0x77, 0x65, 0x72, 0x55, 0x70, 0x43, 0x68, 0x61, 0x6e, 0x63, 0x65,
+	0x12, 0x1d, 0x0a, 0x0a, 0x74, 0x69, 0x63, 0x6b, 0x5f, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x0b,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x74, 0x69, 0x63, 0x6b, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12,
+	0x1f, 0x0a, 0x0b, 0x74, 0x74, 0x6c, 0x5f, 0x73, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x0c,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x74, 0x74, 0x6c, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73,
+	0x12, 0x12, 0x0a, 0x04, 0x73, 0x65, 0x65, 0x64, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04,
+	0x73, 0x65, 0x65, 0x64, 0x22, 0x3e, 0x0a, 0x0e, 0x4e, 0x65, 0x77, 0x47, 0x61, 0x6d, 0x65, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2c, 0x0a, 0x06, 0x63, 0x6f, 0x6e, 0x66, 0x69, 0x67,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76,
+	0x31, 0x2e, 0x47, 0x61, 0x6d, 0x65, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x67, 0x52, 0x06, 0x63, 0x6f,
+	0x6e, 0x66, 0x69, 0x67, 0x22, 0x50, 0x0a, 0x0f, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x67, 0x61, 0x6d, 0x65, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x67, 0x61, 0x6d, 0x65, 0x49, 0x64,
+	0x12, 0x24, 0x0a, 0x05, 0x74, 0x69, 0x63, 0x6b, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x0e, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x54, 0x69, 0x63, 0x6b, 0x52,
+	0x05, 0x74, 0x69, 0x63, 0x6b, 0x73, 0x22, 0x7a, 0x0a, 0x10, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61,
+	0x74, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x29, 0x0a, 0x05, 0x73, 0x74,
+	0x61, 0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x73, 0x6e, 0x61, 0x6b,
+	0x65, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x61, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x23, 0x0a, 0x0d, 0x74, 0x69, 0x63, 0x6b, 0x73, 0x5f, 0x61,
+	0x70, 0x70, 0x6c, 0x69, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x74, 0x69,
+	0x63, 0x6b, 0x73, 0x41, 0x70, 0x70, 0x6c, 0x69, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74,
+	0x75, 0x73, 0x22, 0x68, 0x0a, 0x11, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x47, 0x61, 0x6d, 0x65,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x17, 0x0a, 0x07, 0x67, 0x61, 0x6d, 0x65, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x67, 0x61, 0x6d, 0x65, 0x49, 0x64,
+	0x12, 0x16, 0x0a, 0x06, 0x70, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x06, 0x70, 0x6c, 0x61, 0x79, 0x65, 0x72, 0x12, 0x22, 0x0a, 0x04, 0x74, 0x69, 0x63, 0x6b,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76,
+	0x31, 0x2e, 0x54, 0x69, 0x63, 0x6b, 0x52, 0x04, 0x74, 0x69, 0x63, 0x6b, 0x22, 0x35, 0x0a, 0x05,
+	0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73,
+	0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x22, 0x73, 0x0a, 0x12, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x47, 0x61, 0x6d,
+	0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2b, 0x0a, 0x05, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65,
+	0x2e, 0x76, 0x31, 0x2e, 0x47, 0x61, 0x6d, 0x65, 0x53, 0x74, 0x61, 0x74, 0x65, 0x48, 0x00, 0x52,
+	0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x27, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0f, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31,
+	0x2e, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x48, 0x00, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x42,
+	0x07, 0x0a, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x32, 0xd8, 0x01, 0x0a, 0x0c, 0x53, 0x6e, 0x61,
+	0x6b, 0x65, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x38, 0x0a, 0x07, 0x4e, 0x65, 0x77,
+	0x47, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e,
+	0x4e, 0x65, 0x77, 0x47, 0x61, 0x6d, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x13,
+	0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x47, 0x61, 0x6d, 0x65, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x12, 0x41, 0x0a, 0x08, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x12,
+	0x19, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64,
+	0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x73, 0x6e, 0x61,
+	0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x56, 0x61, 0x6c, 0x69, 0x64, 0x61, 0x74, 0x65, 0x52, 0x65,
+	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x4b, 0x0a, 0x0a, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x47, 0x61, 0x6d, 0x65, 0x12, 0x1b, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x47, 0x61, 0x6d, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x1c, 0x2e, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2e, 0x76, 0x31, 0x2e, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x47, 0x61, 0x6d, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28,
+	0x01, 0x30, 0x01, 0x42, 0x3a, 0x5a, 0x38, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f,
+	0x6d, 0x2f, 0x72, 0x6f, 0x64, 0x72, 0x79, 0x67, 0x77, 0x2f, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x2d,
+	0x67, 0x61, 0x6d, 0x65, 0x2d, 0x61, 0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x2f, 0x73,
+	0x6e, 0x61, 0x6b, 0x65, 0x2f, 0x76, 0x31, 0x3b, 0x73, 0x6e, 0x61, 0x6b, 0x65, 0x70, 0x62, 0x62,
+	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_snake_v1_snake_proto_rawDescOnce sync.Once
+	file_snake_v1_snake_proto_rawDescData = file_snake_v1_snake_proto_rawDesc
+)
+
+func file_snake_v1_snake_proto_rawDescGZIP() []byte {
+	file_snake_v1_snake_proto_rawDescOnce.Do(func() {
+		file_snake_v1_snake_proto_rawDescData = protoimpl.X.CompressGZIP(file_snake_v1_snake_proto_rawDescData)
+	})
+	return file_snake_v1_snake_proto_rawDescData
+}
+
+var file_snake_v1_snake_proto_msgTypes = make([]protoimpl.MessageInfo, 15)
+var file_snake_v1_snake_proto_goTypes = []interface{}{
+	(*Position)(nil),           // 0: snake.v1.Position
+	(*Tick)(nil),               // 1: snake.v1.Tick
+	(*Snake)(nil),              // 2: snake.v1.Snake
+	(*Fruit)(nil),              // 3: snake.v1.Fruit
+	(*FruitChances)(nil),       // 4: snake.v1.FruitChances
+	(*PowerUp)(nil),            // 5: snake.v1.PowerUp
+	(*Effect)(nil),             // 6: snake.v1.Effect
+	(*GameState)(nil),          // 7: snake.v1.GameState
+	(*GameConfig)(nil),         // 8: snake.v1.GameConfig
+	(*NewGameRequest)(nil),     // 9: snake.v1.NewGameRequest
+	(*ValidateRequest)(nil),    // 10: snake.v1.ValidateRequest
+	(*ValidateResponse)(nil),   // 11: snake.v1.ValidateResponse
+	(*StreamGameRequest)(nil),  // 12: snake.v1.StreamGameRequest
+	(*Error)(nil),              // 13: snake.v1.Error
+	(*StreamGameResponse)(nil), // 14: snake.v1.StreamGameResponse
+}
+var file_snake_v1_snake_proto_depIdxs = []int32{
+	0,  // 0: snake.v1.Snake.head:type_name -> snake.v1.Position
+	0,  // 1: snake.v1.Snake.body:type_name -> snake.v1.Position
+	0,  // 2: snake.v1.Fruit.position:type_name -> snake.v1.Position
+	0,  // 3: snake.v1.PowerUp.position:type_name -> snake.v1.Position
+	3,  // 4: snake.v1.GameState.fruits:type_name -> snake.v1.Fruit
+	2,  // 5: snake.v1.GameState.snake:type_name -> snake.v1.Snake
+	4,  // 6: snake.v1.GameState.fruit_chances:type_name -> snake.v1.FruitChances
+	0,  // 7: snake.v1.GameState.obstacles:type_name -> snake.v1.Position
+	5,  // 8: snake.v1.GameState.power_ups:type_name -> snake.v1.PowerUp
+	6,  // 9: snake.v1.GameState.effects:type_name -> snake.v1.Effect
+	2,  // 10: snake.v1.GameState.opponent:type_name -> snake.v1.Snake
+	4,  // 11: snake.v1.GameConfig.fruit_chances:type_name -> snake.v1.FruitChances
+	8,  // 12: snake.v1.NewGameRequest.config:type_name -> snake.v1.GameConfig
+	1,  // 13: snake.v1.ValidateRequest.ticks:type_name -> snake.v1.Tick
+	7,  // 14: snake.v1.ValidateResponse.state:type_name -> snake.v1.GameState
+	1,  // 15: snake.v1.StreamGameRequest.tick:type_name -> snake.v1.Tick
+	7,  // 16: snake.v1.StreamGameResponse.state:type_name -> snake.v1.GameState
+	13, // 17: snake.v1.StreamGameResponse.error:type_name -> snake.v1.Error
+	9,  // 18: snake.v1.SnakeService.NewGame:input_type -> snake.v1.NewGameRequest
+	10, // 19: snake.v1.SnakeService.Validate:input_type -> snake.v1.ValidateRequest
+	12, // 20: snake.v1.SnakeService.StreamGame:input_type -> snake.v1.StreamGameRequest
+	7,  // 21: snake.v1.SnakeService.NewGame:output_type -> snake.v1.GameState
+	11, // 22: snake.v1.SnakeService.Validate:output_type -> snake.v1.ValidateResponse
+	14, // 23: snake.v1.SnakeService.StreamGame:output_type -> snake.v1.StreamGameResponse
+	21, // [21:24] is the sub-list for method output_type
+	18, // [18:21] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
+}
+
+func init() { file_snake_v1_snake_proto_init() }
+func file_snake_v1_snake_proto_init() {
+	if File_snake_v1_snake_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_snake_v1_snake_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Position); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Tick); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Snake); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Fruit); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FruitChances); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*PowerUp); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Effect); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GameState); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GameConfig); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*NewGameRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ValidateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ValidateResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamGameRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Error); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_snake_v1_snake_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*StreamGameResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_snake_v1_snake_proto_msgTypes[14].OneofWrappers = []interface{}{
+		(*StreamGameResponse_State)(nil),
+		(*StreamGameResponse_Error)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_snake_v1_snake_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   15,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_snake_v1_snake_proto_goTypes,
+		DependencyIndexes: file_snake_v1_snake_proto_depIdxs,
+		MessageInfos:      file_snake_v1_snake_proto_msgTypes,
+	}.Build()
+	File_snake_v1_snake_proto = out.File
+	file_snake_v1_snake_proto_rawDesc = nil
+	file_snake_v1_snake_proto_goTypes = nil
+	file_snake_v1_snake_proto_depIdxs = nil
+}
@@ -0,0 +1,238 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             v4.25.3
+// source: snake/v1/snake.proto
+
+// The snake game service served over gRPC next to the HTTP API. Messages
+// mirror the JSON bodies of the HTTP endpoints; enums are kept as the same
+// strings the JSON uses.
+//
+// Regenerate the Go package from the proto directory with:
+//
+//   protoc --go_out=. --go_opt=paths=source_relative \
+//     --go-grpc_out=. --go-grpc_opt=paths=source_relative \
+//     snake/v1/snake.proto
+
+package snakepb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	SnakeService_NewGame_FullMethodName    = "/snake.v1.SnakeService/NewGame"
+	SnakeService_Validate_FullMethodName   = "/snake.v1.SnakeService/Validate"
+	SnakeService_StreamGame_FullMethodName = "/snake.v1.SnakeService/StreamGame"
+)
+
+// SnakeServiceClient is the client API for SnakeService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type SnakeServiceClient interface {
+	// NewGame creates a game, like POST /v1/games
+	NewGame(ctx context.Context, in *NewGameRequest, opts ...grpc.CallOption) (*GameState, error)
+	// Validate applies ticks to a stored game, like POST /v1/games/{id}/validate
+	Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error)
+	// StreamGame plays a live game in real time, like the WebSocket endpoint.
+	// The first request names the game and player; every request may carry a
+	// direction change. The server pushes the state after every tick and ends
+	// the stream once the game is over.
+	StreamGame(ctx context.Context, opts ...grpc.CallOption) (SnakeService_StreamGameClient, error)
+}
+
+type snakeServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSnakeServiceClient(cc grpc.ClientConnInterface) SnakeServiceClient {
+	return &snakeServiceClient{cc}
+}
+
+func (c *snakeServiceClient) NewGame(ctx context.Context, in *NewGameRequest, opts ...grpc.CallOption) (*GameState, error) {
+	out := new(GameState)
+	err := c.cc.Invoke(ctx, SnakeService_NewGame_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *snakeServiceClient) Validate(ctx context.Context, in *ValidateRequest, opts ...grpc.CallOption) (*ValidateResponse, error) {
+	out := new(ValidateResponse)
+	err := c.cc.Invoke(ctx, SnakeService_Validate_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *snakeServiceClient) StreamGame(ctx context.Context, opts ...grpc.CallOption) (SnakeService_StreamGameClient, error) {
+	stream, err := c.cc.NewStream(ctx, &SnakeService_ServiceDesc.Streams[0], SnakeService_StreamGame_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &snakeServiceStreamGameClient{stream}
+	return x, nil
+}
+
+type SnakeService_StreamGameClient interface {
+	Send(*StreamGameRequest) error
+	Recv() (*StreamGameResponse, error)
+	grpc.ClientStream
+}
+
+type snakeServiceStreamGameClient struct {
+	grpc.ClientStream
+}
+
+func (x *snakeServiceStreamGameClient) Send(m *StreamGameRequest) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *snakeServiceStreamGameClient) Recv() (*StreamGameResponse, error) {
+	m := new(StreamGameResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SnakeServiceServer is the server API for SnakeService service.
+// All implementations must embed UnimplementedSnakeServiceServer
+// for forward compatibility
+type SnakeServiceServer interface {
+	// NewGame creates a game, like POST /v1/games
+	NewGame(context.Context, *NewGameRequest) (*GameState, error)
+	// Validate applies ticks to a stored game, like POST /v1/games/{id}/validate
+	Validate(context.Context, *ValidateRequest) (*ValidateResponse, error)
+	// StreamGame plays a live game in real time, like the WebSocket endpoint.
+	// The first request names the game and player; every request may carry a
+	// direction change. The server pushes the state after every tick and ends
+	// the stream once the game is over.
+	StreamGame(SnakeService_StreamGameServer) error
+	mustEmbedUnimplementedSnakeServiceServer()
+}
+
+// UnimplementedSnakeServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedSnakeServiceServer struct {
+}
+
+func (UnimplementedSnakeServiceServer) NewGame(context.Context, *NewGameRequest) (*GameState, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method NewGame not implemented")
+}
+func (UnimplementedSnakeServiceServer) Validate(context.Context, *ValidateRequest) (*ValidateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Validate not implemented")
+}
+func (UnimplementedSnakeServiceServer) StreamGame(SnakeService_StreamGameServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamGame not implemented")
+}
+func (UnimplementedSnakeServiceServer) mustEmbedUnimplementedSnakeServiceServer() {}
+
+// UnsafeSnakeServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SnakeServiceServer will
+// result in compilation errors.
+type UnsafeSnakeServiceServer interface {
+	mustEmbedUnimplementedSnakeServiceServer()
+}
+
+func RegisterSnakeServiceServer(s grpc.ServiceRegistrar, srv SnakeServiceServer) {
+	s.RegisterService(&SnakeService_ServiceDesc, srv)
+}
+
+func _SnakeService_NewGame_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(NewGameRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnakeServiceServer).NewGame(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SnakeService_NewGame_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnakeServiceServer).NewGame(ctx, req.(*NewGameRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SnakeService_Validate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SnakeServiceServer).Validate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SnakeService_Validate_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SnakeServiceServer).Validate(ctx, req.(*ValidateRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SnakeService_StreamGame_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(SnakeServiceServer).StreamGame(&snakeServiceStreamGameServer{stream})
+}
+
+type SnakeService_StreamGameServer interface {
+	Send(*StreamGameResponse) error
+	Recv() (*StreamGameRequest, error)
+	grpc.ServerStream
+}
+
+type snakeServiceStreamGameServer struct {
+	grpc.ServerStream
+}
+
+func (x *snakeServiceStreamGameServer) Send(m *StreamGameResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *snakeServiceStreamGameServer) Recv() (*StreamGameRequest, error) {
+	m := new(StreamGameRequest)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// SnakeService_ServiceDesc is the grpc.ServiceDesc for SnakeService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SnakeService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "snake.v1.SnakeService",
+	HandlerType: (*SnakeServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "NewGame",
+			Handler:    _SnakeService_NewGame_Handler,
+		},
+		{
+			MethodName: "Validate",
+			Handler:    _SnakeService_Validate_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamGame",
+			Handler:       _SnakeService_StreamGame_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
+	Metadata: "snake/v1/snake.proto",
+}
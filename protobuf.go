@@ -0,0 +1,171 @@
+package main
+
+import (
+	"io"
+	"mime"
+	"net/http"
+
+	"google.golang.org/protobuf/proto"
+
+	snakepb "github.com/rodrygw/snake-game-api/proto/snake/v1"
+)
+
+// protobufMediaType selects the messages of proto/snake/v1 instead of JSON
+// as the body of /new and /validate requests and responses
+const protobufMediaType = "application/x-protobuf"
+
+// isProtobuf returns true if the request body is a protobuf message
+func isProtobuf(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == protobufMediaType
+}
+
+// wantsProtobuf returns true if the Accept header asks for a protobuf
+// response. Signed states are only exchanged as JSON in stateless mode.
+func wantsProtobuf(r *http.Request) bool {
+	if signingKey != nil {
+		return false
+	}
+	for _, mediaType := range acceptedTypes(r) {
+		if mediaType == protobufMediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// protoResponse writes a protobuf response with the given status code
+func protoResponse(w http.ResponseWriter, m proto.Message, statusCode int) {
+	body, err := proto.Marshal(m)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Encoding response failed")
+		return
+	}
+	w.Header().Set("Content-Type", protobufMediaType)
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// decodeProtoValidateRequest reads a protobuf ValidateRequest body
+func decodeProtoValidateRequest(r *http.Request) (ValidateRequest, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return ValidateRequest{}, err
+	}
+	var m snakepb.ValidateRequest
+	if err := proto.Unmarshal(body, &m); err != nil {
+		return ValidateRequest{}, err
+	}
+
+	req := ValidateRequest{GameID: m.GetGameId(), Ticks: make([]Tick, len(m.GetTicks()))}
+	for i, tick := range m.GetTicks() {
+		req.Ticks[i] = tickFromProto(tick)
+	}
+	return req, nil
+}
+
+// validateResponseToProto converts a ValidateResponse into its message
+func validateResponseToProto(response ValidateResponse) *snakepb.ValidateResponse {
+	return &snakepb.ValidateResponse{
+		State:        gameStateToProto(response.GameState),
+		TicksApplied: int32(response.TicksApplied),
+		Status:       string(response.Status),
+	}
+}
+
+func gameConfigFromProto(config *snakepb.GameConfig) GameConfig {
+	return GameConfig{
+		Width:      int(config.GetWidth()),
+		Height:     int(config.GetHeight()),
+		FruitSpawn: FruitSpawn(config.GetFruitSpawn()),
+		Mode:       GameMode(config.GetMode()),
+		Live:       config.GetLive(),
+		Versus:     config.GetVersus(),
+		Obstacles:  int(config.GetObstacles()),
+		FruitCount: int(config.GetFruitCount()),
+		FruitChances: FruitChances{
+			Golden: int(config.GetFruitChances().GetGolden()),
+			Poison: int(config.GetFruitChances().GetPoison()),
+		},
+		PowerUpChance: int(config.GetPowerUpChance()),
+		TickLimit:     int(config.GetTickLimit()),
+		TTLSeconds:    int(config.GetTtlSeconds()),
+		Seed:          config.GetSeed(),
+	}
+}
+
+func tickFromProto(tick *snakepb.Tick) Tick {
+	return Tick{VelX: int(tick.GetVelX()), VelY: int(tick.GetVelY())}
+}
+
+func positionToProto(pos Position) *snakepb.Position {
+	return &snakepb.Position{X: int32(pos.X), Y: int32(pos.Y)}
+}
+
+func positionsToProto(positions []Position) []*snakepb.Position {
+	out := make([]*snakepb.Position, len(positions))
+	for i, pos := range positions {
+		out[i] = positionToProto(pos)
+	}
+	return out
+}
+
+func snakeToProto(snake Snake) *snakepb.Snake {
+	return &snakepb.Snake{
+		Head: positionToProto(snake.Position),
+		VelX: int32(snake.VelX),
+		VelY: int32(snake.VelY),
+		Body: positionsToProto(snake.Body),
+	}
+}
+
+func gameStateToProto(state GameState) *snakepb.GameState {
+	out := &snakepb.GameState{
+		GameId:     state.GameID,
+		Width:      int32(state.Width),
+		Height:     int32(state.Height),
+		Score:      int32(state.Score),
+		Snake:      snakeToProto(state.Snake),
+		FruitSpawn: string(state.FruitSpawn),
+		Mode:       string(state.Mode),
+		FruitChances: &snakepb.FruitChances{
+			Golden: int32(state.FruitChances.Golden),
+			Poison: int32(state.FruitChances.Poison),
+		},
+		Obstacles:      positionsToProto(state.Obstacles),
+		PowerUpChance:  int32(state.PowerUpChance),
+		Seed:           state.Seed,
+		FruitSpawns:    int32(state.FruitSpawns),
+		TicksPlayed:    int32(state.TicksPlayed),
+		TickLimit:      int32(state.TickLimit),
+		TtlSeconds:     int32(state.TTLSeconds),
+		Live:           state.Live,
+		Versus:         state.Versus,
+		OpponentScore:  int32(state.OpponentScore),
+		Winner:         int32(state.Winner),
+		Status:         string(state.Status),
+		GameOverReason: string(state.GameOverReason),
+	}
+	for _, fruit := range state.Fruits {
+		out.Fruits = append(out.Fruits, &snakepb.Fruit{
+			Position: positionToProto(fruit.Position),
+			Type:     string(fruit.Type),
+		})
+	}
+	for _, powerUp := range state.PowerUps {
+		out.PowerUps = append(out.PowerUps, &snakepb.PowerUp{
+			Position: positionToProto(powerUp.Position),
+			Type:     string(powerUp.Type),
+		})
+	}
+	for _, effect := range state.Effects {
+		out.Effects = append(out.Effects, &snakepb.Effect{
+			Type:      string(effect.Type),
+			TicksLeft: int32(effect.TicksLeft),
+		})
+	}
+	if state.Opponent != nil {
+		out.Opponent = snakeToProto(*state.Opponent)
+	}
+	return out
+}
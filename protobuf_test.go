@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+
+	snakepb "github.com/rodrygw/snake-game-api/proto/snake/v1"
+)
+
+func TestProtobufValidate(t *testing.T) {
+	h := newTestAPI(t)
+	game := newGame(t, h, "", GameConfig{Width: 10, Height: 10})
+
+	body, err := proto.Marshal(&snakepb.ValidateRequest{Ticks: []*snakepb.Tick{{VelY: 1}, {VelX: 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/games/"+game.GameID+"/validate", bytes.NewReader(body))
+	req.Header.Set("Content-Type", protobufMediaType)
+	req.Header.Set("Accept", protobufMediaType)
+	rec := send(h, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var resp snakepb.ValidateResponse
+	if err := proto.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetTicksApplied() != 2 {
+		t.Errorf("ticksApplied = %d, want 2", resp.GetTicksApplied())
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// PartitionRank is where the best score of a player ranks among the
+	// best scores of every player in a leaderboard partition. Percentile is
+	// the percentage of those players the player ties or beats.
+	PartitionRank struct {
+		Board      string  `json:"board"`
+		Difficulty string  `json:"difficulty"`
+		Rules      string  `json:"rules"`
+		Best       int     `json:"best"`
+		Rank       int     `json:"rank"`
+		Players    int     `json:"players"`
+		Percentile float64 `json:"percentile"`
+	}
+
+	// PlayerRankResponse holds the rank of a player in every partition they
+	// have a score in
+	PlayerRankResponse struct {
+		Player     string          `json:"player"`
+		Partitions []PartitionRank `json:"partitions"`
+	}
+)
+
+// openRanks creates the tables ranks are read from in db if needed. They are
+// kept up to date as scores are added: rank_bests holds the best score of
+// every player in each partition, and rank_counts how many players have
+// each best score, so a rank only sums the counts of the better scores.
+// Scores recorded before the tables existed are counted once.
+func openRanks(db *sql.DB) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS rank_bests (
+			width      INTEGER NOT NULL,
+			height     INTEGER NOT NULL,
+			difficulty TEXT NOT NULL,
+			rules      TEXT NOT NULL,
+			player     TEXT NOT NULL,
+			score      INTEGER NOT NULL,
+			PRIMARY KEY (width, height, difficulty, rules, player)
+		);
+		CREATE INDEX IF NOT EXISTS rank_bests_by_player ON rank_bests (player);
+		CREATE TABLE IF NOT EXISTS rank_counts (
+			width      INTEGER NOT NULL,
+			height     INTEGER NOT NULL,
+			difficulty TEXT NOT NULL,
+			rules      TEXT NOT NULL,
+			score      INTEGER NOT NULL,
+			players    INTEGER NOT NULL,
+			PRIMARY KEY (width, height, difficulty, rules, score)
+		);
+	`)
+	if err != nil {
+		return err
+	}
+
+	var ranked bool
+	if err := db.QueryRow(`SELECT EXISTS (SELECT 1 FROM rank_bests)`).Scan(&ranked); err != nil || ranked {
+		return err
+	}
+	_, err = db.Exec(`
+		INSERT INTO rank_bests (width, height, difficulty, rules, player, score)
+		SELECT width, height, difficulty, rules, player, MAX(score) FROM scores
+		WHERE NOT anonymous GROUP BY width, height, difficulty, rules, player;
+		INSERT INTO rank_counts (width, height, difficulty, rules, score, players)
+		SELECT width, height, difficulty, rules, score, COUNT(*) FROM rank_bests
+		GROUP BY width, height, difficulty, rules, score;
+	`)
+	return err
+}
+
+// addRank counts a score towards the ranks of its partition, as part of the
+// transaction recording it on the leaderboard. Anonymous scores have no
+// player to rank and only the best score of a player counts.
+func addRank(tx *sql.Tx, entry ScoreEntry) error {
+	if entry.Anonymous {
+		return nil
+	}
+	partition := []any{entry.Width, entry.Height, entry.Difficulty, entry.Rules}
+	var best int
+	err := tx.QueryRow(`SELECT score FROM rank_bests
+		WHERE width = ? AND height = ? AND difficulty = ? AND rules = ? AND player = ?`,
+		append(partition, entry.Player)...).Scan(&best)
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return err
+	case entry.Score <= best:
+		return nil
+	default:
+		_, err = tx.Exec(`UPDATE rank_counts SET players = players - 1
+			WHERE width = ? AND height = ? AND difficulty = ? AND rules = ? AND score = ?`,
+			append(partition, best)...)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`INSERT INTO rank_bests (width, height, difficulty, rules, player, score)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT DO UPDATE SET score = excluded.score`,
+		append(partition, entry.Player, entry.Score)...)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO rank_counts (width, height, difficulty, rules, score, players)
+		VALUES (?, ?, ?, ?, ?, 1)
+		ON CONFLICT DO UPDATE SET players = players + 1`,
+		append(partition, entry.Score)...)
+	return err
+}
+
+// ranks returns the rank of a player in every partition they have a score
+// in, ordered like the leaderboard index
+func (l *leaderboard) ranks(player string) ([]PartitionRank, error) {
+	rows, err := l.db.Query(`
+		SELECT b.width, b.height, b.difficulty, b.rules, b.score,
+			COALESCE(SUM(c.players) FILTER (WHERE c.score > b.score), 0), COALESCE(SUM(c.players), 0)
+		FROM rank_bests b JOIN rank_counts c USING (width, height, difficulty, rules)
+		WHERE b.player = ?
+		GROUP BY b.width, b.height, b.difficulty, b.rules
+		ORDER BY b.width, b.height, b.difficulty, b.rules`, player)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ranks := []PartitionRank{}
+	for rows.Next() {
+		var rank PartitionRank
+		var width, height, ahead int
+		err := rows.Scan(&width, &height, &rank.Difficulty, &rank.Rules, &rank.Best, &ahead, &rank.Players)
+		if err != nil {
+			return nil, err
+		}
+		rank.Board = fmt.Sprintf("%dx%d", width, height)
+		rank.Rank = ahead + 1
+		rank.Percentile = 100 * float64(rank.Players-ahead) / float64(rank.Players)
+		ranks = append(ranks, rank)
+	}
+	return ranks, rows.Err()
+}
+
+// playerRankHandler returns the rank of a player in every leaderboard
+// partition they have a score in
+func playerRankHandler(w http.ResponseWriter, r *http.Request) {
+	player, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil || player == "" || len(player) > maxPlayerNameLength {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+			"Player name must be between 1 and %d characters", maxPlayerNameLength))
+		return
+	}
+
+	ranks, err := scores.ranks(player)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load ranks")
+		return
+	}
+	jsonResponse(w, PlayerRankResponse{Player: player, Partitions: ranks})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPlayerRank(t *testing.T) {
+	h := newTestAPI(t)
+	for _, entry := range []ScoreEntry{
+		{Player: "alice", Score: 9, Width: 5, Height: 5},
+		{Player: "bob", Score: 10, Width: 5, Height: 5},
+		{Player: "alice", Score: 12, Width: 5, Height: 5},
+		{Player: "alice", Score: 7, Width: 5, Height: 5},
+		{Player: "carol", Score: 3, Width: 5, Height: 5},
+		{Player: "dave", Score: 50, Width: 5, Height: 5, Anonymous: true},
+		{Player: "alice", Score: 1, Width: 10, Height: 10},
+	} {
+		entry.GameID, entry.CreatedAt = uuid.NewString(), time.Now().UTC()
+		entry.Difficulty, entry.Rules = DifficultySolo, "classic"
+		if err := scores.add(entry, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	check := func(t *testing.T) {
+		tests := []struct {
+			player string
+			want   []PartitionRank
+		}{
+			{player: "alice", want: []PartitionRank{
+				{Board: "5x5", Best: 12, Rank: 1, Players: 3, Percentile: 100},
+				{Board: "10x10", Best: 1, Rank: 1, Players: 1, Percentile: 100},
+			}},
+			{player: "bob", want: []PartitionRank{{Board: "5x5", Best: 10, Rank: 2, Players: 3, Percentile: 200.0 / 3}}},
+			{player: "carol", want: []PartitionRank{{Board: "5x5", Best: 3, Rank: 3, Players: 3, Percentile: 100.0 / 3}}},
+			{player: "dave", want: []PartitionRank{}},
+		}
+		for _, tt := range tests {
+			var resp PlayerRankResponse
+			decode(t, do(t, h, http.MethodGet, "/v1/players/"+tt.player+"/rank", "", nil), &resp)
+			if len(resp.Partitions) != len(tt.want) {
+				t.Fatalf("ranks of %s = %+v, want %+v", tt.player, resp.Partitions, tt.want)
+			}
+			for i, want := range tt.want {
+				want.Difficulty, want.Rules = DifficultySolo, "classic"
+				if resp.Partitions[i] != want {
+					t.Errorf("rank of %s = %+v, want %+v", tt.player, resp.Partitions[i], want)
+				}
+			}
+		}
+	}
+	t.Run("incremental", check)
+
+	// Ranks of scores recorded before the rank tables are counted on open
+	if _, err := scores.db.Exec(`DELETE FROM rank_bests; DELETE FROM rank_counts`); err != nil {
+		t.Fatal(err)
+	}
+	if err := openRanks(scores.db); err != nil {
+		t.Fatal(err)
+	}
+	t.Run("backfilled", check)
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// eloK is how many rating points a single game moves at most
+const eloK = 32
+
+type (
+	// PlayerRating is the Elo rating of a player with the versus games it is
+	// based on. Players who have not finished a rated game yet have the
+	// default rating.
+	PlayerRating struct {
+		Player    string     `json:"player"`
+		Rating    int        `json:"rating"`
+		Games     int        `json:"games"`
+		Wins      int        `json:"wins"`
+		Losses    int        `json:"losses"`
+		Draws     int        `json:"draws"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	}
+
+	RatingsResponse struct {
+		Ratings []PlayerRating `json:"ratings"`
+	}
+)
+
+// ratingStore persists player ratings next to the leaderboard
+type ratingStore struct {
+	db *sql.DB
+}
+
+// ratings is the server-wide rating store, opened in main
+var ratings *ratingStore
+
+// openRatings creates the rating tables in db if needed. rated_games keeps
+// every game that has been rated, so no game counts twice.
+func openRatings(db *sql.DB) (*ratingStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS ratings (
+			player     TEXT PRIMARY KEY,
+			rating     REAL NOT NULL,
+			games      INTEGER NOT NULL,
+			wins       INTEGER NOT NULL,
+			losses     INTEGER NOT NULL,
+			draws      INTEGER NOT NULL,
+			updated_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS ratings_by_rating ON ratings (rating DESC);
+		CREATE TABLE IF NOT EXISTS rated_games (
+			game_id TEXT PRIMARY KEY
+		);
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return &ratingStore{db: db}, nil
+}
+
+// storedRating is a row of the ratings table, with the rating unrounded
+type storedRating struct {
+	rating                     float64
+	games, wins, losses, draws int
+	updatedAt                  int64
+}
+
+// rowQuerier is a *sql.DB or a *sql.Tx
+type rowQuerier interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// loadRating reads the rating of a player, or the default rating if it has
+// none
+func loadRating(q rowQuerier, player string) (storedRating, error) {
+	stored := storedRating{rating: defaultRating}
+	err := q.QueryRow(`SELECT rating, games, wins, losses, draws, updated_at FROM ratings WHERE player = ?`, player).
+		Scan(&stored.rating, &stored.games, &stored.wins, &stored.losses, &stored.draws, &stored.updatedAt)
+	if err == sql.ErrNoRows {
+		return stored, nil
+	}
+	return stored, err
+}
+
+// get returns the rating of a player
+func (s *ratingStore) get(player string) (PlayerRating, error) {
+	stored, err := loadRating(s.db, player)
+	if err != nil {
+		return PlayerRating{}, err
+	}
+	rating := PlayerRating{
+		Player: player,
+		Rating: int(math.Round(stored.rating)),
+		Games:  stored.games,
+		Wins:   stored.wins,
+		Losses: stored.losses,
+		Draws:  stored.draws,
+	}
+	if stored.games > 0 {
+		updatedAt := time.UnixMilli(stored.updatedAt).UTC()
+		rating.UpdatedAt = &updatedAt
+	}
+	return rating, nil
+}
+
+// record updates the ratings of the two players of a finished game that
+// winner, 1 or 2 or 0 for a draw, decided. Games already rated and games a
+// player played against itself are ignored.
+func (s *ratingStore) record(gameID, player1, player2 string, winner int) error {
+	if player1 == player2 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`INSERT INTO rated_games (game_id) VALUES (?) ON CONFLICT DO NOTHING`, gameID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil || n == 0 {
+		return err
+	}
+
+	first, err := loadRating(tx, player1)
+	if err != nil {
+		return err
+	}
+	second, err := loadRating(tx, player2)
+	if err != nil {
+		return err
+	}
+
+	// Elo: each player gains K times how much better it did than its
+	// expected score against the other's rating
+	score := map[int]float64{0: 0.5, 1: 1, 2: 0}[winner]
+	expected := 1 / (1 + math.Pow(10, (second.rating-first.rating)/400))
+	delta := eloK * (score - expected)
+	first.rating += delta
+	second.rating -= delta
+	switch winner {
+	case 0:
+		first.draws++
+		second.draws++
+	case 1:
+		first.wins++
+		second.losses++
+	case 2:
+		first.losses++
+		second.wins++
+	}
+
+	now := time.Now().UnixMilli()
+	for player, stored := range map[string]storedRating{player1: first, player2: second} {
+		stored.games++
+		_, err := tx.Exec(
+			`INSERT INTO ratings (player, rating, games, wins, losses, draws, updated_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT (player) DO UPDATE SET
+				rating = excluded.rating, games = excluded.games, wins = excluded.wins,
+				losses = excluded.losses, draws = excluded.draws, updated_at = excluded.updated_at`,
+			player, stored.rating, stored.games, stored.wins, stored.losses, stored.draws, now)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// top returns the n highest rated players
+func (s *ratingStore) top(n int) ([]PlayerRating, error) {
+	rows, err := s.db.Query(
+		`SELECT player, rating, games, wins, losses, draws, updated_at
+		 FROM ratings ORDER BY rating DESC, player LIMIT ?`, n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []PlayerRating{}
+	for rows.Next() {
+		var entry PlayerRating
+		var rating float64
+		var updatedAt int64
+		err := rows.Scan(&entry.Player, &rating, &entry.Games, &entry.Wins,
+			&entry.Losses, &entry.Draws, &updatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entry.Rating = int(math.Round(rating))
+		at := time.UnixMilli(updatedAt).UTC()
+		entry.UpdatedAt = &at
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// rateGame updates the ratings of the players of a versus game once it is
+// over. Only games between two different accounts are rated: the owner as
+// the snake, and the account that joined or was matched with it as the
+// opponent. Games with an anonymous player or against a bot are not.
+func rateGame(state GameState) {
+	if !state.Versus || state.GameOverReason == "" || state.Bot != nil || len(state.Players) != 2 {
+		return
+	}
+	if state.Owner == "" || state.Players[0] != state.Owner || state.Players[1] == "" ||
+		state.Players[1] == state.Owner {
+		return
+	}
+	if err := ratings.record(state.GameID, state.Players[0], state.Players[1], state.Winner); err != nil {
+		log.Printf("rating game %s: %v", state.GameID, err)
+	}
+}
+
+// playerRatingHandler returns the rating of a player
+func playerRatingHandler(w http.ResponseWriter, r *http.Request) {
+	player, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil || player == "" || len(player) > maxPlayerNameLength {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+			"Player name must be between 1 and %d characters", maxPlayerNameLength))
+		return
+	}
+
+	rating, err := ratings.get(player)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load rating")
+		return
+	}
+	jsonResponse(w, rating)
+}
+
+// ratingsHandler returns the highest rated players, ?limit=N of them
+func ratingsHandler(w http.ResponseWriter, r *http.Request) {
+	limit := defaultLeaderboardSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		var err error
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > maxLeaderboardSize {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+				"limit must be between 1 and %d", maxLeaderboardSize))
+			return
+		}
+	}
+
+	entries, err := ratings.top(limit)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load ratings")
+		return
+	}
+	jsonResponse(w, RatingsResponse{Ratings: entries})
+}
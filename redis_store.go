@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// redisKeyPrefix namespaces game keys in a shared Redis database
+	redisKeyPrefix = "snake:game:"
+	// redisReplayPrefix namespaces replays; {id}:initial holds the initial
+	// state and {id}:ticks a list of recorded ticks
+	redisReplayPrefix = "snake:replay:"
+	// redisMaxRetries bounds optimistic-lock retries in Update
+	redisMaxRetries = 10
+)
+
+var errTooManyConflicts = errors.New("game updated concurrently too many times")
+
+// redisStore keeps games in Redis so they survive restarts and can be shared
+// by several API replicas
+type redisStore struct {
+	client *redis.Client
+}
+
+// newRedisStore connects to the Redis server described by the given URL,
+// e.g. redis://localhost:6379/0
+func newRedisStore(ctx context.Context, url string) (*redisStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+
+	client := redis.NewClient(opts)
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return &redisStore{client: client}, nil
+}
+
+func (s *redisStore) Get(ctx context.Context, gameID string) (GameState, error) {
+	return s.get(ctx, s.client, gameID)
+}
+
+func (s *redisStore) Save(ctx context.Context, state GameState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		ttl := expiresIn(state, clock())
+		pipe.Set(ctx, redisKeyPrefix+state.GameID, data, ttl)
+		pipe.Set(ctx, redisReplayPrefix+state.GameID+":initial", data, ttl)
+		pipe.Del(ctx, redisReplayPrefix+state.GameID+":ticks")
+		return nil
+	})
+	return err
+}
+
+func (s *redisStore) Update(ctx context.Context, gameID string, fn func(GameState) GameState) (GameState, error) {
+	return s.UpdateRecorded(ctx, gameID, func(state GameState) (GameState, []ReplayTick) {
+		return fn(state), nil
+	})
+}
+
+// UpdateRecorded uses WATCH/MULTI so concurrent updates from any replica
+// retry instead of overwriting each other, and replay ticks are appended in
+// the order the updates were applied
+func (s *redisStore) UpdateRecorded(ctx context.Context, gameID string, fn func(GameState) (GameState, []ReplayTick)) (GameState, error) {
+	key := redisKeyPrefix + gameID
+
+	var state GameState
+	for i := 0; i < redisMaxRetries; i++ {
+		err := s.client.Watch(ctx, func(tx *redis.Tx) error {
+			current, err := s.get(ctx, tx, gameID)
+			if err != nil {
+				return err
+			}
+
+			var ticks []ReplayTick
+			state, ticks = fn(current)
+			state = nextVersion(current, state)
+			data, err := json.Marshal(state)
+			if err != nil {
+				return err
+			}
+			encodedTicks := make([]any, len(ticks))
+			for i, tick := range ticks {
+				if encodedTicks[i], err = json.Marshal(tick); err != nil {
+					return err
+				}
+			}
+
+			_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+				ttl := expiresIn(state, clock())
+				pipe.Set(ctx, key, data, ttl)
+				pipe.PExpire(ctx, redisReplayPrefix+gameID+":initial", ttl)
+				if len(encodedTicks) > 0 {
+					pipe.RPush(ctx, redisReplayPrefix+gameID+":ticks", encodedTicks...)
+					pipe.PExpire(ctx, redisReplayPrefix+gameID+":ticks", ttl)
+				}
+				return nil
+			})
+			return err
+		}, key)
+
+		if err == redis.TxFailedErr {
+			continue
+		}
+		return state, err
+	}
+
+	return GameState{}, errTooManyConflicts
+}
+
+func (s *redisStore) Replay(ctx context.Context, gameID string) (Replay, error) {
+	initial, err := s.client.Get(ctx, redisReplayPrefix+gameID+":initial").Bytes()
+	if err == redis.Nil {
+		return Replay{}, errGameNotFound
+	}
+	if err != nil {
+		return Replay{}, err
+	}
+
+	replay := Replay{GameID: gameID}
+	if err := json.Unmarshal(initial, &replay.Initial); err != nil {
+		return Replay{}, err
+	}
+
+	encodedTicks, err := s.client.LRange(ctx, redisReplayPrefix+gameID+":ticks", 0, -1).Result()
+	if err != nil {
+		return Replay{}, err
+	}
+	replay.Ticks = make([]ReplayTick, len(encodedTicks))
+	for i, encoded := range encodedTicks {
+		if err := json.Unmarshal([]byte(encoded), &replay.Ticks[i]); err != nil {
+			return Replay{}, err
+		}
+	}
+
+	return replay, nil
+}
+
+// get loads and decodes a game using the given client or transaction
+func (s *redisStore) get(ctx context.Context, c redis.Cmdable, gameID string) (GameState, error) {
+	data, err := c.Get(ctx, redisKeyPrefix+gameID).Bytes()
+	if err == redis.Nil {
+		return GameState{}, errGameNotFound
+	}
+	if err != nil {
+		return GameState{}, err
+	}
+
+	var state GameState
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+func (s *redisStore) Delete(ctx context.Context, gameID string) error {
+	var deleted *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		deleted = pipe.Del(ctx, redisKeyPrefix+gameID)
+		pipe.Del(ctx, redisReplayPrefix+gameID+":initial", redisReplayPrefix+gameID+":ticks")
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if deleted.Val() == 0 {
+		return errGameNotFound
+	}
+	return nil
+}
+
+func (s *redisStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Reap removes nothing: every key is written with the game's TTL, so Redis
+// expires abandoned games itself
+func (s *redisStore) Reap(context.Context, time.Time) (int, error) {
+	return 0, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+// conflictingStore changes a game with conflict while the first
+// UpdateRecorded on it runs, so that Redis retries fn
+type conflictingStore struct {
+	*redisStore
+	conflict func(GameState) GameState
+}
+
+func (s *conflictingStore) UpdateRecorded(ctx context.Context, gameID string, fn func(GameState) (GameState, []ReplayTick)) (GameState, error) {
+	return s.redisStore.UpdateRecorded(ctx, gameID, func(state GameState) (GameState, []ReplayTick) {
+		newState, ticks := fn(state)
+		if s.conflict != nil {
+			if _, err := s.redisStore.Update(ctx, gameID, s.conflict); err != nil {
+				panic(err)
+			}
+			s.conflict = nil
+		}
+		return newState, ticks
+	})
+}
+
+// newTestRedisStore returns a store backed by an in-process Redis server,
+// and the server
+func newTestRedisStore(t *testing.T) (*redisStore, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	store, err := newRedisStore(context.Background(), "redis://"+server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.client.Close() })
+	return store, server
+}
+
+func TestRedisStore(t *testing.T) {
+	store, server := newTestRedisStore(t)
+	testStore(t, store, func(d time.Duration) {
+		now := clock().Add(d)
+		clock = func() time.Time { return now }
+		server.FastForward(d)
+	})
+}
+
+func TestRedisStoreRetriesConflicts(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name      string
+		conflicts int
+		calls     int
+		err       error
+	}{
+		{name: "no conflict", conflicts: 0, calls: 1},
+		{name: "retried", conflicts: 3, calls: 4},
+		{name: "gives up", conflicts: redisMaxRetries, calls: redisMaxRetries, err: errTooManyConflicts},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := newTestRedisStore(t)
+			state := initializeGame(GameConfig{Width: 10, Height: 10})
+			if err := store.Save(ctx, state); err != nil {
+				t.Fatal(err)
+			}
+
+			calls := 0
+			updated, err := store.Update(ctx, state.GameID, func(current GameState) GameState {
+				calls++
+				if calls <= tt.conflicts {
+					// Another replica changes the game between WATCH and EXEC
+					other := current
+					other.Score += 10
+					if err := store.Save(ctx, other); err != nil {
+						t.Fatal(err)
+					}
+				}
+				current.TicksPlayed++
+				return current
+			})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if calls != tt.calls {
+				t.Errorf("fn called %d times, want %d", calls, tt.calls)
+			}
+
+			stored, err := store.Get(ctx, state.GameID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.err == nil && (updated.TicksPlayed != 1 || stored.TicksPlayed != 1 || stored.Score != 10*tt.conflicts) {
+				t.Errorf("stored ticksPlayed %d, score %d; want the update applied once on top of %d conflicts",
+					stored.TicksPlayed, stored.Score, tt.conflicts)
+			}
+			if tt.err != nil && stored.TicksPlayed != 0 {
+				t.Errorf("stored ticksPlayed = %d, want the update dropped", stored.TicksPlayed)
+			}
+		})
+	}
+}
+
+func TestValidateStoredRetried(t *testing.T) {
+	newTestAPI(t)
+	backend, _ := newTestRedisStore(t)
+	store := &conflictingStore{redisStore: backend}
+	games = store
+	state := initializeGame(GameConfig{Width: 10, Height: 10})
+	if err := store.Save(context.Background(), state); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first attempt applies the tick, the retry finds the game gone live
+	store.conflict = func(state GameState) GameState {
+		state.Live = true
+		return state
+	}
+	got, ticksApplied, statusCode, err := validateStored(context.Background(), state.GameID, []Tick{{VelY: 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statusCode != http.StatusConflict || ticksApplied != 0 {
+		t.Errorf("status = %d with %d ticks applied, want %d with none from the first attempt",
+			statusCode, ticksApplied, http.StatusConflict)
+	}
+	if got.TicksPlayed != 0 {
+		t.Errorf("ticksPlayed = %d, want the live game untouched", got.TicksPlayed)
+	}
+}
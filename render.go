@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// defaultCellPixels is the side of a board cell in rendered images
+	defaultCellPixels = 16
+	// maxRenderPixels bounds the side of a rendered image; cells of large
+	// boards shrink to fit
+	maxRenderPixels = 4096
+
+	// defaultGIFFrameRate and maxGIFFrameRate are the frames per second of
+	// replay GIFs; GIF delays are in hundredths of a second
+	defaultGIFFrameRate = 10
+	maxGIFFrameRate     = 50
+	// maxGIFPixels bounds the pixels of all frames of a replay GIF together
+	maxGIFPixels = 64 << 20
+)
+
+// cellColors are the colors of the characters of boardCells in images
+var cellColors = map[byte]color.RGBA{
+	'.': {0x1e, 0x1e, 0x2e, 0xff},
+	'#': {0x6c, 0x70, 0x86, 0xff},
+	'+': {0x89, 0xdc, 0xeb, 0xff},
+	'*': {0xf3, 0x8b, 0xa8, 0xff},
+	'$': {0xf9, 0xe2, 0xaf, 0xff},
+	'!': {0xcb, 0xa6, 0xf7, 0xff},
+	'x': {0x89, 0xb4, 0xfa, 0xff},
+	'&': {0x1e, 0x66, 0xf5, 0xff},
+	'o': {0xa6, 0xe3, 0xa1, 0xff},
+	'@': {0x40, 0xa0, 0x2b, 0xff},
+}
+
+// cellOrder indexes the colors of cellColors in the palette of GIFs
+const cellOrder = ".#+*$!x&o@"
+
+// cellPalette is the GIF palette of cellColors, in cellOrder
+var cellPalette = func() color.Palette {
+	palette := make(color.Palette, len(cellOrder))
+	for i := range cellOrder {
+		palette[i] = cellColors[cellOrder[i]]
+	}
+	return palette
+}()
+
+// renderFormats are the formats of GET /v1/games/{id}/render
+var renderFormats = []string{"png", "svg"}
+
+// cellPixels returns the side of a cell for a board rendered with cells of
+// the requested size, shrunk so that the image stays within maxRenderPixels
+func cellPixels(state GameState, requested int) int {
+	side := max(state.Width, state.Height, 1)
+	return max(1, min(requested, maxRenderPixels/side))
+}
+
+// boardImage draws the board with cells of the given side
+func boardImage(state GameState, cell int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, state.Width*cell, state.Height*cell))
+	for y, row := range boardCells(state) {
+		for x, c := range row {
+			fillRect(img, image.Rect(x*cell, y*cell, (x+1)*cell, (y+1)*cell), cellColors[c])
+		}
+	}
+	return img
+}
+
+// boardFrame draws the board as a GIF frame with cells of the given side
+func boardFrame(state GameState, cell int) *image.Paletted {
+	img := image.NewPaletted(image.Rect(0, 0, state.Width*cell, state.Height*cell), cellPalette)
+	for y, row := range boardCells(state) {
+		for x, c := range row {
+			index := uint8(strings.IndexByte(cellOrder, c))
+			for py := y * cell; py < (y+1)*cell; py++ {
+				for px := x * cell; px < (x+1)*cell; px++ {
+					img.SetColorIndex(px, py, index)
+				}
+			}
+		}
+	}
+	return img
+}
+
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// boardSVG draws the board as an SVG document with cells of the given side;
+// only occupied cells get a rect of their own
+func boardSVG(state GameState, cell int) []byte {
+	var b bytes.Buffer
+	width, height := state.Width*cell, state.Height*cell
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`+"\n",
+		width, height, width, height)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="%s"/>`+"\n", width, height, hexColor(cellColors['.']))
+	for y, row := range boardCells(state) {
+		for x, c := range row {
+			if c == '.' {
+				continue
+			}
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`+"\n",
+				x*cell, y*cell, cell, cell, hexColor(cellColors[c]))
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.Bytes()
+}
+
+func hexColor(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+// renderHandler returns an image of the current board, as a PNG or with
+// ?format=svg as an SVG. ?cell sets the side of a cell in pixels.
+func renderHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "svg" {
+		jsonResponseWithStatus(w, ErrorResponse{
+			Code:      CodeInvalidRequest,
+			Message:   fmt.Sprintf("Unknown format: %s", format),
+			Supported: renderFormats,
+		}, http.StatusBadRequest)
+		return
+	}
+	requested := defaultCellPixels
+	if v := r.URL.Query().Get("cell"); v != "" {
+		var err error
+		requested, err = strconv.Atoi(v)
+		if err != nil || requested <= 0 {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest, "cell must be a positive number of pixels")
+			return
+		}
+	}
+
+	state, err := games.Get(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+	cell := cellPixels(state, requested)
+
+	w.Header().Set("Cache-Control", "no-cache")
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write(boardSVG(state, cell))
+		return
+	}
+	var body bytes.Buffer
+	if err := png.Encode(&body, boardImage(state, cell)); err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Encoding image failed")
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(body.Bytes())
+}
+
+// replayGIFHandler renders the recorded tick history of a game as an
+// animated GIF, from the initial state to the state after the last tick.
+// ?fps sets the frame rate and ?cell the side of a cell in pixels.
+func replayGIFHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	fps := defaultGIFFrameRate
+	if v := r.URL.Query().Get("fps"); v != "" {
+		var err error
+		fps, err = strconv.Atoi(v)
+		if err != nil || fps <= 0 || fps > maxGIFFrameRate {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+				fmt.Sprintf("fps must be between 1 and %d", maxGIFFrameRate))
+			return
+		}
+	}
+	requested := defaultCellPixels
+	if v := r.URL.Query().Get("cell"); v != "" {
+		var err error
+		requested, err = strconv.Atoi(v)
+		if err != nil || requested <= 0 {
+			jsonError(w, http.StatusBadRequest, CodeInvalidRequest, "cell must be a positive number of pixels")
+			return
+		}
+	}
+
+	replay, err := games.Replay(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+	_, states := simulateReplay(replay, true)
+	states = append([]GameState{replay.Initial}, states...)
+
+	// Shrink the cells until all frames fit in maxGIFPixels
+	cell := cellPixels(replay.Initial, requested)
+	cells := len(states) * replay.Initial.Width * replay.Initial.Height
+	for cell > 1 && cells*cell*cell > maxGIFPixels {
+		cell--
+	}
+	if cells*cell*cell > maxGIFPixels {
+		jsonError(w, http.StatusRequestEntityTooLarge, CodeTooManyTicks,
+			fmt.Sprintf("Replay too long to render: %d ticks on a %dx%d board",
+				len(replay.Ticks), replay.Initial.Width, replay.Initial.Height))
+		return
+	}
+
+	animation := &gif.GIF{}
+	delay := max(1, 100/fps)
+	for _, state := range states {
+		animation.Image = append(animation.Image, boardFrame(state, cell))
+		animation.Delay = append(animation.Delay, delay)
+	}
+	var body bytes.Buffer
+	if err := gif.EncodeAll(&body, animation); err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Encoding image failed")
+		return
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Content-Type", "image/gif")
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+type (
+	// ReplayTick is one recorded tick; Opponent is set in versus games
+	ReplayTick struct {
+		Tick
+		Opponent *Tick `json:"opponent,omitempty"`
+	}
+
+	// Replay is the full ordered tick history of a game from its initial state
+	Replay struct {
+		GameID  string       `json:"gameId"`
+		Initial GameState    `json:"initial"`
+		Ticks   []ReplayTick `json:"ticks"`
+	}
+
+	// PlaybackResponse is the outcome of re-simulating a replay. Matches
+	// reports whether it reproduced the stored game; Frames holds the state
+	// after every tick when requested with ?frames=true.
+	PlaybackResponse struct {
+		Final         GameState   `json:"final"`
+		TicksReplayed int         `json:"ticksReplayed"`
+		Matches       bool        `json:"matches"`
+		Frames        []GameState `json:"frames,omitempty"`
+	}
+
+	// ReplayDiffRequest holds two replays of games on the same seed, in
+	// the form GET /games/{id}/replay returns them
+	ReplayDiffRequest struct {
+		A Replay `json:"a"`
+		B Replay `json:"b"`
+	}
+
+	// ReplayDiffResponse reports the first tick after which the replays
+	// no longer agree, 0 if they start from different states, and how
+	// the states differ after it
+	ReplayDiffResponse struct {
+		Diverged    bool              `json:"diverged"`
+		Tick        int               `json:"tick,omitempty"`
+		Differences []StateDifference `json:"differences,omitempty"`
+	}
+
+	// StateDifference is a field of the game state on which two replays
+	// disagree, with its value in each
+	StateDifference struct {
+		Field string `json:"field"`
+		A     any    `json:"a"`
+		B     any    `json:"b"`
+	}
+)
+
+// outcomeFields are the parts of a game state a replay determines: snake
+// positions, fruit, power-ups, scores and how the game ended
+var outcomeFields = []struct {
+	name  string
+	value func(GameState) any
+}{
+	{"snake", func(s GameState) any { return s.Snake }},
+	{"fruits", func(s GameState) any { return s.Fruits }},
+	{"fruitSpawns", func(s GameState) any { return s.FruitSpawns }},
+	{"ticksPlayed", func(s GameState) any { return s.TicksPlayed }},
+	{"opponent", func(s GameState) any { return s.Opponent }},
+	{"powerUps", func(s GameState) any { return s.PowerUps }},
+	{"effects", func(s GameState) any { return s.Effects }},
+	{"score", func(s GameState) any { return s.Score }},
+	{"opponentScore", func(s GameState) any { return s.OpponentScore }},
+	{"gameOverReason", func(s GameState) any { return s.GameOverReason }},
+	{"winner", func(s GameState) any { return s.Winner }},
+}
+
+// replayTicks converts ticks submitted for a single snake into replay ticks
+func replayTicks(ticks []Tick) []ReplayTick {
+	recorded := make([]ReplayTick, len(ticks))
+	for i, tick := range ticks {
+		recorded[i] = ReplayTick{Tick: tick}
+	}
+	return recorded
+}
+
+// simulateReplay re-runs every recorded tick from the initial state and
+// returns the final state, plus the state after each tick if keepFrames is set
+func simulateReplay(replay Replay, keepFrames bool) (GameState, []GameState) {
+	state := replay.Initial
+	var frames []GameState
+	for _, tick := range replay.Ticks {
+		if tick.Opponent != nil {
+			if state.Opponent == nil {
+				state = addOpponent(state)
+			}
+			state, _ = stepVersus(state, tick.Tick, *tick.Opponent)
+		} else {
+			state, _ = stepGame(state, tick.Tick)
+		}
+		if keepFrames {
+			frames = append(frames, state)
+		}
+	}
+	return state, frames
+}
+
+// outcomeDifferences lists the outcomeFields on which two states disagree
+func outcomeDifferences(a, b GameState) []StateDifference {
+	var differences []StateDifference
+	for _, field := range outcomeFields {
+		if va, vb := field.value(a), field.value(b); !reflect.DeepEqual(va, vb) {
+			differences = append(differences, StateDifference{Field: field.name, A: va, B: vb})
+		}
+	}
+	return differences
+}
+
+// sameOutcome returns true if two states agree on everything a replay
+// determines
+func sameOutcome(a, b GameState) bool {
+	return len(outcomeDifferences(a, b)) == 0
+}
+
+// diffReplays re-simulates both replays and compares them tick by tick.
+// The shorter replay keeps its final state while the longer one goes on.
+func diffReplays(a, b Replay) ReplayDiffResponse {
+	_, framesA := simulateReplay(a, true)
+	_, framesB := simulateReplay(b, true)
+	after := func(replay Replay, frames []GameState, tick int) GameState {
+		if tick = min(tick, len(frames)); tick == 0 {
+			return replay.Initial
+		}
+		return frames[tick-1]
+	}
+
+	for tick := 0; tick <= max(len(framesA), len(framesB)); tick++ {
+		differences := outcomeDifferences(after(a, framesA, tick), after(b, framesB, tick))
+		if len(differences) > 0 {
+			return ReplayDiffResponse{Diverged: true, Tick: tick, Differences: differences}
+		}
+	}
+	return ReplayDiffResponse{}
+}
+
+// replayHandler returns the initial state and ordered tick history of a game
+func replayHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	replay, err := games.Replay(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+
+	jsonResponse(w, replay)
+}
+
+// stateAtHandler rebuilds the state of a game after ?atTick=N of its
+// recorded ticks, 0 being the state it was created in
+func stateAtHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	replay, err := games.Replay(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+	tick, err := strconv.Atoi(r.URL.Query().Get("atTick"))
+	if err != nil || tick < 0 || tick > len(replay.Ticks) {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+			fmt.Sprintf("atTick must be between 0 and %d, the ticks recorded so far", len(replay.Ticks)))
+		return
+	}
+
+	replay.Ticks = replay.Ticks[:tick]
+	state, _ := simulateReplay(replay, false)
+	jsonResponse(w, state)
+}
+
+// replayDiffHandler compares two replays on the same seed, such as the
+// server's replay of a game and a client's prediction of it
+func replayDiffHandler(w http.ResponseWriter, r *http.Request) {
+	var req ReplayDiffRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	a, b := req.A.Initial, req.B.Initial
+	if code, message := boardSizeError(a.Width, a.Height); code != "" {
+		jsonError(w, http.StatusBadRequest, code, message)
+		return
+	}
+	if a.Seed != b.Seed || a.Width != b.Width || a.Height != b.Height {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest,
+			"Replays must start from the same seed on the same board")
+		return
+	}
+	if tooManyTicks(w, max(len(req.A.Ticks), len(req.B.Ticks))) {
+		return
+	}
+
+	jsonResponse(w, diffReplays(req.A, req.B))
+}
+
+// playReplayHandler re-simulates a recorded game server-side and reports
+// whether it reproduces the stored state
+func playReplayHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	replay, err := games.Replay(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+	stored, err := games.Get(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+
+	final, frames := simulateReplay(replay, r.URL.Query().Get("frames") == "true")
+	jsonResponse(w, PlaybackResponse{
+		Final:         final,
+		TicksReplayed: len(replay.Ticks),
+		Matches:       sameOutcome(final, stored),
+		Frames:        frames,
+	})
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestReplayDiff(t *testing.T) {
+	h := newTestAPI(t)
+	game := newGame(t, h, "", GameConfig{Width: 10, Height: 10, Seed: 7})
+	path := "/v1/games/" + game.GameID
+	ticks := []Tick{{VelX: 1}, {VelY: 1}, {VelY: 1}}
+	if rec := do(t, h, http.MethodPost, path+"/validate", "", ValidateRequest{Ticks: ticks}); rec.Code != http.StatusOK {
+		t.Fatalf("validating: %d %s", rec.Code, rec.Body)
+	}
+	var replay Replay
+	decode(t, do(t, h, http.MethodGet, path+"/replay", "", nil), &replay)
+
+	turned := replay
+	turned.Ticks = replayTicks([]Tick{{VelX: 1}, {VelX: 1}, {VelY: 1}})
+	shorter := replay
+	shorter.Ticks = replay.Ticks[:2]
+	reseeded := replay
+	reseeded.Initial.Seed++
+
+	tests := []struct {
+		name   string
+		b      Replay
+		status int
+		want   ReplayDiffResponse
+		fields []string
+	}{
+		{name: "same", b: replay, status: http.StatusOK},
+		{name: "diverging tick", b: turned, status: http.StatusOK,
+			want: ReplayDiffResponse{Diverged: true, Tick: 2}, fields: []string{"snake"}},
+		{name: "shorter", b: shorter, status: http.StatusOK,
+			want: ReplayDiffResponse{Diverged: true, Tick: 3}, fields: []string{"snake", "ticksPlayed"}},
+		{name: "other seed", b: reseeded, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := do(t, h, http.MethodPost, "/v1/replays/diff", "", ReplayDiffRequest{A: replay, B: tt.b})
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var diff ReplayDiffResponse
+			decode(t, rec, &diff)
+			var fields []string
+			for _, difference := range diff.Differences {
+				fields = append(fields, difference.Field)
+			}
+			if diff.Diverged != tt.want.Diverged || diff.Tick != tt.want.Tick || len(fields) != len(tt.fields) {
+				t.Fatalf("diff = diverged %t at tick %d on %v, want %t at %d on %v",
+					diff.Diverged, diff.Tick, fields, tt.want.Diverged, tt.want.Tick, tt.fields)
+			}
+			for i := range fields {
+				if fields[i] != tt.fields[i] {
+					t.Errorf("differences on %v, want %v", fields, tt.fields)
+				}
+			}
+		})
+	}
+}
+
+func TestStateAt(t *testing.T) {
+	h := newTestAPI(t)
+	game := newGame(t, h, "", GameConfig{Width: 10, Height: 10})
+	path := "/v1/games/" + game.GameID
+	if rec := do(t, h, http.MethodPost, path+"/validate", "", ValidateRequest{Ticks: []Tick{{VelX: 1}, {VelY: 1}}}); rec.Code != http.StatusOK {
+		t.Fatalf("validating: %d %s", rec.Code, rec.Body)
+	}
+
+	tests := []struct {
+		query  string
+		status int
+		head   Position
+	}{
+		{query: "?atTick=0", status: http.StatusOK, head: Position{X: 0, Y: 0}},
+		{query: "?atTick=1", status: http.StatusOK, head: Position{X: 1, Y: 0}},
+		{query: "?atTick=2", status: http.StatusOK, head: Position{X: 1, Y: 1}},
+		{query: "?atTick=3", status: http.StatusBadRequest},
+		{query: "?atTick=-1", status: http.StatusBadRequest},
+		{query: "", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := do(t, h, http.MethodGet, path+"/state"+tt.query, "", nil)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if rec.Code != http.StatusOK {
+				return
+			}
+			var state GameState
+			decode(t, rec, &state)
+			if state.Snake.Position != tt.head {
+				t.Errorf("head at %v, want %v", state.Snake.Position, tt.head)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Defaults for the HTTP server, overridden by flags or their environment
+// variables
+const (
+	defaultAddr         = ":8080"
+	defaultReadTimeout  = 10 * time.Second
+	defaultWriteTimeout = 30 * time.Second
+	defaultIdleTimeout  = 120 * time.Second
+)
+
+// envOr returns the value of the environment variable name, or fallback if
+// it is unset
+func envOr(name, fallback string) string {
+	if v, ok := os.LookupEnv(name); ok {
+		return v
+	}
+	return fallback
+}
+
+// envDuration returns the duration in the environment variable name, or
+// fallback if it is unset
+func envDuration(name string, fallback time.Duration) time.Duration {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("invalid $%s: %v", name, err)
+	}
+	return d
+}
+
+// envFloat returns the number in the environment variable name, or fallback
+// if it is unset
+func envFloat(name string, fallback float64) float64 {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.Fatalf("invalid $%s: %v", name, err)
+	}
+	return f
+}
+
+// envInt returns the integer in the environment variable name, or fallback
+// if it is unset
+func envInt(name string, fallback int) int {
+	v, ok := os.LookupEnv(name)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		log.Fatalf("invalid $%s: %v", name, err)
+	}
+	return n
+}
+
+// newServer returns the HTTP server listening on addr. The timeouts bound
+// how long a client may take to send a request and receive its response;
+// streaming handlers lift them with clearDeadlines.
+func newServer(addr string, handler http.Handler, readTimeout, writeTimeout, idleTimeout time.Duration) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: readTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+}
+
+// shutdownTimeout bounds how long in-flight requests may take to finish once
+// the server is asked to stop
+const shutdownTimeout = 10 * time.Second
+
+// serve runs the server, and the gRPC server on grpcAddr unless it is empty,
+// until either fails or the process receives SIGINT or SIGTERM, in which case
+// in-flight requests are given shutdownTimeout to finish
+func serve(server *http.Server, grpcServer *grpc.Server, grpcAddr string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errs := make(chan error, 2)
+	go func() {
+		log.Printf("listening on %s", server.Addr)
+		errs <- server.ListenAndServe()
+	}()
+	if grpcAddr != "" {
+		listener, err := net.Listen("tcp", grpcAddr)
+		if err != nil {
+			return err
+		}
+		go func() {
+			log.Printf("serving gRPC on %s", grpcAddr)
+			errs <- grpcServer.Serve(listener)
+		}()
+		defer stopGRPC(grpcServer)
+	}
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Printf("shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return server.Shutdown(ctx)
+}
+
+// stopGRPC stops the gRPC server, giving in-flight calls shutdownTimeout to
+// finish before open streams are cut
+func stopGRPC(server *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(shutdownTimeout):
+		server.Stop()
+	}
+}
+
+// clearDeadlines removes the server's read and write deadlines from a
+// long-lived Server-Sent Events response
+func clearDeadlines(w http.ResponseWriter) {
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Time{}); err != nil {
+		log.Printf("clearing read deadline: %v", err)
+	}
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		log.Printf("clearing write deadline: %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"log"
+	"net/http"
+)
+
+// signingKeyEnv names the environment variable holding the HMAC key used in
+// stateless mode. It is read from the environment rather than a flag so the
+// secret does not show up in process listings.
+const signingKeyEnv = "SNAKE_HMAC_KEY"
+
+// signingKey is set in stateless mode, where the server keeps no games and
+// clients round-trip the whole signed state instead
+var signingKey []byte
+
+// enableStatelessMode switches the server to signed, client-held states. A
+// random key is generated if none is configured, so states signed by one
+// process are not accepted after a restart or by other replicas.
+func enableStatelessMode(key string) {
+	if key == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			log.Fatalf("generating signing key: %v", err)
+		}
+		log.Printf("stateless mode: %s not set, using a random signing key", signingKeyEnv)
+		signingKey = random
+		return
+	}
+	signingKey = []byte(key)
+}
+
+// signState returns the HMAC-SHA256 of the canonical encoding of state: its
+// JSON form without the signature and pending ticks
+func signState(state GameState) string {
+	state.Signature = ""
+	state.Ticks = nil
+	canonical, _ := json.Marshal(state)
+
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write(canonical)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// validSignature returns true if the state carries a signature produced by
+// this server for exactly this state
+func validSignature(state GameState) bool {
+	return hmac.Equal([]byte(state.Signature), []byte(signState(state)))
+}
+
+// persistNewGame stores a newly created game, or in stateless mode signs it
+// so the client can hand it back
+func persistNewGame(ctx context.Context, state GameState) (GameState, error) {
+	if signingKey != nil {
+		state.Signature = signState(state)
+		return state, nil
+	}
+	return state, games.Save(ctx, state)
+}
+
+// statelessValidateHandler applies the ticks of a client-held, signed state
+// and returns the resulting state signed again. A signature does not stop a
+// client from resubmitting an older state it was given; use the session
+// store when that matters.
+func statelessValidateHandler(w http.ResponseWriter, r *http.Request) {
+	var state GameState
+	if err := decodeBody(r, &state); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	auditGame(r.Context(), state.GameID, len(state.Ticks))
+	if tooManyTicks(w, len(state.Ticks)) {
+		return
+	}
+	if !validSignature(state) {
+		jsonError(w, http.StatusForbidden, CodeInvalidSignature,
+			"Game state signature does not match; the state was modified")
+		return
+	}
+
+	newState, ticksApplied, statusCode := validateTicks(r.Context(), state)
+	if statusCode == http.StatusBadRequest {
+		newState = state
+	}
+	newState.Ticks = nil
+	newState.Signature = signState(newState)
+
+	writeValidateResult(w, r, newState, ticksApplied, statusCode)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// TickFile is the input of snake simulate: a replay as returned by
+// GET /v1/games/{id}/replay, optionally with the final state it must
+// reproduce, such as the final of a POST /v1/replays/{id}/play
+type TickFile struct {
+	Replay
+	Final *GameState `json:"final,omitempty"`
+}
+
+// fileList collects the values of a repeated flag
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// simulateCommand re-runs the ticks of each file with the game engine and
+// reports the outcome. It exits with 1 if a file cannot be read, contains an
+// invalid move or does not reproduce its final state.
+func simulateCommand(args []string) {
+	flags := flag.NewFlagSet("simulate", flag.ExitOnError)
+	var files fileList
+	flags.Var(&files, "ticks", "tick file to simulate; repeat it or list more files as arguments")
+	flags.Parse(args)
+	files = append(files, flags.Args()...)
+	if len(files) == 0 {
+		fmt.Fprintln(os.Stderr, "usage: snake simulate --ticks file.json [file.json...]")
+		os.Exit(2)
+	}
+
+	failed := false
+	for _, path := range files {
+		summary, err := simulateFile(path)
+		if err != nil {
+			failed = true
+			fmt.Printf("%s: FAIL %v\n", path, err)
+			continue
+		}
+		fmt.Printf("%s: ok %s\n", path, summary)
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// simulateFile replays the tick file at path and describes its outcome, or
+// returns why it does not hold up
+func simulateFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var file TickFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return "", fmt.Errorf("decoding: %w", err)
+	}
+
+	final, err := checkedReplay(file.Replay)
+	if err != nil {
+		return "", err
+	}
+	if file.Final != nil && !sameOutcome(final, *file.Final) {
+		return "", fmt.Errorf("final state differs: score=%d ticks=%d, expected score=%d ticks=%d",
+			final.Score, final.TicksPlayed, file.Final.Score, file.Final.TicksPlayed)
+	}
+
+	summary := fmt.Sprintf("score=%d ticks=%d", final.Score, final.TicksPlayed)
+	if final.Versus {
+		summary += fmt.Sprintf(" opponentScore=%d", final.OpponentScore)
+	}
+	if final.GameOverReason != "" {
+		summary += fmt.Sprintf(" gameOver=%s", final.GameOverReason)
+	}
+	return summary, nil
+}
+
+// checkedReplay is simulateReplay failing on the first tick the server would
+// have rejected
+func checkedReplay(replay Replay) (GameState, error) {
+	state := replay.Initial
+	for i, tick := range replay.Ticks {
+		var statusCode int
+		if tick.Opponent != nil {
+			if state.Opponent == nil {
+				state = addOpponent(state)
+			}
+			state, statusCode = stepVersus(state, tick.Tick, *tick.Opponent)
+		} else {
+			state, statusCode = stepGame(state, tick.Tick)
+		}
+		if statusCode == http.StatusBadRequest {
+			return state, fmt.Errorf("invalid move at tick %d", i)
+		}
+		if statusCode == http.StatusTeapot && i < len(replay.Ticks)-1 {
+			return state, fmt.Errorf("ticks continue after the game ended at tick %d: %s", i, state.GameOverReason)
+		}
+	}
+	return state, nil
+}
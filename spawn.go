@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// FruitSpawn names a weighting strategy used to bias where fruit appears
+type FruitSpawn string
+
+const (
+	// SpawnUniform places fruit on any cell with equal probability
+	SpawnUniform FruitSpawn = "uniform"
+	// SpawnCenter favours cells close to the middle of the board
+	SpawnCenter FruitSpawn = "center"
+	// SpawnAwayFromWalls favours cells far from every edge
+	SpawnAwayFromWalls FruitSpawn = "away-from-walls"
+	// SpawnNearSnake only picks cells within a distance band of the snake head
+	SpawnNearSnake FruitSpawn = "near-snake"
+)
+
+// nearSnakeMinDistance and nearSnakeMaxDistance bound the Manhattan distance
+// from the snake head used by SpawnNearSnake
+const (
+	nearSnakeMinDistance = 2
+	nearSnakeMaxDistance = 5
+)
+
+// spawnWeights returns the relative weight of a cell for each non-uniform
+// strategy. Cells with weight 0 are never picked.
+var spawnWeights = map[FruitSpawn]func(state GameState, pos Position) int{
+	SpawnCenter: func(state GameState, pos Position) int {
+		dist := abs(2*pos.X-(state.Width-1)) + abs(2*pos.Y-(state.Height-1))
+		return state.Width + state.Height - dist
+	},
+	SpawnAwayFromWalls: func(state GameState, pos Position) int {
+		return 1 + min(pos.X, state.Width-1-pos.X, pos.Y, state.Height-1-pos.Y)
+	},
+	SpawnNearSnake: func(state GameState, pos Position) int {
+		dist := abs(pos.X-state.Snake.X) + abs(pos.Y-state.Snake.Y)
+		if dist < nearSnakeMinDistance || dist > nearSnakeMaxDistance {
+			return 0
+		}
+		return 1
+	},
+}
+
+// valid returns true if the strategy is known; empty means uniform
+func (s FruitSpawn) valid() bool {
+	if s == "" || s == SpawnUniform {
+		return true
+	}
+	_, ok := spawnWeights[s]
+	return ok
+}
+
+// maxFruits bounds the number of fruits on the board at once
+const maxFruits = 100
+
+// fruitCountError returns an error code and message if the config asks for
+// more fruits than fit next to its obstacles. More than one fruit must leave
+// at least half of the board free, so respawning always finds a cell.
+func fruitCountError(config GameConfig) (ErrorCode, string) {
+	if config.FruitCount < 0 || config.FruitCount > maxFruits {
+		return CodeInvalidRequest,
+			fmt.Sprintf("Invalid fruitCount: %d, max=%d", config.FruitCount, maxFruits)
+	}
+	if config.FruitCount > 1 && config.Obstacles+config.FruitCount > config.Width*config.Height/2 {
+		return CodeInvalidBoard, fmt.Sprintf(
+			"Too many fruits and obstacles for a %dx%d board", config.Width, config.Height)
+	}
+	return "", ""
+}
+
+// spawnFruit places the fruit at index i according to the game's spawn
+// strategy, replacing an eaten fruit or appending a new one when i is
+// len(state.Fruits). It falls back to uniform placement when no cell has
+// positive weight.
+func spawnFruit(state GameState, i int) GameState {
+	rng := fruitRand(state)
+	pos := pickFruitPosition(state, rng, blockedCells(state, i))
+	fruit := Fruit{Position: pos, Type: pickFruitType(state.FruitChances, rng)}
+
+	fruits := append([]Fruit(nil), state.Fruits...)
+	if i == len(fruits) {
+		fruits = append(fruits, fruit)
+	} else {
+		fruits[i] = fruit
+	}
+	state.Fruits = fruits
+	state.FruitSpawns++
+	return maybeSpawnPowerUp(state, rng)
+}
+
+// blockedCells returns the cells a new fruit may not take: obstacles,
+// power-ups and every fruit other than the one at index i
+func blockedCells(state GameState, i int) map[Position]bool {
+	blocked := make(map[Position]bool, len(state.Obstacles)+len(state.Fruits)+len(state.PowerUps))
+	for _, obstacle := range state.Obstacles {
+		blocked[obstacle] = true
+	}
+	for _, powerUp := range state.PowerUps {
+		blocked[powerUp.Position] = true
+	}
+	for j, fruit := range state.Fruits {
+		if j != i {
+			blocked[fruit.Position] = true
+		}
+	}
+	return blocked
+}
+
+// fruitRand returns the random source for the next fruit of the game. It
+// only depends on the seed and the number of fruits spawned so far, so
+// replaying a game reproduces every fruit.
+func fruitRand(state GameState) *rand.Rand {
+	return seededRand(state.Seed, uint64(state.FruitSpawns+1))
+}
+
+// seededRand returns the random source for the given stream of a seed
+func seededRand(seed int64, stream uint64) *rand.Rand {
+	// splitmix64 finalizer, so consecutive streams get unrelated sources
+	z := uint64(seed) + stream*0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	z ^= z >> 31
+	return rand.New(rand.NewSource(int64(z)))
+}
+
+// pickFruitPosition draws a fruit position from rng using the game's
+// weighting strategy. Blocked cells are never picked.
+func pickFruitPosition(state GameState, rng *rand.Rand, blocked map[Position]bool) Position {
+	strategy, ok := spawnWeights[state.FruitSpawn]
+	if !ok {
+		return freeRandomPosition(state, rng, blocked)
+	}
+	weight := func(state GameState, pos Position) int {
+		if blocked[pos] {
+			return 0
+		}
+		return strategy(state, pos)
+	}
+
+	total := 0
+	for y := 0; y < state.Height; y++ {
+		for x := 0; x < state.Width; x++ {
+			total += weight(state, Position{X: x, Y: y})
+		}
+	}
+	if total <= 0 {
+		return freeRandomPosition(state, rng, blocked)
+	}
+
+	pick := rng.Intn(total)
+	for y := 0; y < state.Height; y++ {
+		for x := 0; x < state.Width; x++ {
+			pos := Position{X: x, Y: y}
+			pick -= weight(state, pos)
+			if pick < 0 {
+				return pos
+			}
+		}
+	}
+
+	return freeRandomPosition(state, rng, blocked)
+}
+
+// freeRandomPosition draws a uniformly random cell that is not blocked. At
+// most half of the board is blocked, so this terminates quickly.
+func freeRandomPosition(state GameState, rng *rand.Rand, blocked map[Position]bool) Position {
+	for {
+		pos := generateRandomPosition(rng, state.Width, state.Height)
+		if !blocked[pos] {
+			return pos
+		}
+	}
+}
+
+// abs returns the absolute value of x
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scoreBuckets are the lower bounds of the ranges the score distribution
+// counts submitted scores in
+var scoreBuckets = []int{0, 1, 5, 10, 25, 50, 100, 250}
+
+// startedAt is when the server started counting its process totals
+var startedAt = time.Now().UTC().Truncate(time.Second)
+
+// PlayerStats aggregates the finished games of a player. WinRate is the
+// share of versus games won, and only set once the player has finished one.
+type PlayerStats struct {
+	Player          string   `json:"player"`
+	GamesPlayed     int      `json:"gamesPlayed"`
+	HighScore       int      `json:"highScore"`
+	AverageScore    float64  `json:"averageScore"`
+	FruitsEaten     int      `json:"fruitsEaten"`
+	LongestSurvival int      `json:"longestSurvival"`
+	VersusGames     int      `json:"versusGames"`
+	Wins            int      `json:"wins"`
+	Losses          int      `json:"losses"`
+	Draws           int      `json:"draws"`
+	WinRate         *float64 `json:"winRate,omitempty"`
+}
+
+type (
+	// ServerStats reports participation. GamesCreated and Validations count
+	// since Since, when this server started; ActiveGames are the live games
+	// it is advancing now. The other totals come from the database and
+	// survive restarts.
+	ServerStats struct {
+		Since             time.Time     `json:"since"`
+		GamesCreated      int           `json:"gamesCreated"`
+		Validations       int           `json:"validations"`
+		ActiveGames       int           `json:"activeGames"`
+		GamesFinished     int           `json:"gamesFinished"`
+		Players           int           `json:"players"`
+		ScoresSubmitted   int           `json:"scoresSubmitted"`
+		AverageScore      float64       `json:"averageScore"`
+		ScoreDistribution []ScoreBucket `json:"scoreDistribution"`
+	}
+
+	// ScoreBucket counts the submitted scores from Min up to Max, or above
+	// Min if Max is not set
+	ScoreBucket struct {
+		Min   int  `json:"min"`
+		Max   *int `json:"max,omitempty"`
+		Count int  `json:"count"`
+	}
+)
+
+// historyStore keeps the finished games of named players next to the
+// leaderboard, since games themselves are only kept until they expire
+type historyStore struct {
+	db *sql.DB
+}
+
+// history is the server-wide game history, opened in main
+var history *historyStore
+
+// openHistory creates the game history table in db if needed. Each game has
+// a row per named player, with the result of versus games from their side.
+func openHistory(db *sql.DB) (*historyStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS game_history (
+			game_id     TEXT NOT NULL,
+			player      TEXT NOT NULL,
+			score       INTEGER NOT NULL,
+			fruits      INTEGER NOT NULL,
+			ticks       INTEGER NOT NULL,
+			versus      INTEGER NOT NULL,
+			result      INTEGER NOT NULL,
+			finished_at INTEGER NOT NULL,
+			PRIMARY KEY (game_id, player)
+		);
+		CREATE INDEX IF NOT EXISTS game_history_by_player ON game_history (player);
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return &historyStore{db: db}, nil
+}
+
+// historyEntry is a row of game_history. Result is 1 for a win, -1 for a
+// loss and 0 for a draw or a solo game.
+type historyEntry struct {
+	player               string
+	score, fruits, ticks int
+	versus               bool
+	result               int
+}
+
+// add records the entries of a finished game; games already recorded are
+// ignored
+func (s *historyStore) add(gameID string, entries []historyEntry) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixMilli()
+	for _, entry := range entries {
+		_, err := tx.Exec(
+			`INSERT INTO game_history (game_id, player, score, fruits, ticks, versus, result, finished_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, ?) ON CONFLICT DO NOTHING`,
+			gameID, entry.player, entry.score, entry.fruits, entry.ticks, entry.versus, entry.result, now)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// stats aggregates the history of a player
+func (s *historyStore) stats(player string) (PlayerStats, error) {
+	stats := PlayerStats{Player: player}
+	var average sql.NullFloat64
+	err := s.db.QueryRow(
+		`SELECT COUNT(*), COALESCE(MAX(score), 0), AVG(score), COALESCE(SUM(fruits), 0),
+			COALESCE(MAX(ticks), 0), COALESCE(SUM(versus), 0),
+			COUNT(CASE WHEN versus AND result > 0 THEN 1 END),
+			COUNT(CASE WHEN versus AND result < 0 THEN 1 END),
+			COUNT(CASE WHEN versus AND result = 0 THEN 1 END)
+		 FROM game_history WHERE player = ?`, player).
+		Scan(&stats.GamesPlayed, &stats.HighScore, &average, &stats.FruitsEaten,
+			&stats.LongestSurvival, &stats.VersusGames, &stats.Wins, &stats.Losses, &stats.Draws)
+	if err != nil {
+		return stats, err
+	}
+	stats.AverageScore = average.Float64
+	if stats.VersusGames > 0 {
+		rate := float64(stats.Wins) / float64(stats.VersusGames)
+		stats.WinRate = &rate
+	}
+	return stats, nil
+}
+
+// gamePlayers names the players of a game, snake first: the players of a
+// versus game, or else the owner as the snake. A snake played anonymously
+// has an empty name.
+func gamePlayers(state GameState) []string {
+	if len(state.Players) > 0 {
+		return state.Players
+	}
+	if state.Owner != "" {
+		return []string{state.Owner}
+	}
+	return nil
+}
+
+// recordGame adds a finished game to the history of its named players;
+// anonymous games are not recorded
+func recordGame(state GameState) {
+	if state.GameOverReason == "" {
+		return
+	}
+
+	result := 0
+	if state.Versus {
+		switch state.Winner {
+		case 1:
+			result = 1
+		case 2:
+			result = -1
+		}
+	}
+	var entries []historyEntry
+	for i, player := range gamePlayers(state) {
+		if player == "" || i > 1 {
+			continue
+		}
+		entry := historyEntry{
+			player: player,
+			score:  state.Score,
+			fruits: state.FruitsEaten,
+			ticks:  state.TicksPlayed,
+			versus: state.Versus,
+			result: result,
+		}
+		if i == 1 {
+			entry.score, entry.fruits, entry.result = state.OpponentScore, state.OpponentFruitsEaten, -result
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return
+	}
+	if err := history.add(state.GameID, entries); err != nil {
+		log.Printf("recording game %s: %v", state.GameID, err)
+	}
+}
+
+// playerStatsHandler returns the statistics of a player
+func playerStatsHandler(w http.ResponseWriter, r *http.Request) {
+	player, err := url.PathUnescape(chi.URLParam(r, "id"))
+	if err != nil || player == "" || len(player) > maxPlayerNameLength {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+			"Player name must be between 1 and %d characters", maxPlayerNameLength))
+		return
+	}
+
+	stats, err := history.stats(player)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load statistics")
+		return
+	}
+	jsonResponse(w, stats)
+}
+
+// totals reads the totals of the game history and the leaderboard into stats
+func (s *historyStore) totals(stats *ServerStats) error {
+	err := s.db.QueryRow(`SELECT COUNT(DISTINCT game_id), COUNT(DISTINCT player) FROM game_history`).
+		Scan(&stats.GamesFinished, &stats.Players)
+	if err != nil {
+		return err
+	}
+
+	var average sql.NullFloat64
+	err = s.db.QueryRow(`SELECT COUNT(*), AVG(score) FROM scores`).Scan(&stats.ScoresSubmitted, &average)
+	if err != nil {
+		return err
+	}
+	stats.AverageScore = average.Float64
+
+	stats.ScoreDistribution = make([]ScoreBucket, len(scoreBuckets))
+	for i, lower := range scoreBuckets {
+		stats.ScoreDistribution[i].Min = lower
+		if i+1 < len(scoreBuckets) {
+			upper := scoreBuckets[i+1] - 1
+			stats.ScoreDistribution[i].Max = &upper
+		}
+	}
+	rows, err := s.db.Query(`SELECT score, COUNT(*) FROM scores GROUP BY score`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var score, count int
+		if err := rows.Scan(&score, &count); err != nil {
+			return err
+		}
+		for i := len(scoreBuckets) - 1; i >= 0; i-- {
+			if score >= scoreBuckets[i] {
+				stats.ScoreDistribution[i].Count += count
+				break
+			}
+		}
+	}
+	return rows.Err()
+}
+
+// counterTotal sums every series of the named counter in the default
+// registry
+func counterTotal(name string) int {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		log.Printf("gathering metrics: %v", err)
+	}
+	total := 0.0
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			total += metric.GetCounter().GetValue()
+		}
+	}
+	return int(total)
+}
+
+// serverStatsHandler returns the participation totals of the server
+func serverStatsHandler(w http.ResponseWriter, r *http.Request) {
+	stats := ServerStats{
+		Since:        startedAt,
+		GamesCreated: counterTotal("snake_games_created_total"),
+		Validations:  counterTotal("snake_validations_total"),
+		ActiveGames:  loop.active(),
+	}
+	if err := history.totals(&stats); err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load statistics")
+		return
+	}
+	jsonResponse(w, stats)
+}
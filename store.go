@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+)
+
+var errGameNotFound = errors.New("game not found")
+
+// Store holds the authoritative state of every game, keyed by GameID
+type Store interface {
+	// Get returns the stored state of the given game, or errGameNotFound
+	Get(ctx context.Context, gameID string) (GameState, error)
+
+	// Save stores a new game and starts its replay with state as the
+	// initial state
+	Save(ctx context.Context, state GameState) error
+
+	// Update atomically replaces the stored state of the given game with the
+	// result of fn and returns it, or errGameNotFound. Implementations may
+	// call fn more than once if the game changes concurrently. The game
+	// expires by the LastActivity fn returns; see touch. Its Version is
+	// advanced with nextVersion.
+	Update(ctx context.Context, gameID string, fn func(GameState) GameState) (GameState, error)
+
+	// UpdateRecorded is like Update, but also appends the ticks returned by
+	// fn to the game's replay in the same atomic operation
+	UpdateRecorded(ctx context.Context, gameID string, fn func(GameState) (GameState, []ReplayTick)) (GameState, error)
+
+	// Replay returns the initial state and every recorded tick of a game
+	Replay(ctx context.Context, gameID string) (Replay, error)
+
+	// Delete removes a game and its replay, or returns errGameNotFound
+	Delete(ctx context.Context, gameID string) error
+
+	// Ping returns an error if the store cannot currently be reached
+	Ping(ctx context.Context) error
+
+	// Reap removes the games, with their replays, that have expired by now
+	// and returns how many it removed. Stores that expire games on their
+	// own may remove none.
+	Reap(ctx context.Context, now time.Time) (int, error)
+}
+
+// games is the server-wide session store, selected in main
+var games Store = newMemoryStore()
+
+// storeError writes the error response for a failed store operation
+func storeError(w http.ResponseWriter, err error, gameID string) {
+	if errors.Is(err, errGameNotFound) {
+		jsonError(w, http.StatusNotFound, CodeGameNotFound,
+			fmt.Sprintf("Game not found: %s", gameID))
+		return
+	}
+	jsonError(w, http.StatusServiceUnavailable, CodeUnavailable, "Game store unavailable")
+}
+
+// nextVersion returns the state fn made of before, one version later if fn
+// changed anything, so clients waiting for a new version only wake up for
+// real changes
+func nextVersion(before, after GameState) GameState {
+	after.Version = before.Version
+	if !reflect.DeepEqual(before, after) {
+		after.Version++
+	}
+	return after
+}
+
+// memoryStore keeps games in process memory; they are lost on restart
+type memoryStore struct {
+	mu      sync.Mutex
+	games   map[string]GameState
+	replays map[string]*Replay
+}
+
+// newMemoryStore creates an empty in-memory store
+func newMemoryStore() *memoryStore {
+	return &memoryStore{
+		games:   make(map[string]GameState),
+		replays: make(map[string]*Replay),
+	}
+}
+
+func (s *memoryStore) Get(_ context.Context, gameID string) (GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.games[gameID]
+	if !ok || expired(state, clock()) {
+		return GameState{}, errGameNotFound
+	}
+	return state, nil
+}
+
+func (s *memoryStore) Save(_ context.Context, state GameState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.games[state.GameID] = state
+	s.replays[state.GameID] = &Replay{GameID: state.GameID, Initial: state, Ticks: []ReplayTick{}}
+	return nil
+}
+
+func (s *memoryStore) Update(ctx context.Context, gameID string, fn func(GameState) GameState) (GameState, error) {
+	return s.UpdateRecorded(ctx, gameID, func(state GameState) (GameState, []ReplayTick) {
+		return fn(state), nil
+	})
+}
+
+func (s *memoryStore) UpdateRecorded(_ context.Context, gameID string, fn func(GameState) (GameState, []ReplayTick)) (GameState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.games[gameID]
+	if !ok || expired(state, clock()) {
+		return GameState{}, errGameNotFound
+	}
+
+	state, ticks := fn(state)
+	state = nextVersion(s.games[gameID], state)
+	s.games[gameID] = state
+	if replay := s.replays[gameID]; replay != nil {
+		replay.Ticks = append(replay.Ticks, ticks...)
+	}
+	return state, nil
+}
+
+func (s *memoryStore) Replay(_ context.Context, gameID string) (Replay, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replay, ok := s.replays[gameID]
+	if !ok || expired(s.games[gameID], clock()) {
+		return Replay{}, errGameNotFound
+	}
+
+	copied := *replay
+	copied.Ticks = append([]ReplayTick{}, replay.Ticks...)
+	return copied, nil
+}
+
+func (s *memoryStore) Delete(_ context.Context, gameID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.games[gameID]; !ok {
+		return errGameNotFound
+	}
+	delete(s.games, gameID)
+	delete(s.replays, gameID)
+	return nil
+}
+
+func (s *memoryStore) Ping(context.Context) error {
+	return nil
+}
+
+func (s *memoryStore) Reap(_ context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reaped := 0
+	for id, state := range s.games {
+		if expired(state, now) {
+			delete(s.games, id)
+			delete(s.replays, id)
+			reaped++
+		}
+	}
+	return reaped, nil
+}
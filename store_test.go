@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// testStore checks the behaviour every Store implementation shares. elapse
+// moves clock, and the store's own notion of time, forward.
+func testStore(t *testing.T, store Store, elapse func(time.Duration)) {
+	ctx := context.Background()
+	defer func(now func() time.Time) { clock = now }(clock)
+	now := time.Now()
+	clock = func() time.Time { return now }
+	state := initializeGame(GameConfig{Width: 10, Height: 10})
+
+	if _, err := store.Get(ctx, state.GameID); !errors.Is(err, errGameNotFound) {
+		t.Fatalf("Get before Save: err = %v, want errGameNotFound", err)
+	}
+	if err := store.Save(ctx, state); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.Get(ctx, state.GameID); err != nil || got.Seed != state.Seed {
+		t.Fatalf("Get = %+v, %v, want the saved state", got, err)
+	}
+
+	ticks := []ReplayTick{{Tick: Tick{VelX: 1}}, {Tick: Tick{VelY: 1}}}
+	for _, tick := range ticks {
+		updated, err := store.UpdateRecorded(ctx, state.GameID, func(state GameState) (GameState, []ReplayTick) {
+			state.TicksPlayed++
+			return state, []ReplayTick{tick}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !updated.LastActivity.Equal(state.LastActivity) {
+			t.Error("UpdateRecorded changed LastActivity on its own")
+		}
+	}
+	if got, _ := store.Get(ctx, state.GameID); got.TicksPlayed != len(ticks) {
+		t.Errorf("ticksPlayed = %d after %d updates", got.TicksPlayed, len(ticks))
+	}
+	replay, err := store.Replay(ctx, state.GameID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replay.Initial.TicksPlayed != 0 || len(replay.Ticks) != len(ticks) ||
+		replay.Ticks[0] != ticks[0] || replay.Ticks[1] != ticks[1] {
+		t.Errorf("replay = %+v, want the initial state and ticks %v", replay, ticks)
+	}
+
+	if _, err := store.Update(ctx, "missing", func(state GameState) GameState { return state }); !errors.Is(err, errGameNotFound) {
+		t.Errorf("Update of a missing game: err = %v, want errGameNotFound", err)
+	}
+	if err := store.Delete(ctx, state.GameID); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, state.GameID); !errors.Is(err, errGameNotFound) {
+		t.Errorf("second Delete: err = %v, want errGameNotFound", err)
+	}
+	if _, err := store.Replay(ctx, state.GameID); !errors.Is(err, errGameNotFound) {
+		t.Errorf("Replay after Delete: err = %v, want errGameNotFound", err)
+	}
+
+	// Updates only restart the TTL if fn touches the game
+	expiring := initializeGame(GameConfig{Width: 10, Height: 10, TTLSeconds: 2})
+	if err := store.Save(ctx, expiring); err != nil {
+		t.Fatal(err)
+	}
+	elapse(1500 * time.Millisecond)
+	if _, err := store.Update(ctx, expiring.GameID, func(state GameState) GameState { return state }); err != nil {
+		t.Fatal(err)
+	}
+	elapse(time.Second)
+	if _, err := store.Get(ctx, expiring.GameID); !errors.Is(err, errGameNotFound) {
+		t.Errorf("Get of a game untouched for its TTL: err = %v, want errGameNotFound", err)
+	}
+
+	touched := initializeGame(GameConfig{Width: 10, Height: 10, TTLSeconds: 2})
+	if err := store.Save(ctx, touched); err != nil {
+		t.Fatal(err)
+	}
+	elapse(1500 * time.Millisecond)
+	if _, err := store.Update(ctx, touched.GameID, touch); err != nil {
+		t.Fatal(err)
+	}
+	elapse(time.Second)
+	if _, err := store.Get(ctx, touched.GameID); err != nil {
+		t.Errorf("Get of a game touched within its TTL: %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, newMemoryStore(), func(d time.Duration) {
+		now := clock().Add(d)
+		clock = func() time.Time { return now }
+	})
+}
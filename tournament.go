@@ -0,0 +1,596 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxTournamentNameLength bounds the name of a tournament
+	maxTournamentNameLength = 64
+	// maxTournamentPlayers bounds the players of a single tournament
+	maxTournamentPlayers = 64
+)
+
+var (
+	errTournamentNotFound = errors.New("tournament not found")
+	errTournamentStarted  = errors.New("tournament has already started")
+	errNotEnoughPlayers   = errors.New("tournament needs at least two players")
+	errTournamentFull     = errors.New("tournament has no places left")
+	errPlayerRegistered   = errors.New("player is already registered")
+	errMatchNotFound      = errors.New("match not found")
+	errMatchNotReady      = errors.New("match is still waiting for a player")
+	errMatchDecided       = errors.New("match already has a winner")
+	errGameRecorded       = errors.New("game was already recorded for a match")
+	errMatchPlayers       = errors.New("game was not played by the players of the match")
+	errNotOrganizer       = errors.New("tournament is run by another account")
+)
+
+// TournamentStatus tells whether a tournament takes registrations, is being
+// played or is over
+type TournamentStatus string
+
+const (
+	TournamentRegistering TournamentStatus = "registering"
+	TournamentRunning     TournamentStatus = "running"
+	TournamentFinished    TournamentStatus = "finished"
+)
+
+type (
+	// Tournament is a single-elimination competition run by the account of
+	// Organizer. Players are seeded in the order they registered; Matches
+	// hold every round of the bracket once it has been generated, with
+	// round 1 first.
+	Tournament struct {
+		ID        string           `json:"id"`
+		Name      string           `json:"name"`
+		Organizer string           `json:"organizer,omitempty"`
+		Status    TournamentStatus `json:"status"`
+		Players   []string         `json:"players"`
+		Rounds    int              `json:"rounds,omitempty"`
+		Matches   []Match          `json:"matches"`
+		Winner    string           `json:"winner,omitempty"`
+		CreatedAt time.Time        `json:"createdAt"`
+	}
+
+	// Match is a game of a bracket between Player1, who plays the snake,
+	// and Player2, who plays the opponent of a versus game they both play
+	// logged in. A player without an opponent in round 1 has a bye and wins
+	// without a game.
+	Match struct {
+		Round   int    `json:"round"`
+		Slot    int    `json:"slot"`
+		Player1 string `json:"player1,omitempty"`
+		Player2 string `json:"player2,omitempty"`
+		GameID  string `json:"gameId,omitempty"`
+		Winner  string `json:"winner,omitempty"`
+	}
+
+	// Standing is how far a player has come in a tournament
+	Standing struct {
+		Player     string `json:"player"`
+		Seed       int    `json:"seed"`
+		Wins       int    `json:"wins"`
+		Round      int    `json:"round"`
+		Eliminated bool   `json:"eliminated"`
+	}
+
+	// TournamentRequest creates a tournament
+	TournamentRequest struct {
+		Name string `json:"name"`
+	}
+
+	// MatchResultRequest records the finished versus game of a match
+	MatchResultRequest struct {
+		Round  int    `json:"round"`
+		Slot   int    `json:"slot"`
+		GameID string `json:"gameId"`
+	}
+
+	StandingsResponse struct {
+		Standings []Standing `json:"standings"`
+	}
+)
+
+// tournamentStore persists tournaments next to the leaderboard
+type tournamentStore struct {
+	db *sql.DB
+}
+
+// tournaments is the server-wide tournament store, opened in main
+var tournaments *tournamentStore
+
+// openTournaments creates the tournament tables in db if needed
+func openTournaments(db *sql.DB) (*tournamentStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS tournaments (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			organizer  TEXT NOT NULL DEFAULT '',
+			status     TEXT NOT NULL,
+			rounds     INTEGER NOT NULL DEFAULT 0,
+			winner     TEXT NOT NULL DEFAULT '',
+			created_at INTEGER NOT NULL
+		);
+		CREATE TABLE IF NOT EXISTS tournament_players (
+			tournament_id TEXT NOT NULL,
+			player        TEXT NOT NULL,
+			seed          INTEGER NOT NULL,
+			PRIMARY KEY (tournament_id, player)
+		);
+		CREATE TABLE IF NOT EXISTS tournament_matches (
+			tournament_id TEXT NOT NULL,
+			round         INTEGER NOT NULL,
+			slot          INTEGER NOT NULL,
+			player1       TEXT NOT NULL DEFAULT '',
+			player2       TEXT NOT NULL DEFAULT '',
+			game_id       TEXT NOT NULL DEFAULT '',
+			winner        TEXT NOT NULL DEFAULT '',
+			PRIMARY KEY (tournament_id, round, slot)
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS tournament_matches_by_game
+			ON tournament_matches (game_id) WHERE game_id != '';
+	`)
+	if err != nil {
+		return nil, err
+	}
+	// Tournaments created before they had organizers are open to every
+	// logged-in account
+	_, err = db.Exec(`ALTER TABLE tournaments ADD COLUMN organizer TEXT NOT NULL DEFAULT ''`)
+	if err != nil && !strings.Contains(err.Error(), "duplicate column name") {
+		return nil, err
+	}
+	return &tournamentStore{db: db}, nil
+}
+
+// create stores a new tournament without players
+func (s *tournamentStore) create(t Tournament) error {
+	_, err := s.db.Exec(
+		`INSERT INTO tournaments (id, name, organizer, status, created_at) VALUES (?, ?, ?, ?, ?)`,
+		t.ID, t.Name, t.Organizer, t.Status, t.CreatedAt.UnixMilli())
+	return err
+}
+
+// get returns the tournament with its players and matches, or
+// errTournamentNotFound
+func (s *tournamentStore) get(ctx context.Context, id string) (Tournament, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return Tournament{}, err
+	}
+	defer tx.Rollback()
+	return loadTournament(tx, id)
+}
+
+// update atomically replaces the tournament with the result of fn, which
+// may fail with an error of its own. Players and matches are only ever
+// added or filled in, so they are written back as a whole.
+func (s *tournamentStore) update(ctx context.Context, id string, fn func(*Tournament) error) (Tournament, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Tournament{}, err
+	}
+	defer tx.Rollback()
+
+	t, err := loadTournament(tx, id)
+	if err != nil {
+		return t, err
+	}
+	if err := fn(&t); err != nil {
+		return t, err
+	}
+
+	_, err = tx.Exec(`UPDATE tournaments SET status = ?, rounds = ?, winner = ? WHERE id = ?`,
+		t.Status, t.Rounds, t.Winner, t.ID)
+	if err != nil {
+		return t, err
+	}
+	for seed, player := range t.Players {
+		_, err := tx.Exec(
+			`INSERT INTO tournament_players (tournament_id, player, seed) VALUES (?, ?, ?)
+			 ON CONFLICT DO NOTHING`,
+			t.ID, player, seed+1)
+		if err != nil {
+			return t, err
+		}
+	}
+	for _, m := range t.Matches {
+		_, err := tx.Exec(
+			`INSERT INTO tournament_matches (tournament_id, round, slot, player1, player2, game_id, winner)
+			 VALUES (?, ?, ?, ?, ?, ?, ?)
+			 ON CONFLICT (tournament_id, round, slot) DO UPDATE SET
+				player1 = excluded.player1, player2 = excluded.player2,
+				game_id = excluded.game_id, winner = excluded.winner`,
+			t.ID, m.Round, m.Slot, m.Player1, m.Player2, m.GameID, m.Winner)
+		if err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed") {
+			return t, errGameRecorded
+		}
+		if err != nil {
+			return t, err
+		}
+	}
+	return t, tx.Commit()
+}
+
+// loadTournament reads the tournament with the given id inside tx
+func loadTournament(tx *sql.Tx, id string) (Tournament, error) {
+	t := Tournament{ID: id, Players: []string{}, Matches: []Match{}}
+	var createdAt int64
+	err := tx.QueryRow(`SELECT name, organizer, status, rounds, winner, created_at FROM tournaments WHERE id = ?`, id).
+		Scan(&t.Name, &t.Organizer, &t.Status, &t.Rounds, &t.Winner, &createdAt)
+	if err == sql.ErrNoRows {
+		return t, errTournamentNotFound
+	}
+	if err != nil {
+		return t, err
+	}
+	t.CreatedAt = time.UnixMilli(createdAt).UTC()
+
+	rows, err := tx.Query(`SELECT player FROM tournament_players WHERE tournament_id = ? ORDER BY seed`, id)
+	if err != nil {
+		return t, err
+	}
+	for rows.Next() {
+		var player string
+		if err := rows.Scan(&player); err != nil {
+			rows.Close()
+			return t, err
+		}
+		t.Players = append(t.Players, player)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return t, err
+	}
+
+	rows, err = tx.Query(
+		`SELECT round, slot, player1, player2, game_id, winner FROM tournament_matches
+		 WHERE tournament_id = ? ORDER BY round, slot`, id)
+	if err != nil {
+		return t, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var m Match
+		if err := rows.Scan(&m.Round, &m.Slot, &m.Player1, &m.Player2, &m.GameID, &m.Winner); err != nil {
+			return t, err
+		}
+		t.Matches = append(t.Matches, m)
+	}
+	return t, rows.Err()
+}
+
+// runBy returns true if the account may change the bracket of the tournament
+func (t *Tournament) runBy(account string) bool {
+	return t.Organizer == "" || t.Organizer == account
+}
+
+// match returns the match of the given round and slot, or nil
+func (t *Tournament) match(round, slot int) *Match {
+	for i := range t.Matches {
+		if t.Matches[i].Round == round && t.Matches[i].Slot == slot {
+			return &t.Matches[i]
+		}
+	}
+	return nil
+}
+
+// generateBracket seeds the players into a single-elimination bracket of
+// the next power of two, so the top seeds can only meet in the last rounds
+// and get the byes if the players do not fill it
+func (t *Tournament) generateBracket() {
+	size, rounds := 1, 0
+	for size < len(t.Players) {
+		size *= 2
+		rounds++
+	}
+
+	// Seed 1 meets seed size, seed 2 meets seed size-1 and so on, with the
+	// winners of each half meeting again as late as possible
+	order := []int{1}
+	for len(order) < size {
+		next := make([]int, 0, 2*len(order))
+		for _, seed := range order {
+			next = append(next, seed, 2*len(order)+1-seed)
+		}
+		order = next
+	}
+
+	t.Rounds = rounds
+	t.Matches = t.Matches[:0]
+	for round := 1; round <= rounds; round++ {
+		for slot := 0; slot < size>>round; slot++ {
+			t.Matches = append(t.Matches, Match{Round: round, Slot: slot})
+		}
+	}
+	player := func(seed int) string {
+		if seed > len(t.Players) {
+			return ""
+		}
+		return t.Players[seed-1]
+	}
+	for slot := 0; slot < size/2; slot++ {
+		m := t.match(1, slot)
+		m.Player1, m.Player2 = player(order[2*slot]), player(order[2*slot+1])
+		if m.Player2 == "" {
+			t.decide(m, m.Player1)
+		}
+	}
+	t.Status = TournamentRunning
+}
+
+// decide records the winner of a match and moves them on to the next round,
+// finishing the tournament after the final
+func (t *Tournament) decide(m *Match, winner string) {
+	m.Winner = winner
+	if m.Round == t.Rounds {
+		t.Winner = winner
+		t.Status = TournamentFinished
+		return
+	}
+	next := t.match(m.Round+1, m.Slot/2)
+	if m.Slot%2 == 0 {
+		next.Player1 = winner
+	} else {
+		next.Player2 = winner
+	}
+}
+
+// standings ranks the players by the round they reached and the matches
+// they won, then by seed
+func (t Tournament) standings() []Standing {
+	standings := make([]Standing, len(t.Players))
+	index := make(map[string]int, len(t.Players))
+	for i, player := range t.Players {
+		standings[i] = Standing{Player: player, Seed: i + 1}
+		index[player] = i
+	}
+	for _, m := range t.Matches {
+		for _, player := range []string{m.Player1, m.Player2} {
+			if i, ok := index[player]; ok {
+				standings[i].Round = max(standings[i].Round, m.Round)
+			}
+		}
+		if m.Winner == "" || m.GameID == "" {
+			continue
+		}
+		standings[index[m.Winner]].Wins++
+		loser := m.Player1
+		if loser == m.Winner {
+			loser = m.Player2
+		}
+		standings[index[loser]].Eliminated = true
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Round != b.Round {
+			return a.Round > b.Round
+		}
+		if a.Eliminated != b.Eliminated {
+			return !a.Eliminated
+		}
+		return a.Wins > b.Wins
+	})
+	return standings
+}
+
+// tournamentError writes the error response for a failed tournament
+// operation
+func tournamentError(w http.ResponseWriter, err error, id string) {
+	switch err {
+	case errTournamentNotFound:
+		jsonError(w, http.StatusNotFound, CodeTournamentNotFound, fmt.Sprintf("Tournament not found: %s", id))
+	case errTournamentStarted:
+		jsonError(w, http.StatusConflict, CodeTournamentStarted,
+			"The bracket has already been generated; registration is closed")
+	case errNotEnoughPlayers:
+		jsonError(w, http.StatusConflict, CodeNotEnoughPlayers, "At least two players must register first")
+	case errTournamentFull:
+		jsonError(w, http.StatusConflict, CodeTournamentFull,
+			fmt.Sprintf("At most %d players can register", maxTournamentPlayers))
+	case errPlayerRegistered:
+		jsonError(w, http.StatusConflict, CodePlayerRegistered, "Player is already registered")
+	case errMatchNotFound:
+		jsonError(w, http.StatusNotFound, CodeMatchNotFound, "No match in this round and slot")
+	case errMatchNotReady:
+		jsonError(w, http.StatusConflict, CodeMatchNotReady, "The match is still waiting for a player")
+	case errMatchDecided:
+		jsonError(w, http.StatusConflict, CodeMatchDecided, "The match already has a winner")
+	case errGameRecorded:
+		jsonError(w, http.StatusConflict, CodeGameRecorded, "The game was already recorded for a match")
+	case errMatchPlayers:
+		jsonError(w, http.StatusConflict, CodeMatchPlayers,
+			"The game must be played logged in by the players of the match, player 1 as the snake")
+	case errNotOrganizer:
+		jsonError(w, http.StatusForbidden, CodeForbidden, "Only the organizer of the tournament may do this")
+	default:
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Tournament store failed")
+	}
+}
+
+// createTournamentHandler creates a tournament open for registration, run by
+// the caller
+func createTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	var req TournamentRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > maxTournamentNameLength {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, fmt.Sprintf(
+			"Tournament name must be between 1 and %d characters", maxTournamentNameLength))
+		return
+	}
+
+	t := Tournament{
+		ID:        uuid.NewString(),
+		Name:      req.Name,
+		Organizer: accountFrom(r.Context()),
+		Status:    TournamentRegistering,
+		Players:   []string{},
+		Matches:   []Match{},
+		CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if err := tournaments.create(t); err != nil {
+		tournamentError(w, err, t.ID)
+		return
+	}
+	jsonResponseWithStatus(w, t, http.StatusCreated)
+}
+
+// getTournamentHandler returns a tournament with its bracket
+func getTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	t, err := tournaments.get(r.Context(), id)
+	if err != nil {
+		tournamentError(w, err, id)
+		return
+	}
+	jsonResponse(w, t)
+}
+
+// registerPlayerHandler adds the caller's account to a tournament that has
+// not started
+func registerPlayerHandler(w http.ResponseWriter, r *http.Request) {
+	player := accountFrom(r.Context())
+	id := chi.URLParam(r, "id")
+	t, err := tournaments.update(r.Context(), id, func(t *Tournament) error {
+		switch {
+		case t.Status != TournamentRegistering:
+			return errTournamentStarted
+		case len(t.Players) >= maxTournamentPlayers:
+			return errTournamentFull
+		}
+		for _, registered := range t.Players {
+			if registered == player {
+				return errPlayerRegistered
+			}
+		}
+		t.Players = append(t.Players, player)
+		return nil
+	})
+	if err != nil {
+		tournamentError(w, err, id)
+		return
+	}
+	jsonResponseWithStatus(w, t, http.StatusCreated)
+}
+
+// bracketHandler closes registration and generates the bracket
+func bracketHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	account := accountFrom(r.Context())
+	t, err := tournaments.update(r.Context(), id, func(t *Tournament) error {
+		switch {
+		case !t.runBy(account):
+			return errNotOrganizer
+		case t.Status != TournamentRegistering:
+			return errTournamentStarted
+		case len(t.Players) < 2:
+			return errNotEnoughPlayers
+		}
+		t.generateBracket()
+		return nil
+	})
+	if err != nil {
+		tournamentError(w, err, id)
+		return
+	}
+	jsonResponse(w, t)
+}
+
+// matchResultHandler decides a match with the finished versus game its
+// players played logged in: the snake is player 1 and the opponent player 2.
+// Draws decide nothing; the players play the match again. Results are
+// posted by the organizer or either player, and the game counts towards the
+// ratings of the players.
+func matchResultHandler(w http.ResponseWriter, r *http.Request) {
+	var req MatchResultRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	if !validGameID(req.GameID) {
+		gameIDError(w, req.GameID)
+		return
+	}
+	state, err := games.Get(r.Context(), req.GameID)
+	if err != nil {
+		storeError(w, err, req.GameID)
+		return
+	}
+	switch {
+	case !state.Versus || state.Bot != nil:
+		jsonError(w, http.StatusConflict, CodeNotVersus,
+			"Matches are decided by versus games between their two players")
+		return
+	case state.GameOverReason == "":
+		jsonError(w, http.StatusConflict, CodeGameNotOver, "Only finished games can decide a match")
+		return
+	case state.Winner == 0:
+		jsonError(w, http.StatusConflict, CodeMatchDraw, "The game was a draw; play the match again")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+	account := accountFrom(r.Context())
+	var decided Match
+	t, err := tournaments.update(r.Context(), id, func(t *Tournament) error {
+		m := t.match(req.Round, req.Slot)
+		switch {
+		case m == nil:
+			return errMatchNotFound
+		case !t.runBy(account) && account != m.Player1 && account != m.Player2:
+			return errNotOrganizer
+		case m.Winner != "":
+			return errMatchDecided
+		case m.Player1 == "" || m.Player2 == "":
+			return errMatchNotReady
+		case len(state.Players) != 2 || state.Players[0] != m.Player1 || state.Players[1] != m.Player2:
+			return errMatchPlayers
+		}
+		m.GameID = req.GameID
+		winner := m.Player1
+		if state.Winner == 2 {
+			winner = m.Player2
+		}
+		t.decide(m, winner)
+		decided = *m
+		return nil
+	})
+	if err != nil {
+		tournamentError(w, err, id)
+		return
+	}
+	if err := ratings.record(req.GameID, decided.Player1, decided.Player2, state.Winner); err != nil {
+		log.Printf("rating game %s: %v", req.GameID, err)
+	}
+	notifyMatchComplete(id, decided)
+	jsonResponse(w, t)
+}
+
+// standingsHandler ranks the players of a tournament
+func standingsHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	t, err := tournaments.get(r.Context(), id)
+	if err != nil {
+		tournamentError(w, err, id)
+		return
+	}
+	jsonResponse(w, StandingsResponse{Standings: t.standings()})
+}
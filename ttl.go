@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// defaultGameTTL is how long a game is kept after its last activity unless
+// configured otherwise
+const defaultGameTTL = 24 * time.Hour
+
+// reapInterval is how often the reaper looks for expired games
+const reapInterval = time.Minute
+
+// gameTTL is the expiry of games created without a TTL of their own
+var gameTTL = defaultGameTTL
+
+// clock returns the current time; fixed in mock mode
+var clock = time.Now
+
+// ttlOf returns how long the game is kept after its last activity
+func ttlOf(state GameState) time.Duration {
+	if state.TTLSeconds > 0 {
+		return time.Duration(state.TTLSeconds) * time.Second
+	}
+	return gameTTL
+}
+
+// expired returns true if the game has seen no activity for its TTL
+func expired(state GameState, now time.Time) bool {
+	return now.Sub(state.LastActivity) >= ttlOf(state)
+}
+
+// touch records a client action on the game, restarting its TTL. Updates
+// the game loop makes on its own are not activity, so a live game nobody
+// steers still expires.
+func touch(state GameState) GameState {
+	state.LastActivity = clock().UTC()
+	return state
+}
+
+// expiresIn returns how much longer than now a store should keep the game
+func expiresIn(state GameState, now time.Time) time.Duration {
+	remaining := state.LastActivity.Add(ttlOf(state)).Sub(now)
+	if remaining < time.Millisecond {
+		// Redis treats a zero expiry as none at all
+		return time.Millisecond
+	}
+	return remaining
+}
+
+// ttlError returns an error code and message if the config asks for an
+// invalid TTL
+func ttlError(config GameConfig) (ErrorCode, string) {
+	if config.TTLSeconds < 0 {
+		return CodeInvalidRequest, fmt.Sprintf("Invalid ttl: %d", config.TTLSeconds)
+	}
+	return "", ""
+}
+
+// runReaper removes expired games from the store every interval, so
+// abandoned games do not accumulate forever
+func runReaper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		n, err := games.Reap(context.Background(), clock())
+		if err != nil {
+			log.Printf("reaping expired games: %v", err)
+			continue
+		}
+		if n > 0 {
+			log.Printf("reaped %d expired games", n)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// Keys read by the terminal play mode
+const (
+	keyQuit = iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+)
+
+// keyTicks are the directions of the arrow keys
+var keyTicks = map[int]Tick{
+	keyUp:    {VelX: 0, VelY: -1},
+	keyDown:  {VelX: 0, VelY: 1},
+	keyLeft:  {VelX: -1, VelY: 0},
+	keyRight: {VelX: 1, VelY: 0},
+}
+
+// playTUI plays in real time on the terminal: the snake moves every
+// interval and the arrow keys, or w/a/s/d, steer it until the game is over
+// or q is pressed
+func playTUI(session playSession, state GameState, interval time.Duration) error {
+	fd := int(os.Stdin.Fd())
+	restore, err := term.MakeRaw(fd)
+	if err != nil {
+		return fmt.Errorf("switching the terminal to raw mode: %w", err)
+	}
+	defer term.Restore(fd, restore)
+	// Hide the cursor while playing
+	fmt.Print("\x1b[?25l")
+	defer fmt.Print("\x1b[?25h")
+
+	keys := make(chan int)
+	go readKeys(os.Stdin, keys)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	heading := Tick{VelX: state.Snake.VelX, VelY: state.Snake.VelY}
+	next := heading
+	message := "arrow keys steer, q quits"
+	drawTUI(state, message)
+	for {
+		select {
+		case key := <-keys:
+			if key == keyQuit {
+				return nil
+			}
+			tick := keyTicks[key]
+			// Turning back onto the neck is ignored rather than fatal
+			if tick.VelX != -heading.VelX || tick.VelY != -heading.VelY {
+				next = tick
+			}
+		case <-ticker.C:
+			newState, status, err := session.step(next)
+			if err != nil {
+				message = err.Error()
+				drawTUI(state, message)
+				continue
+			}
+			state, heading = newState, next
+			if status == ResultGameOver {
+				drawTUI(state, fmt.Sprintf("game over: %s, press any key", state.GameOverReason))
+				<-keys
+				return nil
+			}
+			drawTUI(state, message)
+		}
+	}
+}
+
+// readKeys sends the keys read from r until it fails; escape sequences of
+// the arrow keys are decoded and other keys are dropped
+func readKeys(r io.Reader, keys chan<- int) {
+	in := bufio.NewReader(r)
+	for {
+		b, err := in.ReadByte()
+		if err != nil {
+			keys <- keyQuit
+			return
+		}
+		switch b {
+		case 'q', 3: // q or Ctrl-C
+			keys <- keyQuit
+		case 'w':
+			keys <- keyUp
+		case 's':
+			keys <- keyDown
+		case 'a':
+			keys <- keyLeft
+		case 'd':
+			keys <- keyRight
+		case 0x1b:
+			if next, _ := in.ReadByte(); next != '[' {
+				continue
+			}
+			switch code, _ := in.ReadByte(); code {
+			case 'A':
+				keys <- keyUp
+			case 'B':
+				keys <- keyDown
+			case 'C':
+				keys <- keyRight
+			case 'D':
+				keys <- keyLeft
+			}
+		}
+	}
+}
+
+// drawTUI redraws the board inside a border with the score, active effects
+// and a message below it. Raw mode needs explicit carriage returns.
+func drawTUI(state GameState, message string) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+	border := "+" + strings.Repeat("-", state.Width) + "+\r\n"
+	b.WriteString(border)
+	for _, row := range boardCells(state) {
+		b.WriteString("|")
+		b.Write(row)
+		b.WriteString("|\r\n")
+	}
+	b.WriteString(border)
+
+	fmt.Fprintf(&b, "score %d  ticks %d", state.Score, state.TicksPlayed)
+	if state.TickLimit > 0 {
+		fmt.Fprintf(&b, "/%d", state.TickLimit)
+	}
+	for _, effect := range state.Effects {
+		fmt.Fprintf(&b, "  %s %d", effect.Type, effect.TicksLeft)
+	}
+	b.WriteString("\r\n" + message + "\r\n")
+	os.Stdout.WriteString(b.String())
+}
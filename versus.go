@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	errNotVersus = errors.New("game is not a versus game")
+	errGameFull  = errors.New("game already has an opponent")
+)
+
+// joinHandler adds the second player to a versus game and starts it. A
+// logged-in player joining is recorded as the opponent, which only they may
+// steer from then on.
+func joinHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	joiner := accountFrom(r.Context())
+
+	var joinErr error
+	_, err := games.Update(r.Context(), gameID, func(state GameState) GameState {
+		joinErr = nil
+		switch {
+		case !state.Versus:
+			joinErr = errNotVersus
+		case state.Opponent != nil:
+			joinErr = errGameFull
+		default:
+			state = touch(addOpponent(state))
+			if state.Owner != "" || joiner != "" {
+				state.Players = []string{state.Owner, joiner}
+			}
+		}
+		return state
+	})
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+
+	switch joinErr {
+	case errNotVersus:
+		jsonError(w, http.StatusConflict, CodeNotVersus,
+			"Game was not created as a versus game")
+		return
+	case errGameFull:
+		jsonError(w, http.StatusConflict, CodeGameFull,
+			fmt.Sprintf("Game %s already has two players", gameID))
+		return
+	}
+
+	state, err := loop.start(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+
+	jsonResponse(w, state)
+}
+
+// addOpponent places the second player's snake in the corner opposite the
+// first snake, heading towards it
+func addOpponent(state GameState) GameState {
+	state.Opponent = &Snake{
+		Position: Position{X: state.Width - 1, Y: state.Height - 1},
+		VelX:     -1,
+		VelY:     0,
+		Body:     []Position{},
+	}
+	return state
+}
+
+// stepVersus advances a versus game by one tick, moving both snakes at once.
+// A snake dies if it hits a wall, itself or the other snake; if both die in
+// the same tick, including head-on collisions, the game is a draw. A game
+// that reaches its tick limit times out and goes to the higher score.
+func stepVersus(state GameState, tick, opponentTick Tick) (GameState, int) {
+	if state.GameOverReason != "" {
+		return state, http.StatusTeapot
+	}
+
+	snake := wrapSnake(state, moveSnake(state.Snake, tick))
+	opponent := wrapSnake(state, moveSnake(*state.Opponent, opponentTick))
+	if !isUnitTick(tick) || !isUnitTick(opponentTick) ||
+		!isValidMove(state, GameState{Snake: snake}) ||
+		!isValidMove(GameState{Snake: *state.Opponent}, GameState{Snake: opponent}) {
+		return state, http.StatusBadRequest
+	}
+	state.Snake = snake
+	state.Opponent = &opponent
+	state.TicksPlayed++
+
+	reason := versusCollision(state, snake, opponent)
+	opponentReason := versusCollision(state, opponent, snake)
+	if reason != "" || opponentReason != "" {
+		switch {
+		case reason != "" && opponentReason != "":
+			state.Winner = 0
+			state.GameOverReason = reason
+		case reason != "":
+			state.Winner = 2
+			state.GameOverReason = reason
+		default:
+			state.Winner = 1
+			state.GameOverReason = opponentReason
+		}
+		return state, http.StatusTeapot
+	}
+
+	if i := fruitAt(state, snake.Position); i >= 0 {
+		state.Score += state.Fruits[i].points()
+		state.FruitsEaten++
+		state.Snake = growSnake(snake)
+		state = spawnFruit(state, i)
+	}
+	if i := fruitAt(state, opponent.Position); i >= 0 {
+		state.OpponentScore += state.Fruits[i].points()
+		state.OpponentFruitsEaten++
+		grown := growSnake(opponent)
+		state.Opponent = &grown
+		state = spawnFruit(state, i)
+	}
+
+	if timedOut(state) {
+		state.GameOverReason = ReasonTimeout
+		switch {
+		case state.Score > state.OpponentScore:
+			state.Winner = 1
+		case state.OpponentScore > state.Score:
+			state.Winner = 2
+		}
+		return state, http.StatusTeapot
+	}
+
+	return state, http.StatusOK
+}
+
+// versusCollision returns why the given snake has died, or an empty reason.
+// Besides walls and its own body, running into any part of the other snake
+// is fatal.
+func versusCollision(state GameState, snake, other Snake) GameOverReason {
+	if reason := snakeCollision(state, snake); reason != "" {
+		return reason
+	}
+
+	if snake.Position == other.Position {
+		return ReasonCollision
+	}
+	for _, segment := range other.Body {
+		if segment == snake.Position {
+			return ReasonCollision
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+)
+
+// versusBoard returns a versus game of the given size with both snakes on
+// it, and the fruits if any
+func versusBoard(width, height int, snake, opponent Snake, fruits ...Fruit) GameState {
+	state := board(width, height, snake, fruits...)
+	if opponent.Body == nil {
+		opponent.Body = []Position{}
+	}
+	state.Versus, state.Opponent = true, &opponent
+	return state
+}
+
+func TestStepVersus(t *testing.T) {
+	right, left, down := Tick{VelX: 1}, Tick{VelX: -1}, Tick{VelY: 1}
+	limited := versusBoard(6, 6, at(0, 0, 1, 0), at(5, 5, -1, 0))
+	limited.TickLimit, limited.Score = 1, 2
+
+	tests := []struct {
+		name                 string
+		state                GameState
+		tick, opponentTick   Tick
+		status               int
+		reason               GameOverReason
+		winner               int
+		score, opponentScore int
+	}{
+		{name: "both move", state: versusBoard(6, 6, at(0, 0, 1, 0), at(5, 5, -1, 0)),
+			tick: right, opponentTick: left, status: http.StatusOK},
+		{name: "snake hits wall", state: versusBoard(6, 6, at(5, 0, 1, 0), at(5, 5, -1, 0)),
+			tick: right, opponentTick: left, status: http.StatusTeapot, reason: ReasonWall, winner: 2},
+		{name: "opponent hits wall", state: versusBoard(6, 6, at(0, 0, 1, 0), at(0, 5, -1, 0)),
+			tick: right, opponentTick: left, status: http.StatusTeapot, reason: ReasonWall, winner: 1},
+		{name: "head-on is a draw", state: versusBoard(6, 6, at(2, 0, 1, 0), at(4, 0, -1, 0)),
+			tick: right, opponentTick: left, status: http.StatusTeapot, reason: ReasonCollision},
+		{name: "snake runs into the opponent's body",
+			state: versusBoard(6, 6, at(2, 1, 1, 0), at(3, 2, 0, 1, Position{X: 3, Y: 1}, Position{X: 3, Y: 0})),
+			tick:  right, opponentTick: down, status: http.StatusTeapot, reason: ReasonCollision, winner: 2},
+		{name: "both eat", state: versusBoard(6, 6, at(0, 0, 1, 0), at(5, 5, -1, 0),
+			Fruit{Position{X: 1}, FruitNormal}, Fruit{Position{X: 4, Y: 5}, FruitGolden}),
+			tick: right, opponentTick: left, status: http.StatusOK, score: 1, opponentScore: goldenFruitPoints},
+		{name: "timeout goes to the higher score", state: limited,
+			tick: right, opponentTick: left, status: http.StatusTeapot, reason: ReasonTimeout, winner: 1, score: 2},
+		{name: "opponent reverses", state: versusBoard(6, 6, at(0, 0, 1, 0), at(5, 5, -1, 0)),
+			tick: right, opponentTick: right, status: http.StatusBadRequest},
+		{name: "snake moves diagonally", state: versusBoard(6, 6, at(0, 0, 1, 0), at(5, 5, -1, 0)),
+			tick: Tick{VelX: 1, VelY: 1}, opponentTick: left, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, status := stepVersus(tt.state, tt.tick, tt.opponentTick)
+			if status != tt.status {
+				t.Errorf("status = %d, want %d", status, tt.status)
+			}
+			if state.GameOverReason != tt.reason || state.Winner != tt.winner {
+				t.Errorf("reason %q won by %d, want %q won by %d", state.GameOverReason, state.Winner, tt.reason, tt.winner)
+			}
+			if state.Score != tt.score || state.OpponentScore != tt.opponentScore {
+				t.Errorf("scores %d:%d, want %d:%d", state.Score, state.OpponentScore, tt.score, tt.opponentScore)
+			}
+		})
+	}
+}
+
+func TestAddOpponent(t *testing.T) {
+	state := addOpponent(board(8, 6, at(0, 0, 1, 0)))
+	want := at(7, 5, -1, 0)
+	if state.Opponent == nil || state.Opponent.Position != want.Position ||
+		state.Opponent.VelX != want.VelX || state.Opponent.VelY != want.VelY {
+		t.Errorf("opponent = %+v, want %+v", state.Opponent, want)
+	}
+}
@@ -0,0 +1,789 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	// webhookInterval is how often due deliveries are sent
+	webhookInterval = time.Second
+
+	// webhookTimeout bounds a single delivery attempt
+	webhookTimeout = 5 * time.Second
+
+	// maxConcurrentDeliveries bounds the deliveries sent at once
+	maxConcurrentDeliveries = 8
+
+	// webhookQueueSize is how many events wait to be queued for delivery
+	// before new ones are dropped
+	webhookQueueSize = 1024
+
+	// maxWebhookAttempts is how often a delivery is tried before it is
+	// given up; retries back off exponentially from webhookBackoff
+	maxWebhookAttempts = 6
+	webhookBackoff     = 5 * time.Second
+
+	// maxWebhooks bounds the webhooks a single tenant may register
+	maxWebhooks = 20
+
+	// maxDeliveryLogs is how many deliveries GET .../deliveries returns
+	maxDeliveryLogs = 100
+
+	// webhookSignatureHeader carries the HMAC-SHA256 of the body under the
+	// webhook secret, as "sha256=<hex>"
+	webhookSignatureHeader = "X-Snake-Signature"
+)
+
+// WebhookEvent names what a webhook is notified of
+type WebhookEvent string
+
+const (
+	// EventGameOver is sent when a game of the tenant is over
+	EventGameOver WebhookEvent = "game.over"
+	// EventHighScore is sent when the tenant submits a personal best score
+	EventHighScore WebhookEvent = "score.high"
+	// EventMatchComplete is sent when a match of the tournament is decided
+	EventMatchComplete WebhookEvent = "match.complete"
+)
+
+// webhookEvents are the events a webhook can subscribe to
+var webhookEvents = []string{string(EventGameOver), string(EventHighScore), string(EventMatchComplete)}
+
+// DeliveryStatus tells where a delivery is in its retries. Failed
+// deliveries are kept with their payload as dead letters until an admin
+// redelivers them.
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliveryDelivered DeliveryStatus = "delivered"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+var (
+	errWebhookNotFound  = errors.New("webhook not found")
+	errDeliveryNotFound = errors.New("delivery not found")
+	errTooManyWebhooks  = fmt.Errorf("at most %d webhooks can be registered", maxWebhooks)
+	errWebhookAddress   = errors.New("webhook address is not a public address")
+)
+
+type (
+	// Webhook receives signed POSTs of the events it subscribes to. Tenant
+	// webhooks get the events of the games and scores of the account or
+	// API key that registered them; tournament webhooks get the matches
+	// of their tournament instead. Secret is only set in the response
+	// registering it.
+	Webhook struct {
+		ID           string         `json:"id"`
+		URL          string         `json:"url"`
+		Events       []WebhookEvent `json:"events"`
+		Owner        string         `json:"owner"`
+		TournamentID string         `json:"tournamentId,omitempty"`
+		Secret       string         `json:"secret,omitempty"`
+		CreatedAt    time.Time      `json:"createdAt"`
+	}
+
+	// WebhookRequest is the body of POST /v1/webhooks
+	WebhookRequest struct {
+		URL          string         `json:"url"`
+		Events       []WebhookEvent `json:"events"`
+		TournamentID string         `json:"tournamentId,omitempty"`
+	}
+
+	WebhooksResponse struct {
+		Webhooks []Webhook `json:"webhooks"`
+	}
+
+	// WebhookPayload is the body of every delivery
+	WebhookPayload struct {
+		ID        string       `json:"id"`
+		Event     WebhookEvent `json:"event"`
+		CreatedAt time.Time    `json:"createdAt"`
+		Data      any          `json:"data"`
+	}
+
+	// Delivery logs the attempts to send an event to a webhook
+	Delivery struct {
+		ID            string         `json:"id"`
+		WebhookID     string         `json:"webhookId"`
+		Event         WebhookEvent   `json:"event"`
+		Status        DeliveryStatus `json:"status"`
+		Attempts      int            `json:"attempts"`
+		ResponseCode  int            `json:"responseCode,omitempty"`
+		Error         string         `json:"error,omitempty"`
+		CreatedAt     time.Time      `json:"createdAt"`
+		NextAttemptAt *time.Time     `json:"nextAttemptAt,omitempty"`
+	}
+
+	DeliveriesResponse struct {
+		Deliveries []Delivery `json:"deliveries"`
+	}
+
+	// HighScoreEvent is the data of score.high
+	HighScoreEvent struct {
+		ScoreEntry
+		PreviousBest int `json:"previousBest"`
+	}
+
+	// MatchCompleteEvent is the data of match.complete
+	MatchCompleteEvent struct {
+		TournamentID string `json:"tournamentId"`
+		Match        Match  `json:"match"`
+	}
+)
+
+// webhookStore persists webhooks and their deliveries next to the
+// leaderboard, so retries survive a restart
+type webhookStore struct {
+	db     *sql.DB
+	client *http.Client
+	// emits holds the events emit hands to run, which queues their
+	// deliveries
+	emits chan webhookEmit
+}
+
+// webhookEmit is an event waiting to be queued for delivery, with its data
+// already encoded
+type webhookEmit struct {
+	event               WebhookEvent
+	owner, tournamentID string
+	data                json.RawMessage
+}
+
+// webhooks is the server-wide webhook store, opened in main
+var webhooks *webhookStore
+
+// openWebhooks creates the webhook tables in db if needed
+func openWebhooks(db *sql.DB) (*webhookStore, error) {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS webhooks (
+			id            TEXT PRIMARY KEY,
+			owner         TEXT NOT NULL,
+			url           TEXT NOT NULL,
+			events        TEXT NOT NULL,
+			tournament_id TEXT NOT NULL,
+			secret        TEXT NOT NULL,
+			created_at    INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS webhooks_by_owner ON webhooks (owner);
+		CREATE TABLE IF NOT EXISTS webhook_deliveries (
+			id              TEXT PRIMARY KEY,
+			webhook_id      TEXT NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+			event           TEXT NOT NULL,
+			payload         BLOB NOT NULL,
+			status          TEXT NOT NULL,
+			attempts        INTEGER NOT NULL,
+			response_code   INTEGER NOT NULL,
+			error           TEXT NOT NULL,
+			created_at      INTEGER NOT NULL,
+			next_attempt_at INTEGER NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+		CREATE INDEX IF NOT EXISTS webhook_deliveries_by_webhook ON webhook_deliveries (webhook_id, created_at);
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return &webhookStore{db: db, client: newWebhookClient(), emits: make(chan webhookEmit, webhookQueueSize)}, nil
+}
+
+// newWebhookClient returns the client deliveries are sent with. It only
+// connects to public addresses, checked once the host is resolved so DNS
+// cannot point a webhook at the server's own network, and never follows
+// redirects, which a webhook could use to the same end.
+func newWebhookClient() *http.Client {
+	dialer := &net.Dialer{
+		Timeout: webhookTimeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip, err := netip.ParseAddr(host); err != nil || !publicAddress(ip) {
+				return errWebhookAddress
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{
+		Timeout:   webhookTimeout,
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, which like
+// private ranges is only reachable from inside a provider's network
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// publicAddress returns false for loopback, private, shared, link-local,
+// multicast and unspecified addresses
+func publicAddress(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return !ip.IsLoopback() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip) &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() && !ip.IsMulticast() && !ip.IsUnspecified()
+}
+
+// create registers a webhook with a new secret
+func (s *webhookStore) create(hook Webhook) (Webhook, error) {
+	var count int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM webhooks WHERE owner = ?`, hook.Owner).Scan(&count); err != nil {
+		return hook, err
+	}
+	if count >= maxWebhooks {
+		return hook, errTooManyWebhooks
+	}
+
+	secret := make([]byte, 24)
+	if _, err := rand.Read(secret); err != nil {
+		return hook, err
+	}
+	hook.ID = uuid.NewString()
+	hook.Secret = "whsec_" + hex.EncodeToString(secret)
+	hook.CreatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	_, err := s.db.Exec(
+		`INSERT INTO webhooks (id, owner, url, events, tournament_id, secret, created_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		hook.ID, hook.Owner, hook.URL, joinEvents(hook.Events), hook.TournamentID, hook.Secret,
+		hook.CreatedAt.UnixMilli())
+	return hook, err
+}
+
+// list returns the webhooks of a tenant, without their secrets
+func (s *webhookStore) list(owner string) ([]Webhook, error) {
+	rows, err := s.db.Query(
+		`SELECT id, owner, url, events, tournament_id, created_at FROM webhooks
+		 WHERE owner = ? ORDER BY created_at, id`, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []Webhook{}
+	for rows.Next() {
+		hook, _, err := scanWebhook(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, hook)
+	}
+	return hooks, rows.Err()
+}
+
+// get returns a webhook of a tenant, without its secret
+func (s *webhookStore) get(owner, id string) (Webhook, error) {
+	hook, _, err := scanWebhook(s.db.QueryRow(
+		`SELECT id, owner, url, events, tournament_id, created_at FROM webhooks
+		 WHERE owner = ? AND id = ?`, owner, id), false)
+	if err == sql.ErrNoRows {
+		return hook, errWebhookNotFound
+	}
+	return hook, err
+}
+
+// delete removes a webhook of a tenant with its delivery log
+func (s *webhookStore) delete(owner, id string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM webhooks WHERE owner = ? AND id = ?`, owner, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return errWebhookNotFound
+	}
+	if _, err := tx.Exec(`DELETE FROM webhook_deliveries WHERE webhook_id = ?`, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// scanWebhook reads a webhook selected as id, owner, url, events,
+// tournament_id and created_at, followed by secret if withSecret is set
+func scanWebhook(row interface{ Scan(...any) error }, withSecret bool) (Webhook, string, error) {
+	var hook Webhook
+	var events, secret string
+	var createdAt int64
+	dest := []any{&hook.ID, &hook.Owner, &hook.URL, &events, &hook.TournamentID, &createdAt}
+	if withSecret {
+		dest = append(dest, &secret)
+	}
+	if err := row.Scan(dest...); err != nil {
+		return hook, "", err
+	}
+	for _, event := range strings.Split(events, ",") {
+		hook.Events = append(hook.Events, WebhookEvent(event))
+	}
+	hook.CreatedAt = time.UnixMilli(createdAt).UTC()
+	return hook, secret, nil
+}
+
+// joinEvents is how events are stored
+func joinEvents(events []WebhookEvent) string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = string(event)
+	}
+	return strings.Join(names, ",")
+}
+
+// emit queues a delivery of the event to every webhook subscribed to it,
+// the tenant webhooks of owner or the webhooks of tournamentID. It never
+// waits on the database, so games are not held up by their webhooks; the
+// deliveries are queued by run, and events are dropped while it is behind.
+func (s *webhookStore) emit(event WebhookEvent, owner, tournamentID string, data any) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("webhooks: encoding %s: %v", event, err)
+		return
+	}
+	select {
+	case s.emits <- webhookEmit{event: event, owner: owner, tournamentID: tournamentID, data: raw}:
+	default:
+		log.Printf("webhooks: queue is full, dropping %s", event)
+	}
+}
+
+// queue stores a delivery of an emitted event for every webhook subscribed
+// to it
+func (s *webhookStore) queue(event WebhookEvent, owner, tournamentID string, data json.RawMessage) {
+	query, arg := `SELECT id, owner, url, events, tournament_id, created_at FROM webhooks
+		WHERE owner = ? AND tournament_id = ''`, owner
+	if tournamentID != "" {
+		query, arg = `SELECT id, owner, url, events, tournament_id, created_at FROM webhooks
+		WHERE tournament_id = ?`, tournamentID
+	}
+	rows, err := s.db.Query(query, arg)
+	if err != nil {
+		log.Printf("webhooks: finding subscribers of %s: %v", event, err)
+		return
+	}
+	var subscribed []string
+	for rows.Next() {
+		hook, _, err := scanWebhook(rows, false)
+		if err != nil {
+			log.Printf("webhooks: finding subscribers of %s: %v", event, err)
+			break
+		}
+		if slices.Contains(hook.Events, event) {
+			subscribed = append(subscribed, hook.ID)
+		}
+	}
+	rows.Close()
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	for _, hookID := range subscribed {
+		id := uuid.NewString()
+		payload, err := json.Marshal(WebhookPayload{ID: id, Event: event, CreatedAt: now, Data: data})
+		if err != nil {
+			log.Printf("webhooks: encoding %s: %v", event, err)
+			return
+		}
+		_, err = s.db.Exec(
+			`INSERT INTO webhook_deliveries (id, webhook_id, event, payload, status, attempts, response_code,
+				error, created_at, next_attempt_at)
+			 VALUES (?, ?, ?, ?, ?, 0, 0, '', ?, ?)`,
+			id, hookID, event, payload, DeliveryPending, now.UnixMilli(), now.UnixMilli())
+		if err != nil {
+			log.Printf("webhooks: queueing %s for %s: %v", event, hookID, err)
+		}
+	}
+}
+
+// deliveryColumns are the columns scanDelivery reads
+const deliveryColumns = `id, webhook_id, event, status, attempts, response_code, error, created_at, next_attempt_at`
+
+// scanDelivery reads a delivery selected as deliveryColumns
+func scanDelivery(row interface{ Scan(...any) error }) (Delivery, error) {
+	var delivery Delivery
+	var createdAt, nextAttemptAt int64
+	err := row.Scan(&delivery.ID, &delivery.WebhookID, &delivery.Event, &delivery.Status, &delivery.Attempts,
+		&delivery.ResponseCode, &delivery.Error, &createdAt, &nextAttemptAt)
+	if err != nil {
+		return delivery, err
+	}
+	delivery.CreatedAt = time.UnixMilli(createdAt).UTC()
+	if delivery.Status == DeliveryPending {
+		at := time.UnixMilli(nextAttemptAt).UTC()
+		delivery.NextAttemptAt = &at
+	}
+	return delivery, nil
+}
+
+// deliveries returns the most recent deliveries of a webhook
+func (s *webhookStore) deliveries(hookID string) ([]Delivery, error) {
+	return s.queryDeliveries(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE webhook_id = ? ORDER BY created_at DESC, id LIMIT ?`, hookID, maxDeliveryLogs)
+}
+
+// deadLetters returns the most recent deliveries of every webhook that ran
+// out of attempts
+func (s *webhookStore) deadLetters() ([]Delivery, error) {
+	return s.queryDeliveries(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE status = ? ORDER BY created_at DESC, id LIMIT ?`, DeliveryFailed, maxDeliveryLogs)
+}
+
+// queryDeliveries returns the deliveries a query selects as deliveryColumns
+func (s *webhookStore) queryDeliveries(query string, args ...any) ([]Delivery, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// redeliver queues a delivery again with a fresh set of attempts, the first
+// due at now
+func (s *webhookStore) redeliver(id string, now time.Time) (Delivery, error) {
+	result, err := s.db.Exec(
+		`UPDATE webhook_deliveries SET status = ?, attempts = 0, response_code = 0, error = '',
+			next_attempt_at = ? WHERE id = ?`,
+		DeliveryPending, now.UnixMilli(), id)
+	if err != nil {
+		return Delivery{}, err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return Delivery{}, err
+	} else if n == 0 {
+		return Delivery{}, errDeliveryNotFound
+	}
+	return scanDelivery(s.db.QueryRow(`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ?`, id))
+}
+
+// run queues the deliveries of emitted events as they come, and sends due
+// deliveries once per interval, forever
+func (s *webhookStore) run(interval time.Duration) {
+	go func() {
+		for emit := range s.emits {
+			s.queue(emit.event, emit.owner, emit.tournamentID, emit.data)
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := s.deliverDue(time.Now()); err != nil {
+			log.Printf("webhooks: delivering: %v", err)
+		}
+	}
+}
+
+// dueDelivery is a pending delivery with what it takes to send it
+type dueDelivery struct {
+	id, url, secret string
+	event           WebhookEvent
+	payload         []byte
+	attempts        int
+}
+
+// deliverDue sends every pending delivery whose next attempt is due by now,
+// up to maxConcurrentDeliveries at once, and returns once they are all sent
+func (s *webhookStore) deliverDue(now time.Time) error {
+	rows, err := s.db.Query(
+		`SELECT d.id, h.url, h.secret, d.event, d.payload, d.attempts
+		 FROM webhook_deliveries d JOIN webhooks h ON h.id = d.webhook_id
+		 WHERE d.status = ? AND d.next_attempt_at <= ? ORDER BY d.next_attempt_at LIMIT 50`,
+		DeliveryPending, now.UnixMilli())
+	if err != nil {
+		return err
+	}
+	var due []dueDelivery
+	for rows.Next() {
+		var d dueDelivery
+		if err := rows.Scan(&d.id, &d.url, &d.secret, &d.event, &d.payload, &d.attempts); err != nil {
+			rows.Close()
+			return err
+		}
+		due = append(due, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	slots := make(chan struct{}, maxConcurrentDeliveries)
+	for _, d := range due {
+		slots <- struct{}{}
+		wg.Add(1)
+		go func(d dueDelivery) {
+			defer func() { <-slots; wg.Done() }()
+			if err := s.deliver(d, now); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(d)
+	}
+	wg.Wait()
+	return firstErr
+}
+
+// deliver makes an attempt at a delivery and records how it went
+func (s *webhookStore) deliver(d dueDelivery, now time.Time) error {
+	code, err := s.send(d)
+	d.attempts++
+	status, message, next := DeliveryDelivered, "", now
+	switch {
+	case err == nil && code >= 200 && code < 300:
+	case d.attempts >= maxWebhookAttempts:
+		status = DeliveryFailed
+	default:
+		status = DeliveryPending
+		next = now.Add(webhookBackoff << (d.attempts - 1))
+	}
+	if err != nil {
+		message = err.Error()
+	} else if status != DeliveryDelivered {
+		message = fmt.Sprintf("webhook answered %d", code)
+	}
+	_, err = s.db.Exec(
+		`UPDATE webhook_deliveries SET status = ?, attempts = ?, response_code = ?, error = ?,
+			next_attempt_at = ? WHERE id = ?`,
+		status, d.attempts, code, message, next.UnixMilli(), d.id)
+	return err
+}
+
+// send POSTs a delivery, signed with the webhook secret, and returns the
+// status code it was answered with
+func (s *webhookStore) send(d dueDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(d.payload))
+	if err != nil {
+		return 0, err
+	}
+	mac := hmac.New(sha256.New, []byte(d.secret))
+	mac.Write(d.payload)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "snake-game-api webhooks")
+	req.Header.Set("X-Snake-Event", string(d.event))
+	req.Header.Set("X-Snake-Delivery", d.id)
+	req.Header.Set(webhookSignatureHeader, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// notifyGameOver sends game.over to the tenants of a game that just ended
+func notifyGameOver(state GameState) {
+	for _, player := range gamePlayers(state) {
+		if player == "" {
+			continue
+		}
+		webhooks.emit(EventGameOver, player, "", state)
+	}
+}
+
+// notifyHighScore sends score.high to the tenant of a score that beats its
+// previous best
+func notifyHighScore(owner string, entry ScoreEntry) {
+	var best sql.NullInt64
+	err := scores.db.QueryRow(`SELECT MAX(score) FROM scores WHERE player = ? AND NOT anonymous AND game_id != ?`,
+		entry.Player, entry.GameID).Scan(&best)
+	if err != nil {
+		log.Printf("webhooks: finding best score of %s: %v", entry.Player, err)
+		return
+	}
+	if best.Valid && int64(entry.Score) <= best.Int64 {
+		return
+	}
+	webhooks.emit(EventHighScore, owner, "", HighScoreEvent{ScoreEntry: entry, PreviousBest: int(best.Int64)})
+}
+
+// notifyMatchComplete sends match.complete to the webhooks of a tournament
+func notifyMatchComplete(tournamentID string, match Match) {
+	webhooks.emit(EventMatchComplete, "", tournamentID, MatchCompleteEvent{TournamentID: tournamentID, Match: match})
+}
+
+// createWebhookHandler registers a webhook of the caller
+func createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	owner := accountFrom(r.Context())
+	var req WebhookRequest
+	if err := decodeBody(r, &req); err != nil {
+		bodyError(w, err)
+		return
+	}
+	defer r.Body.Close()
+
+	target, err := url.Parse(req.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, "url must be an absolute http or https URL")
+		return
+	}
+	if ip, err := netip.ParseAddr(target.Hostname()); (err == nil && !publicAddress(ip)) ||
+		strings.EqualFold(target.Hostname(), "localhost") {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, "url must point to a public address")
+		return
+	}
+	if len(req.Events) == 0 {
+		jsonError(w, http.StatusBadRequest, CodeInvalidRequest, "events must name at least one event")
+		return
+	}
+	for _, event := range req.Events {
+		if !slices.Contains(webhookEvents, string(event)) {
+			jsonResponseWithStatus(w, ErrorResponse{
+				Code:      CodeInvalidRequest,
+				Message:   fmt.Sprintf("Unknown event: %s", event),
+				Supported: webhookEvents,
+			}, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.TournamentID != "" {
+		// Only the organizer hears of a tournament's results
+		t, err := tournaments.get(r.Context(), req.TournamentID)
+		if err == nil && !t.runBy(owner) {
+			err = errNotOrganizer
+		}
+		if err != nil {
+			tournamentError(w, err, req.TournamentID)
+			return
+		}
+	}
+	slices.Sort(req.Events)
+	req.Events = slices.Compact(req.Events)
+
+	hook, err := webhooks.create(Webhook{
+		URL:          target.String(),
+		Events:       req.Events,
+		Owner:        owner,
+		TournamentID: req.TournamentID,
+	})
+	if err == errTooManyWebhooks {
+		jsonError(w, http.StatusConflict, CodeTooManyWebhooks,
+			fmt.Sprintf("At most %d webhooks can be registered; delete one first", maxWebhooks))
+		return
+	}
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to register webhook")
+		return
+	}
+	jsonResponseWithStatus(w, hook, http.StatusCreated)
+}
+
+// webhooksHandler lists the webhooks of the caller
+func webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	owner := accountFrom(r.Context())
+	hooks, err := webhooks.list(owner)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load webhooks")
+		return
+	}
+	jsonResponse(w, WebhooksResponse{Webhooks: hooks})
+}
+
+// deleteWebhookHandler removes a webhook of the caller
+func deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	owner := accountFrom(r.Context())
+	id := chi.URLParam(r, "id")
+	switch err := webhooks.delete(owner, id); err {
+	case nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errWebhookNotFound:
+		jsonError(w, http.StatusNotFound, CodeWebhookNotFound, fmt.Sprintf("Webhook not found: %s", id))
+	default:
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to delete webhook")
+	}
+}
+
+// deliveriesHandler returns the delivery log of a webhook of the caller
+func deliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	owner := accountFrom(r.Context())
+	id := chi.URLParam(r, "id")
+	if _, err := webhooks.get(owner, id); err == errWebhookNotFound {
+		jsonError(w, http.StatusNotFound, CodeWebhookNotFound, fmt.Sprintf("Webhook not found: %s", id))
+		return
+	} else if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load webhook")
+		return
+	}
+
+	deliveries, err := webhooks.deliveries(id)
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load deliveries")
+		return
+	}
+	jsonResponse(w, DeliveriesResponse{Deliveries: deliveries})
+}
+
+// deadLettersHandler lists the deliveries of every webhook that ran out of
+// attempts
+func deadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	deliveries, err := webhooks.deadLetters()
+	if err != nil {
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to load deliveries")
+		return
+	}
+	jsonResponse(w, DeliveriesResponse{Deliveries: deliveries})
+}
+
+// redeliverHandler queues a delivery again, such as a dead letter once its
+// webhook is fixed
+func redeliverHandler(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	switch delivery, err := webhooks.redeliver(id, time.Now()); err {
+	case nil:
+		jsonResponse(w, delivery)
+	case errDeliveryNotFound:
+		jsonError(w, http.StatusNotFound, CodeDeliveryNotFound, fmt.Sprintf("Delivery not found: %s", id))
+	default:
+		jsonError(w, http.StatusInternalServerError, CodeInternal, "Failed to queue delivery")
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPublicAddress(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "8.8.8.8", want: true},
+		{ip: "2606:4700::1111", want: true},
+		{ip: "100.63.255.255", want: true},
+		{ip: "100.64.0.1", want: false},
+		{ip: "100.127.255.254", want: false},
+		{ip: "127.0.0.1", want: false},
+		{ip: "10.1.2.3", want: false},
+		{ip: "192.168.0.1", want: false},
+		{ip: "169.254.169.254", want: false},
+		{ip: "::ffff:10.0.0.1", want: false},
+		{ip: "::1", want: false},
+		{ip: "0.0.0.0", want: false},
+	}
+	for _, tt := range tests {
+		if got := publicAddress(netip.MustParseAddr(tt.ip)); got != tt.want {
+			t.Errorf("publicAddress(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCreateWebhookAccess(t *testing.T) {
+	h := newTestAPI(t)
+	alice := register(t, h, "alice")
+	bob := register(t, h, "bob")
+	scoresOnly, err := apiKeys.create("scorer", []APIScope{ScopeScores})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := do(t, h, http.MethodPost, "/v1/tournaments", alice, map[string]string{"name": "cup"})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("creating tournament: %d %s", rec.Code, rec.Body)
+	}
+	var cup Tournament
+	decode(t, rec, &cup)
+
+	results := WebhookRequest{URL: "https://example.com/hook", Events: []WebhookEvent{EventMatchComplete},
+		TournamentID: cup.ID}
+	tests := []struct {
+		name   string
+		token  string
+		apiKey string
+		body   WebhookRequest
+		status int
+		code   ErrorCode
+	}{
+		{name: "organizer", token: alice, body: results, status: http.StatusCreated},
+		{name: "another account", token: bob, body: results, status: http.StatusForbidden, code: CodeForbidden},
+		{name: "anonymous", body: results, status: http.StatusUnauthorized, code: CodeUnauthorized},
+		{name: "key without play scope", apiKey: scoresOnly.Key,
+			body:   WebhookRequest{URL: "https://example.com/hook", Events: []WebhookEvent{EventGameOver}},
+			status: http.StatusForbidden, code: CodeForbidden},
+		{name: "shared address", token: bob,
+			body:   WebhookRequest{URL: "http://100.64.0.1/hook", Events: []WebhookEvent{EventGameOver}},
+			status: http.StatusBadRequest, code: CodeInvalidRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newRequest(t, http.MethodPost, "/v1/webhooks", tt.token, tt.body)
+			if tt.apiKey != "" {
+				req.Header.Set(apiKeyHeader, tt.apiKey)
+			}
+			rec := send(h, req)
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.code != "" {
+				var resp ErrorResponse
+				decode(t, rec, &resp)
+				if resp.Code != tt.code {
+					t.Errorf("code = %s, want %s", resp.Code, tt.code)
+				}
+			}
+		})
+	}
+}
+
+func TestDeliveryRetries(t *testing.T) {
+	h := newTestAPI(t)
+	var answer atomic.Int32
+	answer.Store(http.StatusInternalServerError)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(answer.Load()))
+	}))
+	defer receiver.Close()
+	webhooks.client = receiver.Client()
+	root, err := apiKeys.create("root", []APIScope{ScopeAdmin})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hook, err := webhooks.create(Webhook{URL: receiver.URL, Events: []WebhookEvent{EventGameOver}, Owner: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	webhooks.queue(EventGameOver, "alice", "", json.RawMessage(`{}`))
+	delivery := func() Delivery {
+		t.Helper()
+		deliveries, err := webhooks.deliveries(hook.ID)
+		if err != nil || len(deliveries) != 1 {
+			t.Fatalf("deliveries = %v, %v; want one", deliveries, err)
+		}
+		return deliveries[0]
+	}
+
+	now := time.Now()
+	for attempt := 1; attempt < maxWebhookAttempts; attempt++ {
+		if err := webhooks.deliverDue(now); err != nil {
+			t.Fatal(err)
+		}
+		d := delivery()
+		want := now.Add(webhookBackoff << (attempt - 1)).Truncate(time.Millisecond)
+		if d.Status != DeliveryPending || d.Attempts != attempt || d.NextAttemptAt == nil || !d.NextAttemptAt.Equal(want) {
+			t.Fatalf("after attempt %d: %s with %d attempts, next at %v; want pending, next at %v",
+				attempt, d.Status, d.Attempts, d.NextAttemptAt, want)
+		}
+		now = *d.NextAttemptAt
+	}
+	webhooks.deliverDue(now)
+	if d := delivery(); d.Status != DeliveryFailed || d.Attempts != maxWebhookAttempts {
+		t.Fatalf("after the last attempt: %s with %d attempts, want failed with %d", d.Status, d.Attempts, maxWebhookAttempts)
+	}
+
+	admin := func(method, path string) *httptest.ResponseRecorder {
+		req := newRequest(t, method, path, "", nil)
+		req.Header.Set(apiKeyHeader, root.Key)
+		return send(h, req)
+	}
+	var failed DeliveriesResponse
+	decode(t, admin(http.MethodGet, "/v1/admin/deliveries/failed"), &failed)
+	if len(failed.Deliveries) != 1 || failed.Deliveries[0].WebhookID != hook.ID {
+		t.Fatalf("dead letters = %+v, want the delivery to %s", failed.Deliveries, hook.ID)
+	}
+	if rec := admin(http.MethodPost, "/v1/admin/deliveries/unknown/redeliver"); rec.Code != http.StatusNotFound {
+		t.Errorf("redelivering an unknown delivery: status = %d, want 404", rec.Code)
+	}
+	rec := admin(http.MethodPost, "/v1/admin/deliveries/"+failed.Deliveries[0].ID+"/redeliver")
+	var redelivered Delivery
+	decode(t, rec, &redelivered)
+	if rec.Code != http.StatusOK || redelivered.Status != DeliveryPending || redelivered.Attempts != 0 {
+		t.Fatalf("redeliver answered %d with %s after %d attempts, want 200 with pending after 0",
+			rec.Code, redelivered.Status, redelivered.Attempts)
+	}
+
+	answer.Store(http.StatusNoContent)
+	webhooks.deliverDue(time.Now())
+	if d := delivery(); d.Status != DeliveryDelivered || d.Attempts != 1 {
+		t.Errorf("after redelivering: %s with %d attempts, want delivered with 1", d.Status, d.Attempts)
+	}
+}
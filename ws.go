@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{}
+
+// wsHandler upgrades to a WebSocket on which the client sends direction
+// changes as Tick messages while the game loop advances the game and the
+// updated GameState is pushed after every tick. Connecting makes the game live.
+// In versus games ?player=2 steers the opponent; either snake only takes
+// connections from the player steering it.
+func wsHandler(w http.ResponseWriter, r *http.Request) {
+	gameID := chi.URLParam(r, "id")
+	player := 1
+	if r.URL.Query().Get("player") == "2" {
+		player = 2
+	}
+
+	state, err := games.Get(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+	if !controlledBy(state, r.Context(), player) {
+		steerError(w, player)
+		return
+	}
+
+	updates, unsubscribe := loop.subscribe(gameID)
+	defer unsubscribe()
+
+	state, err = loop.start(r.Context(), gameID)
+	if err != nil {
+		storeError(w, err, gameID)
+		return
+	}
+	if state.GameOverReason != "" {
+		jsonResponseWithStatus(w, ErrorResponse{
+			Code:    CodeGameOver,
+			Message: fmt.Sprintf("Game over: %s", state.GameOverReason),
+			State:   &state,
+		}, http.StatusConflict)
+		return
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+	// The connection outlives the server's request timeouts
+	conn.SetReadDeadline(time.Time{})
+	conn.SetWriteDeadline(time.Time{})
+
+	// Direction changes keep the account of the request, not its deadline
+	ctx := context.WithoutCancel(r.Context())
+	done := make(chan struct{})
+	rejected := make(chan string, 1)
+	go func() {
+		defer close(done)
+		for {
+			var tick Tick
+			if err := conn.ReadJSON(&tick); err != nil {
+				return
+			}
+			message := rejectionMessage(loop.setDirection(ctx, gameID, player, tick))
+			if message == "" {
+				continue
+			}
+			select {
+			case rejected <- message:
+			default:
+			}
+		}
+	}()
+
+	if err := conn.WriteJSON(state); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case <-done:
+			return
+		case message := <-rejected:
+			err := conn.WriteJSON(ErrorResponse{
+				Code:    CodeInvalidTick,
+				Message: message,
+			})
+			if err != nil {
+				return
+			}
+		case state, ok := <-updates:
+			if !ok {
+				return
+			}
+			if state.GameOverReason != "" {
+				conn.WriteJSON(ErrorResponse{
+					Code:    CodeGameOver,
+					Message: fmt.Sprintf("Game over: %s", state.GameOverReason),
+					State:   &state,
+				})
+				return
+			}
+			if err := conn.WriteJSON(state); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// rejectionMessage describes why a direction change sent over a stream was
+// rejected, or returns "" if it was accepted or failed for another reason
+func rejectionMessage(err error) string {
+	switch err {
+	case errReversal:
+		return "The snake cannot reverse onto itself"
+	case errBadVelocity:
+		return "Velocity must move one cell up, down, left or right"
+	case errGamePaused:
+		return "Game is paused; resume it before changing direction"
+	case errQueueFull:
+		return "Too many direction changes queued; wait for the next tick"
+	case errNotOwner:
+		return "The snake is steered by another player"
+	}
+	return ""
+}